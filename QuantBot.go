@@ -1,9 +1,90 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/api"
 	"github.com/HunterUPP/QuantBot/handler"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/HunterUPP/QuantBot/trader"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			backfill(os.Args[2:])
+			return
+		case "import":
+			importRecords(os.Args[2:])
+			return
+		}
+	}
 	handler.Server()
 }
+
+// backfill download the history records into the database, e.g.
+// QuantBot backfill -exchange okex -stock BTC/USDT -period M -start "2018-06-01 00:00:00"
+func backfill(args []string) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	e := model.Exchange{}
+	flags.StringVar(&e.Type, "exchange", "", "exchange type, e.g. okex")
+	flags.StringVar(&e.ProxyURL, "proxy", "", "proxy url, e.g. socks5://127.0.0.1:1080")
+	flags.StringVar(&e.BaseURL, "base", "", "api base url of the exchange")
+	stockType := flags.String("stock", "BTC/USDT", "stock type")
+	period := flags.String("period", "M", "period of the records, e.g. M, M5, H, D")
+	start := flags.String("start", time.Now().AddDate(0, 0, -1).Format("2006-01-02 15:04:05"), "start time in the local time")
+	flags.Parse(args)
+	stored, err := trader.Backfill(e, *stockType, *period, *start, func(stored int, last int64) {
+		fmt.Printf("%v records stored, last %v\n", stored, time.Unix(last, 0).Format("2006-01-02 15:04:05"))
+	})
+	if err != nil {
+		fmt.Printf("Backfill error after %v records: %v\n", stored, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Backfill done, %v records stored\n", stored)
+}
+
+// importRecords import the records of a csv or json file into the database, e.g.
+// QuantBot import -exchange okex -stock BTC/USDT -period M -file BTC_USDT.csv -columns "time=Date,volume=Volume BTC" -time-format "2006-01-02 15:04" -timezone Asia/Shanghai
+func importRecords(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	opt := api.ImportOptions{Columns: map[string]string{}}
+	flags.StringVar(&opt.ExchangeType, "exchange", "", "exchange type, e.g. okex")
+	flags.StringVar(&opt.StockType, "stock", "BTC/USDT", "stock type")
+	flags.StringVar(&opt.Period, "period", "M", "period of the records, e.g. M, M5, H, D")
+	flags.StringVar(&opt.Format, "format", "", "csv or json, guessed by the file extension if empty")
+	flags.BoolVar(&opt.NoHeader, "no-header", false, "the csv file has no header")
+	flags.StringVar(&opt.Comma, "comma", "", "the separator of the csv file")
+	flags.StringVar(&opt.TimeFormat, "time-format", "", "unix, unixms or a Go time layout")
+	flags.StringVar(&opt.Timezone, "timezone", "UTC", "the timezone of the time, e.g. Asia/Shanghai")
+	file := flags.String("file", "", "the csv or json file")
+	columns := flags.String("columns", "", "the columns of the fields, e.g. time=Date,open=Open")
+	flags.Parse(args)
+	for _, pair := range strings.Split(*columns, ",") {
+		if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+			opt.Columns[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	if opt.Format == "" {
+		opt.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Println("Import error:", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	result, err := api.ImportRecords(f, opt)
+	if err != nil {
+		fmt.Println("Import error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Import done, %v records from %v to %v\n", result.Count,
+		time.Unix(result.First, 0).Format("2006-01-02 15:04:05"), time.Unix(result.Last, 0).Format("2006-01-02 15:04:05"))
+}
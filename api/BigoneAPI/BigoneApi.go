@@ -11,21 +11,24 @@ import (
 )
 
 const (
-	API_BASE_URL = "https://big.one/api/v2"
-	TICKER_URI   = API_BASE_URL + "/markets/%s/ticker"
-	DEPTH_URI    = API_BASE_URL + "/markets/%s/depth"
-	ACCOUNT_URI  = API_BASE_URL + "/viewer/accounts"
-	ORDERS_URI   = API_BASE_URL + "/viewer/orders"
+	API_BASE_URL = "https://big.one/api/"
+	CANDLES_URI  = "v3/asset_pairs/%s/candles?period=%s&limit=%d"
+	MARKETS_URI  = "v2/markets"
+	TICKER_URI   = "v2/markets/%s/ticker"
+	DEPTH_URI    = "v2/markets/%s/depth"
+	ACCOUNT_URI  = "v2/viewer/accounts"
+	ORDERS_URI   = "v2/viewer/orders"
 )
 
 type Bigone struct {
 	accessKey,
 	secretKey string
 	httpClient *http.Client
+	BaseURL    string //API请求地址, 要带最后的/
 }
 
 func New(client *http.Client, api_key, secret_key string) *Bigone {
-	return &Bigone{api_key, secret_key, client}
+	return &Bigone{api_key, secret_key, client, API_BASE_URL}
 }
 
 type TickerResp struct {
@@ -62,7 +65,7 @@ type TickerResp struct {
 
 func (bo *Bigone) GetTicker(currencyPair string) (*TickerResp, error) {
 	var resp TickerResp
-	tickerURI := fmt.Sprintf(TICKER_URI, currencyPair)
+	tickerURI := fmt.Sprintf(bo.BaseURL+TICKER_URI, currencyPair)
 	err := HttpGet(bo.httpClient, tickerURI, nil, &resp)
 	if err != nil {
 		return nil, err
@@ -96,13 +99,19 @@ type PlaceOrderResp struct {
 	} `json:"data"`
 }
 
-func (bo *Bigone) placeOrder(amount, price string, currencyPair string, orderType, orderSide string) (*PlaceOrderResp, error) {
-	path := ORDERS_URI
+func (bo *Bigone) placeOrder(amount, price string, currencyPair string, orderType, orderSide string, options map[string]string) (*PlaceOrderResp, error) {
+	path := bo.BaseURL + ORDERS_URI
 	params := make(map[string]string)
 	params["market_id"] = currencyPair
 	params["side"] = orderSide
+	params["type"] = orderType
 	params["amount"] = amount
-	params["price"] = price
+	if price != "" {
+		params["price"] = price
+	}
+	for k, v := range options {
+		params[k] = v
+	}
 
 	var resp PlaceOrderResp
 	buf, err := HttpPostForm(bo.httpClient, path, params, bo.privateHeader())
@@ -119,20 +128,28 @@ func (bo *Bigone) placeOrder(amount, price string, currencyPair string, orderTyp
 	return &resp, nil
 }
 
+// PlaceOrder place an order, orderType is LIMIT or MARKET and the amount of a MARKET BID is in the quote currency,
+// options are the optional params like post_only and immediate_or_cancel
+func (bo *Bigone) PlaceOrder(amount, price string, currencyPair string, orderType, orderSide string, options map[string]string) (*PlaceOrderResp, error) {
+	return bo.placeOrder(amount, price, currencyPair, orderType, orderSide, options)
+}
+
 func (bo *Bigone) LimitBuy(amount, price string, currencyPair string) (*PlaceOrderResp, error) {
-	return bo.placeOrder(amount, price, currencyPair, "LIMIT", "BID")
+	return bo.placeOrder(amount, price, currencyPair, "LIMIT", "BID", nil)
 }
 
 func (bo *Bigone) LimitSell(amount, price string, currencyPair string) (*PlaceOrderResp, error) {
-	return bo.placeOrder(amount, price, currencyPair, "LIMIT", "ASK")
+	return bo.placeOrder(amount, price, currencyPair, "LIMIT", "ASK", nil)
 }
 
+// MarketBuy the amount is in the quote currency and the price is ignored
 func (bo *Bigone) MarketBuy(amount, price string, currencyPair string) (*PlaceOrderResp, error) {
-	panic("not implements")
+	return bo.placeOrder(amount, "", currencyPair, "MARKET", "BID", nil)
 }
 
+// MarketSell the price is ignored
 func (bo *Bigone) MarketSell(amount, price string, currencyPair string) (*PlaceOrderResp, error) {
-	panic("not implements")
+	return bo.placeOrder(amount, "", currencyPair, "MARKET", "ASK", nil)
 }
 
 func (bo *Bigone) privateHeader() map[string]string {
@@ -188,7 +205,7 @@ type OrderListResp struct {
 
 func (bo *Bigone) getOrdersList(currencyPair string, size int, tpy int) (*OrderListResp, error) {
 	apiURL := ""
-	apiURL = fmt.Sprintf("%s?market_id=%s", ORDERS_URI, currencyPair)
+	apiURL = fmt.Sprintf("%s?market_id=%s", bo.BaseURL+ORDERS_URI, currencyPair)
 
 	if tpy == 0 {
 		apiURL += "&state=FILLED"
@@ -237,7 +254,7 @@ type CancelOrderResp struct {
 }
 
 func (bo *Bigone) CancelOrder(orderId string, currencyPair string) (*CancelOrderResp, error) {
-	path := ORDERS_URI + "/" + orderId + "/cancel"
+	path := bo.BaseURL + ORDERS_URI + "/" + orderId + "/cancel"
 	params := make(map[string]string)
 	params["order_id"] = orderId
 
@@ -276,7 +293,7 @@ type AccountResp struct {
 
 func (bo *Bigone) GetAccount() (*AccountResp, error) {
 	var resp AccountResp
-	apiUrl := ACCOUNT_URI
+	apiUrl := bo.BaseURL + ACCOUNT_URI
 
 	err := HttpGet(bo.httpClient, apiUrl, bo.privateHeader(), &resp)
 	if err != nil {
@@ -314,7 +331,62 @@ type DepthResp struct {
 
 func (bo *Bigone) GetDepth(currencyPair string) (*DepthResp, error) {
 	var resp DepthResp
-	apiURL := fmt.Sprintf(DEPTH_URI, currencyPair)
+	apiURL := fmt.Sprintf(bo.BaseURL+DEPTH_URI, currencyPair)
+	err := HttpGet(bo.httpClient, apiURL, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+type MarketsResp struct {
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+
+	Data []struct {
+		Name       string `json:"name"`
+		BaseScale  int    `json:"baseScale"`
+		QuoteScale int    `json:"quoteScale"`
+		BaseAsset  struct {
+			Symbol string `json:"symbol"`
+		} `json:"baseAsset"`
+		QuoteAsset struct {
+			Symbol string `json:"symbol"`
+		} `json:"quoteAsset"`
+	} `json:"data"`
+}
+
+func (bo *Bigone) GetMarkets() (*MarketsResp, error) {
+	var resp MarketsResp
+	err := HttpGet(bo.httpClient, bo.BaseURL+MARKETS_URI, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+type CandlesResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+
+	Data []struct {
+		Time   string `json:"time"`
+		Open   string `json:"open"`
+		High   string `json:"high"`
+		Low    string `json:"low"`
+		Close  string `json:"close"`
+		Volume string `json:"volume"`
+	} `json:"data"`
+}
+
+func (bo *Bigone) GetCandles(currencyPair, period string, limit int) (*CandlesResp, error) {
+	if limit > 500 {
+		limit = 500
+	}
+	var resp CandlesResp
+	apiURL := fmt.Sprintf(bo.BaseURL+CANDLES_URI, currencyPair, period, limit)
 	err := HttpGet(bo.httpClient, apiURL, nil, &resp)
 	if err != nil {
 		return nil, err
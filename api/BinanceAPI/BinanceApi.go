@@ -14,8 +14,8 @@ import (
 
 const (
 	API_BASE_URL = "https://api.binance.com/"
-	API_V1       = API_BASE_URL + "api/v1/"
-	API_V3       = API_BASE_URL + "api/v3/"
+	API_V1       = "api/v1/"
+	API_V3       = "api/v3/"
 
 	TICKER_URI             = "ticker/24hr?symbol=%s"
 	TICKERS_URI            = "ticker/allBookTickers"
@@ -23,68 +23,140 @@ const (
 	ACCOUNT_URI            = "account?"
 	ORDER_URI              = "order?"
 	UNFINISHED_ORDERS_INFO = "openOrders?"
+	MY_TRADES_URI          = "myTrades?"
+	USER_DATA_STREAM_URI   = "userDataStream"
+	EXCHANGE_INFO_URI      = "exchangeInfo"
+	KLINES_URI             = "klines?symbol=%s&interval=%s&limit=%d"
+	TRADES_URI             = "trades?symbol=%s&limit=%d"
 )
 
-var (
-	ACCESS_KEY string       = ""
-	SECRET_KEY string       = ""
-	httpClient *http.Client = &http.Client{}
-)
+// Client 币安的API客户端, 每个客户端使用自己的密钥, 可以同时运行任意多个账户
+type Client struct {
+	AccessKey  string
+	SecretKey  string
+	BaseURL    string //API请求地址, 要带最后的/
+	httpClient *http.Client
+}
 
-func init() {
-	//os.Setenv("HTTP_PROXY", "http://127.0.0.1:6667")
-	//os.Setenv("HTTPS_PROXY", "https://127.0.0.1:6667")
+// NewClient create a binance api client with the default base url
+func NewClient(client *http.Client, accessKey, secretKey string) *Client {
+	return &Client{
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		BaseURL:    API_BASE_URL,
+		httpClient: client,
+	}
 }
 
-func buildParamsSigned(postForm *url.Values) error {
+func (c *Client) buildParamsSigned(postForm *url.Values) error {
 	postForm.Set("recvWindow", "6000000")
 	tonce := strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
 	postForm.Set("timestamp", tonce)
 	payload := postForm.Encode()
-	sign, _ := GetParamHmacSHA256Sign(SECRET_KEY, payload)
+	sign, _ := GetParamHmacSHA256Sign(c.SecretKey, payload)
 	postForm.Set("signature", sign)
 	return nil
 }
 
-func GetDepth(size int, symbol string) (map[string]interface{}, error) {
+func (c *Client) GetDepth(size int, symbol string) (map[string]interface{}, error) {
 	if size > 100 {
 		size = 100
 	} else if size < 5 {
 		size = 5
 	}
 
-	apiUrl := fmt.Sprintf(API_V1+DEPTH_URI, symbol, size)
-	resp, err := HttpGet(httpClient, apiUrl)
+	apiUrl := fmt.Sprintf(c.BaseURL+API_V1+DEPTH_URI, symbol, size)
+	resp, err := HttpGet(c.httpClient, apiUrl)
 	return resp, err
 }
 
-func GetAccount() (map[string]interface{}, error) {
+// SymbolFilter 交易对的交易规则, 按 FilterType 区分: PRICE_FILTER, LOT_SIZE, MIN_NOTIONAL
+type SymbolFilter struct {
+	FilterType  string `json:"filterType"`
+	TickSize    string `json:"tickSize"`
+	MinQty      string `json:"minQty"`
+	StepSize    string `json:"stepSize"`
+	MinNotional string `json:"minNotional"`
+}
+
+// ExchangeInfo 交易所支持的所有交易对及交易规则
+type ExchangeInfo struct {
+	Symbols []struct {
+		Symbol     string         `json:"symbol"`
+		Status     string         `json:"status"`
+		BaseAsset  string         `json:"baseAsset"`
+		QuoteAsset string         `json:"quoteAsset"`
+		Filters    []SymbolFilter `json:"filters"`
+	} `json:"symbols"`
+}
+
+func (c *Client) GetExchangeInfo() (*ExchangeInfo, error) {
+	respData, err := NewHttpRequest(c.httpClient, "GET", c.BaseURL+API_V1+EXCHANGE_INFO_URI, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var info ExchangeInfo
+	if err = json.Unmarshal(respData, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) GetKlines(symbol, interval string, limit int) ([]interface{}, error) {
+	if limit > 1000 {
+		limit = 1000
+	}
+	apiUrl := fmt.Sprintf(c.BaseURL+API_V1+KLINES_URI, symbol, interval, limit)
+	return HttpGet3(c.httpClient, apiUrl, nil)
+}
+
+// GetKlinesSince get the klines since startTime, startTime is a timestamp in milliseconds
+func (c *Client) GetKlinesSince(symbol, interval string, startTime int64, limit int) ([]interface{}, error) {
+	if limit > 1000 {
+		limit = 1000
+	}
+	apiUrl := fmt.Sprintf(c.BaseURL+API_V1+KLINES_URI, symbol, interval, limit) + fmt.Sprintf("&startTime=%d", startTime)
+	return HttpGet3(c.httpClient, apiUrl, nil)
+}
+
+// GetTrades get the latest public trades of the market
+func (c *Client) GetTrades(symbol string, limit int) ([]interface{}, error) {
+	if limit > 1000 {
+		limit = 1000
+	}
+	apiUrl := fmt.Sprintf(c.BaseURL+API_V1+TRADES_URI, symbol, limit)
+	return HttpGet3(c.httpClient, apiUrl, nil)
+}
+
+func (c *Client) GetAccount() (map[string]interface{}, error) {
 	params := url.Values{}
-	buildParamsSigned(&params)
-	path := API_V3 + ACCOUNT_URI + params.Encode()
-	respmap, err := HttpGet2(httpClient, path, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	c.buildParamsSigned(&params)
+	path := c.BaseURL + API_V3 + ACCOUNT_URI + params.Encode()
+	respmap, err := HttpGet2(c.httpClient, path, map[string]string{"X-MBX-APIKEY": c.AccessKey})
 	return respmap, err
 }
 
-func placeOrder(amount, price string, symbol string, orderType, orderSide string) (map[string]interface{}, error) {
-	path := API_V3 + ORDER_URI
+func (c *Client) placeOrder(amount, price string, symbol string, orderType, orderSide string) (map[string]interface{}, error) {
 	params := url.Values{}
-	params.Set("symbol", symbol)
-	params.Set("side", orderSide)
-	params.Set("type", orderType)
-
 	params.Set("quantity", amount)
-	params.Set("type", "LIMIT")
-	params.Set("timeInForce", "GTC")
-
 	switch orderType {
 	case "LIMIT":
 		params.Set("price", price)
+		params.Set("timeInForce", "GTC")
 	}
+	return c.PlaceOrder(symbol, orderSide, orderType, params)
+}
+
+// PlaceOrder place an order with the params of its type, like quantity or quoteOrderQty, price and timeInForce
+func (c *Client) PlaceOrder(symbol, orderSide, orderType string, params url.Values) (map[string]interface{}, error) {
+	path := c.BaseURL + API_V3 + ORDER_URI
+	params.Set("symbol", symbol)
+	params.Set("side", orderSide)
+	params.Set("type", orderType)
 
-	buildParamsSigned(&params)
+	c.buildParamsSigned(&params)
 
-	resp, err := HttpPostForm2(httpClient, path, params, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	resp, err := HttpPostForm2(c.httpClient, path, params, map[string]string{"X-MBX-APIKEY": c.AccessKey})
 	//log.Println("resp:", string(resp), "err:", err)
 	if err != nil {
 		return nil, err
@@ -100,31 +172,31 @@ func placeOrder(amount, price string, symbol string, orderType, orderSide string
 	return respmap, nil
 }
 
-func LimitBuy(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "LIMIT", "BUY")
+func (c *Client) LimitBuy(amount, price string, symbol string) (map[string]interface{}, error) {
+	return c.placeOrder(amount, price, symbol, "LIMIT", "BUY")
 }
 
-func LimitSell(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "LIMIT", "SELL")
+func (c *Client) LimitSell(amount, price string, symbol string) (map[string]interface{}, error) {
+	return c.placeOrder(amount, price, symbol, "LIMIT", "SELL")
 }
 
-func MarketBuy(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "MARKET", "BUY")
+func (c *Client) MarketBuy(amount, price string, symbol string) (map[string]interface{}, error) {
+	return c.placeOrder(amount, price, symbol, "MARKET", "BUY")
 }
 
-func MarketSell(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "MARKET", "SELL")
+func (c *Client) MarketSell(amount, price string, symbol string) (map[string]interface{}, error) {
+	return c.placeOrder(amount, price, symbol, "MARKET", "SELL")
 }
 
-func CancelOrder(orderId string, symbol string) (bool, error) {
-	path := API_V3 + ORDER_URI
+func (c *Client) CancelOrder(orderId string, symbol string) (bool, error) {
+	path := c.BaseURL + API_V3 + ORDER_URI
 	params := url.Values{}
 	params.Set("symbol", symbol)
 	params.Set("orderId", orderId)
 
-	buildParamsSigned(&params)
+	c.buildParamsSigned(&params)
 
-	resp, err := HttpDeleteForm(httpClient, path, params, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	resp, err := HttpDeleteForm(c.httpClient, path, params, map[string]string{"X-MBX-APIKEY": c.AccessKey})
 
 	//log.Println("resp:", string(resp), "err:", err)
 	if err != nil {
@@ -146,7 +218,7 @@ func CancelOrder(orderId string, symbol string) (bool, error) {
 	return true, nil
 }
 
-func GetOneOrder(orderId string, symbol string) (map[string]interface{}, error) {
+func (c *Client) GetOneOrder(orderId string, symbol string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Set("symbol", symbol)
 	if orderId != "" {
@@ -154,20 +226,54 @@ func GetOneOrder(orderId string, symbol string) (map[string]interface{}, error)
 	}
 	params.Set("orderId", orderId)
 
-	buildParamsSigned(&params)
-	path := API_V3 + ORDER_URI + params.Encode()
+	c.buildParamsSigned(&params)
+	path := c.BaseURL + API_V3 + ORDER_URI + params.Encode()
+
+	respmap, err := HttpGet2(c.httpClient, path, map[string]string{"X-MBX-APIKEY": c.AccessKey})
+	return respmap, err
+}
+
+func (c *Client) GetUnfinishOrders(symbol string) ([]interface{}, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+
+	c.buildParamsSigned(&params)
+	path := c.BaseURL + API_V3 + UNFINISHED_ORDERS_INFO + params.Encode()
 
-	respmap, err := HttpGet2(httpClient, path, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	respmap, err := HttpGet3(c.httpClient, path, map[string]string{"X-MBX-APIKEY": c.AccessKey})
 	return respmap, err
 }
 
-func GetUnfinishOrders(symbol string) ([]interface{}, error) {
+func (c *Client) GetMyTrades(symbol string, limit int) ([]interface{}, error) {
 	params := url.Values{}
 	params.Set("symbol", symbol)
+	params.Set("limit", fmt.Sprint(limit))
 
-	buildParamsSigned(&params)
-	path := API_V3 + UNFINISHED_ORDERS_INFO + params.Encode()
+	c.buildParamsSigned(&params)
+	path := c.BaseURL + API_V3 + MY_TRADES_URI + params.Encode()
 
-	respmap, err := HttpGet3(httpClient, path, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	respmap, err := HttpGet3(c.httpClient, path, map[string]string{"X-MBX-APIKEY": c.AccessKey})
 	return respmap, err
 }
+
+// StartUserDataStream get a listenKey of the user data stream, it expires after 60 minutes without keepalive
+func (c *Client) StartUserDataStream() (string, error) {
+	path := c.BaseURL + API_V3 + USER_DATA_STREAM_URI
+	resp, err := NewHttpRequest(c.httpClient, "POST", path, "", map[string]string{"X-MBX-APIKEY": c.AccessKey})
+	if err != nil {
+		return "", err
+	}
+	var respmap struct {
+		ListenKey string `json:"listenKey"`
+	}
+	if err = json.Unmarshal(resp, &respmap); err != nil {
+		return "", err
+	}
+	return respmap.ListenKey, nil
+}
+
+func (c *Client) KeepAliveUserDataStream(listenKey string) error {
+	path := c.BaseURL + API_V3 + USER_DATA_STREAM_URI + "?listenKey=" + listenKey
+	_, err := NewHttpRequest(c.httpClient, "PUT", path, "", map[string]string{"X-MBX-APIKEY": c.AccessKey})
+	return err
+}
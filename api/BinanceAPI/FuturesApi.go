@@ -0,0 +1,83 @@
+package BinanceAPI
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FUTURES_BASE_URL = "https://fapi.binance.com/"
+)
+
+// FuturesClient 币安 U 本位合约的API客户端, 每个客户端使用自己的密钥
+type FuturesClient struct {
+	AccessKey  string
+	SecretKey  string
+	BaseURL    string //API请求地址, 要带最后的/
+	httpClient *http.Client
+}
+
+// NewFuturesClient create a binance futures api client with the default base url
+func NewFuturesClient(client *http.Client, accessKey, secretKey string) *FuturesClient {
+	return &FuturesClient{
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		BaseURL:    FUTURES_BASE_URL,
+		httpClient: client,
+	}
+}
+
+// APIError 币安返回的错误码和错误信息
+type APIError struct {
+	Code int64  `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("<APIError> code=%d, msg=%s", e.Code, e.Msg)
+}
+
+// Request send a request to the futures api and get the raw response, the signed requests carry the timestamp and the signature,
+// the error returned by binance is an *APIError
+func (c *FuturesClient) Request(method, path string, params url.Values, signed bool) ([]byte, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	headers := map[string]string{}
+	query := params.Encode()
+	if signed {
+		params.Set("recvWindow", "5000")
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixNano()/1000000, 10))
+		query = params.Encode()
+		sign, _ := GetParamHmacSHA256Sign(c.SecretKey, query)
+		query += "&signature=" + sign
+		headers["X-MBX-APIKEY"] = c.AccessKey
+	}
+	reqUrl := c.BaseURL + path
+	body := ""
+	if method == "GET" || method == "DELETE" {
+		if query != "" {
+			reqUrl += "?" + query
+		}
+	} else {
+		body = query
+		headers["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+	data, err := NewHttpRequest(c.httpClient, method, reqUrl, body, headers)
+	if err != nil {
+		// 出错时响应的内容是 {"code": -1121, "msg": "Invalid symbol."}
+		if i := strings.Index(err.Error(), "{"); i >= 0 {
+			apiErr := &APIError{}
+			if json.Unmarshal([]byte(err.Error()[i:]), apiErr) == nil && apiErr.Code != 0 {
+				return nil, apiErr
+			}
+		}
+		return nil, err
+	}
+	return data, nil
+}
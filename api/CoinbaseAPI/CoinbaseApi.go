@@ -0,0 +1,253 @@
+package CoinbaseAPI
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BASE_URL = "https://api.exchange.coinbase.com/"
+)
+
+// pageSize 分页接口每页的最大数量
+var pageSize = 100
+
+// Client Coinbase Exchange 的API客户端, 私有接口需要密钥、密钥的口令和 base64 编码的 secret
+type Client struct {
+	AccessKey  string
+	SecretKey  string
+	Passphrase string
+	BaseURL    string //API请求地址, 要带最后的/
+	httpClient *http.Client
+}
+
+// NewClient create a coinbase exchange api client with the default base url
+func NewClient(client *http.Client, accessKey, secretKey, passphrase string) *Client {
+	return &Client{
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		Passphrase: passphrase,
+		BaseURL:    BASE_URL,
+		httpClient: client,
+	}
+}
+
+// APIError coinbase 返回的 HTTP 状态码和错误信息
+type APIError struct {
+	Status  int    `json:"-"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("<APIError> status=%d, message=%s", e.Status, e.Message)
+}
+
+// Sign get the signature of a request, it is the base64 encoded HMAC-SHA256 of timestamp + method + requestPath + body,
+// the key is the base64 decoded secret
+func Sign(secretKey, timestamp, method, requestPath, body string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(secretKey)
+	if err != nil {
+		return "", fmt.Errorf("the secret key is not base64 encoded: %v", err)
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// Request send a request and get the raw response and the cursor of the next page, the body is sent as json,
+// the error returned by coinbase is an *APIError
+func (c *Client) Request(method, path string, params url.Values, body interface{}, signed bool) ([]byte, string, error) {
+	requestPath := "/" + strings.TrimPrefix(path, "/")
+	if len(params) > 0 {
+		requestPath += "?" + params.Encode()
+	}
+	payload := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, "", err
+		}
+		payload = string(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+requestPath, bytes.NewBufferString(payload))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if signed {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sign, err := Sign(c.SecretKey, timestamp, method, requestPath, payload)
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("CB-ACCESS-KEY", c.AccessKey)
+		req.Header.Set("CB-ACCESS-SIGN", sign)
+		req.Header.Set("CB-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("CB-ACCESS-PASSPHRASE", c.Passphrase)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("[%s %s] HTTP Error Info: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// 出错时响应的内容是 {"message": "Insufficient funds"}
+		apiErr := &APIError{Status: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = fmt.Sprintf("[%s %s] HTTP Status: %d", method, path, resp.StatusCode)
+		}
+		return nil, "", apiErr
+	}
+	return data, resp.Header.Get("CB-AFTER"), nil
+}
+
+// get send a request and decode the response into v
+func (c *Client) get(path string, params url.Values, signed bool, v interface{}) (string, error) {
+	data, after, err := c.Request("GET", path, params, nil, signed)
+	if err != nil {
+		return "", err
+	}
+	return after, json.Unmarshal(data, v)
+}
+
+// Products get all the products of the exchange
+func (c *Client) Products() ([]Product, error) {
+	products := []Product{}
+	_, err := c.get("products", nil, false, &products)
+	return products, err
+}
+
+// Accounts get the balances of all the currencies in the profile of the api key
+func (c *Client) Accounts() ([]Account, error) {
+	accounts := []Account{}
+	_, err := c.get("accounts", nil, true, &accounts)
+	return accounts, err
+}
+
+// Book get the aggregated order book of a product
+func (c *Client) Book(productID string) (Book, error) {
+	book := Book{}
+	_, err := c.get("products/"+productID+"/book", url.Values{"level": {"2"}}, false, &book)
+	return book, err
+}
+
+// Candles get the candles of a product between start and end in time order, coinbase returns at most 300 candles,
+// the granularity is in seconds and the zero start or end is left to the exchange
+func (c *Client) Candles(productID string, granularity int64, start, end int64) ([]Candle, error) {
+	params := url.Values{"granularity": {strconv.FormatInt(granularity, 10)}}
+	if start > 0 {
+		params.Set("start", time.Unix(start, 0).UTC().Format(time.RFC3339))
+	}
+	if end > 0 {
+		params.Set("end", time.Unix(end, 0).UTC().Format(time.RFC3339))
+	}
+	rows := [][]json.Number{}
+	if _, err := c.get("products/"+productID+"/candles", params, false, &rows); err != nil {
+		return nil, err
+	}
+	candles := []Candle{}
+	// 返回的是 [time, low, high, open, close, volume], 最新的在前面
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		if len(row) < 6 {
+			continue
+		}
+		t, _ := row[0].Int64()
+		candle := Candle{Time: t}
+		for j, v := range []*float64{&candle.Low, &candle.High, &candle.Open, &candle.Close, &candle.Volume} {
+			*v, _ = row[j+1].Float64()
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// Trades get the latest public trades of a product, the newest first
+func (c *Client) Trades(productID string, limit int) ([]Trade, error) {
+	params := url.Values{}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	trades := []Trade{}
+	_, err := c.get("products/"+productID+"/trades", params, false, &trades)
+	return trades, err
+}
+
+// PlaceOrder place an order and get it back
+func (c *Client) PlaceOrder(order OrderRequest) (Order, error) {
+	result := Order{}
+	data, _, err := c.Request("POST", "orders", nil, order, true)
+	if err != nil {
+		return result, err
+	}
+	return result, json.Unmarshal(data, &result)
+}
+
+// GetOrder get an order by its id
+func (c *Client) GetOrder(id string) (Order, error) {
+	order := Order{}
+	_, err := c.get("orders/"+id, nil, true, &order)
+	return order, err
+}
+
+// OpenOrders get all the open orders of a product, the pages are walked by the cursor
+func (c *Client) OpenOrders(productID string) ([]Order, error) {
+	orders := []Order{}
+	params := url.Values{"product_id": {productID}, "status": {"open"}, "limit": {strconv.Itoa(pageSize)}}
+	for {
+		page := []Order{}
+		after, err := c.get("orders", params, true, &page)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, page...)
+		if len(page) < pageSize || after == "" || after == params.Get("after") {
+			return orders, nil
+		}
+		params.Set("after", after)
+	}
+}
+
+// Fills get the latest fills of a product, the newest first, the pages of fills are walked backwards by the cursor
+// in the CB-AFTER header until there are limit fills or no more fills
+func (c *Client) Fills(productID string, limit int) ([]Fill, error) {
+	fills := []Fill{}
+	params := url.Values{"product_id": {productID}, "limit": {strconv.Itoa(pageSize)}}
+	for limit <= 0 || len(fills) < limit {
+		page := []Fill{}
+		after, err := c.get("fills", params, true, &page)
+		if err != nil {
+			return nil, err
+		}
+		fills = append(fills, page...)
+		if len(page) < pageSize || after == "" || after == params.Get("after") {
+			break
+		}
+		params.Set("after", after)
+	}
+	if limit > 0 && len(fills) > limit {
+		fills = fills[:limit]
+	}
+	return fills, nil
+}
+
+// CancelOrder cancel an order by its id
+func (c *Client) CancelOrder(id string) error {
+	_, _, err := c.Request("DELETE", "orders/"+id, nil, nil, true)
+	return err
+}
@@ -0,0 +1,396 @@
+package CoinbaseAPI
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 模拟服务器和它的密钥是导出的, CoinbaseAPI_test 中的交易所测试也使用它们
+const (
+	MockKey        = "test-key"
+	MockPassphrase = "test-passphrase"
+)
+
+var MockSecret = base64.StdEncoding.EncodeToString([]byte("test-secret"))
+
+// Route 模拟服务器的一个接口, 返回 testdata 中的文件
+type Route struct {
+	Status  int    //HTTP 状态码, 为 0 时是 200
+	Fixture string //testdata 中的文件名
+	After   string //CB-AFTER 响应头, 下一页的游标
+	Private bool   //是否需要签名
+}
+
+// MockServer 按照 "METHOD /path" 或者 "METHOD /path?after=cursor" 返回固定的响应, 并记录收到的请求
+type MockServer struct {
+	*httptest.Server
+	t      *testing.T
+	routes map[string]Route
+
+	mutex    sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+// NewMockServer start a mock server and create a client of it
+func NewMockServer(t *testing.T, routes map[string]Route) (*MockServer, *Client) {
+	s := &MockServer{t: t, routes: routes}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	client := NewClient(s.Client(), MockKey, MockSecret, MockPassphrase)
+	client.BaseURL = s.URL + "/"
+	return s, client
+}
+
+func (s *MockServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mutex.Lock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, string(body))
+	s.mutex.Unlock()
+
+	key := r.Method + " " + r.URL.Path
+	if after := r.URL.Query().Get("after"); after != "" {
+		key += "?after=" + after
+	}
+	rt, ok := s.routes[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "NotFound"}`))
+		return
+	}
+	if rt.Private {
+		if err := checkSign(r, string(body)); err != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "` + err + `"}`))
+			return
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", rt.Fixture))
+	if err != nil {
+		s.t.Errorf("read fixture %s: %v", rt.Fixture, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if rt.After != "" {
+		w.Header().Set("CB-AFTER", rt.After)
+	}
+	if rt.Status != 0 {
+		w.WriteHeader(rt.Status)
+	}
+	w.Write(data)
+}
+
+// Requests get the requests the mock server received and their bodies
+func (s *MockServer) Requests() ([]*http.Request, []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests, s.bodies
+}
+
+// checkSign check the auth headers of a private request like coinbase does
+func checkSign(r *http.Request, body string) string {
+	if r.Header.Get("CB-ACCESS-KEY") != MockKey {
+		return "invalid api key"
+	}
+	if r.Header.Get("CB-ACCESS-PASSPHRASE") != MockPassphrase {
+		return "invalid passphrase"
+	}
+	timestamp := r.Header.Get("CB-ACCESS-TIMESTAMP")
+	sign, _ := Sign(MockSecret, timestamp, r.Method, r.URL.RequestURI(), body)
+	if timestamp == "" || r.Header.Get("CB-ACCESS-SIGN") != sign {
+		return "invalid signature"
+	}
+	return ""
+}
+
+// Count get how many requests are sent to a path
+func (s *MockServer) Count(method, path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.Method == method && r.URL.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSign(t *testing.T) {
+	// 用 openssl 计算的结果:
+	// printf '1687255872GET/accounts' | openssl dgst -sha256 -hmac test-secret -binary | base64
+	sign, err := Sign(MockSecret, "1687255872", "GET", "/accounts", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sign != "5cTGPo0GGnKn81Zw8wLINKwOTU+7wV7/LymSUtvtfHU=" {
+		t.Errorf("unexpected signature %s", sign)
+	}
+	if _, err := Sign("not base64!", "1687255872", "GET", "/accounts", ""); err == nil {
+		t.Error("expect an error for the secret which is not base64 encoded")
+	}
+}
+
+func TestProducts(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /products": {Fixture: "products.json"},
+	})
+	defer server.Close()
+	products, err := client.Products()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 3 {
+		t.Fatalf("expect 3 products, got %d", len(products))
+	}
+	p := products[1]
+	if p.ID != "ETH-BTC" || p.BaseCurrency != "ETH" || p.QuoteCurrency != "BTC" || p.QuoteIncrement != "0.00001" ||
+		p.BaseMinSize != "0.01" || p.MinMarketFunds != "0.0001" || p.Status != "online" {
+		t.Errorf("unexpected product %+v", p)
+	}
+	if !products[2].TradingDisabled {
+		t.Errorf("expect the delisted product to be disabled")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /accounts": {Fixture: "accounts.json", Private: true},
+	})
+	defer server.Close()
+	accounts, err := client.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].Currency != "BTC" || accounts[0].Available != "1.25" || accounts[0].Hold != "0.2500000000000000" {
+		t.Errorf("unexpected accounts %+v", accounts)
+	}
+	if server.Count("GET", "/accounts") != 1 {
+		t.Errorf("expect 1 request")
+	}
+
+	client.SecretKey = base64.StdEncoding.EncodeToString([]byte("wrong-secret"))
+	_, err = client.Accounts()
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.Status != http.StatusUnauthorized || apiErr.Message != "invalid signature" {
+		t.Errorf("expect an auth error, got %v", err)
+	}
+}
+
+func TestBook(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /products/BTC-USD/book": {Fixture: "book.json"},
+	})
+	defer server.Close()
+	book, err := client.Book("BTC-USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Bids) != 3 || len(book.Asks) != 2 {
+		t.Fatalf("unexpected book %+v", book)
+	}
+	if price, _ := book.Bids[0][0].Float64(); price != 29000.01 {
+		t.Errorf("unexpected best bid %v", price)
+	}
+	if amount, _ := book.Asks[1][1].Float64(); amount != 2 {
+		t.Errorf("unexpected ask amount %v", amount)
+	}
+	if level := server.requests[0].URL.Query().Get("level"); level != "2" {
+		t.Errorf("expect level 2, got %q", level)
+	}
+}
+
+func TestCandles(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /products/BTC-USD/candles": {Fixture: "candles.json"},
+	})
+	defer server.Close()
+	candles, err := client.Candles("BTC-USD", 60, 1687255200, 1687255380)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 3 {
+		t.Fatalf("expect 3 candles, got %d", len(candles))
+	}
+	// 按时间从旧到新排列
+	first, last := candles[0], candles[2]
+	if first.Time != 1687255200 || first.Open != 28975 || first.High != 28990 || first.Low != 28970.25 || first.Close != 28985.5 || first.Volume != 1.125 {
+		t.Errorf("unexpected first candle %+v", first)
+	}
+	if last.Time != 1687255320 || last.Close != 29005.2 {
+		t.Errorf("unexpected last candle %+v", last)
+	}
+	query := server.requests[0].URL.Query()
+	if query.Get("granularity") != "60" || query.Get("start") != "2023-06-20T10:00:00Z" || query.Get("end") != "2023-06-20T10:03:00Z" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestTrades(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /products/BTC-USD/trades": {Fixture: "trades.json"},
+	})
+	defer server.Close()
+	trades, err := client.Trades("BTC-USD", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].TradeID != 523000102 || trades[0].Side != "sell" || trades[1].Price != "29000.02" {
+		t.Errorf("unexpected trades %+v", trades)
+	}
+	if trades[0].Time.Unix() != 1687255872 {
+		t.Errorf("unexpected trade time %v", trades[0].Time)
+	}
+}
+
+func TestPlaceOrder(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"POST /orders": {Fixture: "order.json", Private: true},
+	})
+	defer server.Close()
+	order, err := client.PlaceOrder(OrderRequest{Type: "limit", Side: "buy", ProductID: "BTC-USD", Price: "29000", Size: "0.01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.ID != "d0c5340b-6d6c-49d9-b567-48c4bfca13d2" || order.Status != "open" {
+		t.Errorf("unexpected order %+v", order)
+	}
+	body := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(server.bodies[0]), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["type"] != "limit" || body["side"] != "buy" || body["product_id"] != "BTC-USD" || body["price"] != "29000" || body["size"] != "0.01" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if _, ok := body["funds"]; ok {
+		t.Errorf("expect no funds in a limit order")
+	}
+}
+
+func TestPlaceOrderError(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"POST /orders": {Fixture: "error_insufficient_funds.json", Status: http.StatusBadRequest, Private: true},
+	})
+	defer server.Close()
+	_, err := client.PlaceOrder(OrderRequest{Type: "market", Side: "buy", ProductID: "BTC-USD", Size: "100"})
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("expect an *APIError, got %v", err)
+	}
+	if apiErr.Status != http.StatusBadRequest || apiErr.Message != "Insufficient funds" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /orders/d0c5340b-6d6c-49d9-b567-48c4bfca13d2": {Fixture: "order.json", Private: true},
+	})
+	defer server.Close()
+	order, err := client.GetOrder("d0c5340b-6d6c-49d9-b567-48c4bfca13d2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Price != "29000.00000000" || order.Size != "0.01000000" || order.FilledSize != "0.00500000" ||
+		order.FillFees != "0.1450000000000000" || order.Side != "buy" || order.ProductID != "BTC-USD" {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if _, err := client.GetOrder("unknown"); err == nil {
+		t.Error("expect an error for an unknown order")
+	}
+}
+
+func TestOpenOrders(t *testing.T) {
+	defer func(size int) { pageSize = size }(pageSize)
+	pageSize = 2
+	server, client := NewMockServer(t, map[string]Route{
+		"GET /orders":                {Fixture: "orders.json", After: "cursor-1", Private: true},
+		"GET /orders?after=cursor-1": {Fixture: "orders_2.json", Private: true},
+	})
+	defer server.Close()
+	orders, err := client.OpenOrders("BTC-USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 3 || orders[1].Side != "sell" {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+	query := server.requests[0].URL.Query()
+	if query.Get("product_id") != "BTC-USD" || query.Get("status") != "open" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestFills(t *testing.T) {
+	defer func(size int) { pageSize = size }(pageSize)
+	pageSize = 2
+	routes := map[string]Route{
+		"GET /fills":           {Fixture: "fills_1.json", After: "104", Private: true},
+		"GET /fills?after=104": {Fixture: "fills_2.json", After: "102", Private: true},
+		"GET /fills?after=102": {Fixture: "fills_3.json", After: "101", Private: true},
+	}
+
+	server, client := NewMockServer(t, routes)
+	defer server.Close()
+	fills, err := client.Fills("BTC-USD", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 5 {
+		t.Fatalf("expect all the 5 fills, got %d", len(fills))
+	}
+	for i, fill := range fills {
+		if fill.TradeID != int64(105-i) {
+			t.Errorf("expect the newest fill first, got %d at %d", fill.TradeID, i)
+		}
+	}
+	// 最后一页不满, 不再请求下一页
+	if n := server.Count("GET", "/fills"); n != 3 {
+		t.Errorf("expect 3 pages, got %d", n)
+	}
+	if fills[1].Fee != "1.737" || fills[1].Liquidity != "T" || fills[1].OrderID != "4a7e23c4-26c1-4ab2-a2c6-8f2e5d0f0a11" {
+		t.Errorf("unexpected fill %+v", fills[1])
+	}
+
+	server.Close()
+	server, client = NewMockServer(t, routes)
+	defer server.Close()
+	fills, err = client.Fills("BTC-USD", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 3 || fills[2].TradeID != 103 {
+		t.Errorf("unexpected fills %+v", fills)
+	}
+	if n := server.Count("GET", "/fills"); n != 2 {
+		t.Errorf("expect 2 pages, got %d", n)
+	}
+	if query := server.requests[1].URL.Query(); query.Get("after") != "104" || query.Get("product_id") != "BTC-USD" || query.Get("limit") != "2" {
+		t.Errorf("unexpected query of the second page %v", query)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	server, client := NewMockServer(t, map[string]Route{
+		"DELETE /orders/d0c5340b-6d6c-49d9-b567-48c4bfca13d2": {Fixture: "cancel.json", Private: true},
+	})
+	defer server.Close()
+	if err := client.CancelOrder("d0c5340b-6d6c-49d9-b567-48c4bfca13d2"); err != nil {
+		t.Fatal(err)
+	}
+	if server.Count("DELETE", "/orders/d0c5340b-6d6c-49d9-b567-48c4bfca13d2") != 1 {
+		t.Error("expect 1 cancel request")
+	}
+	err := client.CancelOrder("unknown")
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Status != http.StatusNotFound || !strings.Contains(apiErr.Message, "NotFound") {
+		t.Errorf("expect a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package CoinbaseAPI_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/HunterUPP/QuantBot/api"
+	"github.com/HunterUPP/QuantBot/api/CoinbaseAPI"
+	_ "github.com/HunterUPP/QuantBot/api/internal/testenv" //测试的配置, 数据库在内存中
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+// coinbaseExchange the methods of the coinbase adapter used by the tests
+type coinbaseExchange interface {
+	api.Exchange
+	api.TypedExchange
+	RecordsSince(stockType, period string, since int64, size int) ([]api.Record, error)
+}
+
+// newTestExchange start a mock server with the products and create a coinbase adapter of it
+func newTestExchange(t *testing.T, secretKey string, routes map[string]CoinbaseAPI.Route) (*CoinbaseAPI.MockServer, coinbaseExchange) {
+	routes["GET /products"] = CoinbaseAPI.Route{Fixture: "products.json"}
+	server, _ := CoinbaseAPI.NewMockServer(t, routes)
+	e := api.NewCoinbase(api.Option{
+		Type:       constant.Coinbase,
+		Name:       "coinbase",
+		AccessKey:  CoinbaseAPI.MockKey,
+		SecretKey:  secretKey,
+		Passphrase: CoinbaseAPI.MockPassphrase,
+		BaseURL:    server.URL,
+	})
+	return server, e.(coinbaseExchange)
+}
+
+func TestExchangeRecordsSince(t *testing.T) {
+	server, e := newTestExchange(t, CoinbaseAPI.MockSecret, map[string]CoinbaseAPI.Route{
+		"GET /products/BTC-USD/candles": {Fixture: "candles.json"},
+	})
+	defer server.Close()
+	// K线周期对应 coinbase 的 granularity 秒数
+	for i, c := range []struct {
+		period      string
+		granularity string
+	}{{"M", "60"}, {"M15", "900"}, {"H", "3600"}, {"D", "86400"}} {
+		records, err := e.RecordsSince("BTC/USD", c.period, 1687255200, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 || records[0].Time != 1687255200 || records[0].Open != 28975 || records[2].Close != 29005.2 {
+			t.Errorf("unexpected records %+v", records)
+		}
+		requests, _ := server.Requests()
+		query := requests[len(requests)-1].URL.Query()
+		if query.Get("granularity") != c.granularity {
+			t.Errorf("%d: expect granularity %s of %s, got %s", i, c.granularity, c.period, query.Get("granularity"))
+		}
+	}
+	if _, err := e.RecordsSince("BTC/USD", "H4", 1687255200, 3); api.ErrorKind(err) != api.ErrNotSupported {
+		t.Errorf("expect a not supported error for H4, got %v", err)
+	}
+}
+
+func TestExchangeTrades(t *testing.T) {
+	server, e := newTestExchange(t, CoinbaseAPI.MockSecret, map[string]CoinbaseAPI.Route{
+		"GET /fills": {Fixture: "fills_1.json", Private: true},
+	})
+	defer server.Close()
+	trades, err := e.Trades("BTC/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("expect 2 trades, got %d", len(trades))
+	}
+	// 按时间从旧到新排列, 手续费是计价币种
+	first, last := trades[0], trades[1]
+	if first.ID != "104" || first.OrderID != "4a7e23c4-26c1-4ab2-a2c6-8f2e5d0f0a11" || first.Price != 28950.5 || first.Amount != 0.01 ||
+		first.Fee != 1.737 || first.FeeCurrency != "USD" || first.TradeType != constant.TradeTypeSell || first.StockType != "BTC/USD" {
+		t.Errorf("unexpected trade %+v", first)
+	}
+	if last.ID != "105" || last.TradeType != constant.TradeTypeBuy || last.Time != 1687255872 {
+		t.Errorf("unexpected trade %+v", last)
+	}
+}
+
+func TestExchangePlaceOrder(t *testing.T) {
+	server, e := newTestExchange(t, CoinbaseAPI.MockSecret, map[string]CoinbaseAPI.Route{
+		"POST /orders": {Fixture: "order.json", Private: true},
+	})
+	defer server.Close()
+	for i, c := range []struct {
+		tradeType string
+		price     float64
+		amount    float64
+		options   interface{}
+		expect    map[string]interface{}
+	}{
+		// 市价买单的数量是计价币种的金额
+		{constant.TradeTypeBuy, 0, 100, nil, map[string]interface{}{"type": "market", "side": "buy", "funds": "100"}},
+		{constant.TradeTypeSell, -1, 0.5, nil, map[string]interface{}{"type": "market", "side": "sell", "size": "0.5"}},
+		{constant.TradeTypeBuy, 29000, 0.01, map[string]interface{}{"postOnly": true},
+			map[string]interface{}{"type": "limit", "price": "29000", "size": "0.01", "post_only": true}},
+		{constant.TradeTypeSell, 29100, 0.01, map[string]interface{}{"timeInForce": "ioc"},
+			map[string]interface{}{"type": "limit", "price": "29100", "time_in_force": "IOC"}},
+	} {
+		msgs := []interface{}{}
+		if c.options != nil {
+			msgs = append(msgs, c.options)
+		}
+		id, err := e.PlaceOrder(c.tradeType, "BTC/USD", c.price, c.amount, msgs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != "d0c5340b-6d6c-49d9-b567-48c4bfca13d2" {
+			t.Errorf("%d: unexpected id %s", i, id)
+		}
+		_, bodies := server.Requests()
+		body := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(bodies[len(bodies)-1]), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["product_id"] != "BTC-USD" {
+			t.Errorf("%d: unexpected product %v", i, body["product_id"])
+		}
+		for k, v := range c.expect {
+			if body[k] != v {
+				t.Errorf("%d: expect %s = %v, got %v", i, k, v, body[k])
+			}
+		}
+		if c.price <= 0 && c.tradeType == constant.TradeTypeBuy {
+			if _, ok := body["size"]; ok {
+				t.Errorf("%d: expect no size in a market buy", i)
+			}
+		}
+	}
+	if _, err := e.PlaceOrder(constant.TradeTypeBuy, "BTC/USD", 0, 100, map[string]interface{}{"postOnly": true}); err == nil {
+		t.Error("expect an error for a post-only market order")
+	}
+}
+
+func TestExchangeErrorKinds(t *testing.T) {
+	server, e := newTestExchange(t, CoinbaseAPI.MockSecret, map[string]CoinbaseAPI.Route{
+		"POST /orders":                  {Fixture: "error_insufficient_funds.json", Status: http.StatusBadRequest, Private: true},
+		"GET /products/BTC-USD/candles": {Fixture: "error_rate_limit.json", Status: http.StatusTooManyRequests},
+	})
+	defer server.Close()
+	if _, err := e.PlaceOrder(constant.TradeTypeBuy, "BTC/USD", 29000, 100); api.ErrorKind(err) != api.ErrInsufficientBalance {
+		t.Errorf("expect an insufficient balance error, got %v", err)
+	}
+	if _, err := e.RecordsSince("BTC/USD", "M", 1687255200, 3); api.ErrorKind(err) != api.ErrRateLimited {
+		t.Errorf("expect a rate limited error, got %v", err)
+	}
+	if _, err := e.Ticker("XXX/USD", 1); api.ErrorKind(err) != api.ErrInvalidSymbol {
+		t.Errorf("expect an invalid symbol error, got %v", err)
+	}
+	server.Close()
+
+	wrongSecret := base64.StdEncoding.EncodeToString([]byte("wrong-secret"))
+	server, e = newTestExchange(t, wrongSecret, map[string]CoinbaseAPI.Route{
+		"GET /accounts": {Fixture: "accounts.json", Private: true},
+	})
+	defer server.Close()
+	if _, err := e.Account(); api.ErrorKind(err) != api.ErrAuth {
+		t.Errorf("expect an auth error, got %v", err)
+	}
+	if e.GetAccount() != false || e.GetLastError() == nil {
+		t.Error("expect GetAccount to fail and keep the last error")
+	}
+}
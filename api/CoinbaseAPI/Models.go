@@ -0,0 +1,94 @@
+package CoinbaseAPI
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Product 交易对, 数字都是字符串
+type Product struct {
+	ID              string `json:"id"`
+	BaseCurrency    string `json:"base_currency"`
+	QuoteCurrency   string `json:"quote_currency"`
+	BaseIncrement   string `json:"base_increment"`
+	QuoteIncrement  string `json:"quote_increment"`
+	BaseMinSize     string `json:"base_min_size"`
+	MinMarketFunds  string `json:"min_market_funds"`
+	Status          string `json:"status"`
+	TradingDisabled bool   `json:"trading_disabled"`
+}
+
+// Account 一个币种的资金, hold 是挂单冻结的数量
+type Account struct {
+	ID        string `json:"id"`
+	Currency  string `json:"currency"`
+	Balance   string `json:"balance"`
+	Hold      string `json:"hold"`
+	Available string `json:"available"`
+}
+
+// Book 深度, 每一档是 [price, size, num_orders]
+type Book struct {
+	Sequence int64           `json:"sequence"`
+	Bids     [][]json.Number `json:"bids"`
+	Asks     [][]json.Number `json:"asks"`
+}
+
+// Candle K线, 时间是秒
+type Candle struct {
+	Time   int64
+	Low    float64
+	High   float64
+	Open   float64
+	Close  float64
+	Volume float64
+}
+
+// Trade 公开成交, side 是挂单方的方向
+type Trade struct {
+	TradeID int64     `json:"trade_id"`
+	Price   string    `json:"price"`
+	Size    string    `json:"size"`
+	Side    string    `json:"side"`
+	Time    time.Time `json:"time"`
+}
+
+// OrderRequest 下单的参数, 市价单不需要 price
+type OrderRequest struct {
+	Type        string `json:"type"`
+	Side        string `json:"side"`
+	ProductID   string `json:"product_id"`
+	Price       string `json:"price,omitempty"`
+	Size        string `json:"size,omitempty"`
+	Funds       string `json:"funds,omitempty"`
+	TimeInForce string `json:"time_in_force,omitempty"`
+	PostOnly    bool   `json:"post_only,omitempty"`
+}
+
+// Order 订单
+type Order struct {
+	ID            string    `json:"id"`
+	Price         string    `json:"price"`
+	Size          string    `json:"size"`
+	ProductID     string    `json:"product_id"`
+	Side          string    `json:"side"`
+	Type          string    `json:"type"`
+	Status        string    `json:"status"`
+	FilledSize    string    `json:"filled_size"`
+	FillFees      string    `json:"fill_fees"`
+	ExecutedValue string    `json:"executed_value"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Fill 自己的成交, 手续费是计价币种
+type Fill struct {
+	TradeID   int64     `json:"trade_id"`
+	ProductID string    `json:"product_id"`
+	OrderID   string    `json:"order_id"`
+	Price     string    `json:"price"`
+	Size      string    `json:"size"`
+	Fee       string    `json:"fee"`
+	Side      string    `json:"side"`
+	Liquidity string    `json:"liquidity"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,14 +1,31 @@
-﻿package config
+package config
 
-// API KEY
-var (
-	ACCESS_KEY string = ""
-	SECRET_KEY string = ""
-	ACCOUNT_ID string = ""
-)
+import "net/http"
 
 // API请求地址, 不要带最后的/
 const (
 	MARKET_URL string = "https://api.huobi.pro"
 	TRADE_URL  string = "https://api.huobi.pro"
+	FUTURE_URL string = "https://api.hbdm.com" //合约的行情和交易API
 )
+
+// Config 一个火币账户的API配置, 每个账户使用自己的配置, 可以同时运行任意多个账户
+type Config struct {
+	AccessKey  string
+	SecretKey  string
+	AccountID  string       //现货账户ID, 通过GetAccounts()获取
+	MarketURL  string       //行情API请求地址, 不要带最后的/
+	TradeURL   string       //交易API请求地址, 不要带最后的/
+	HTTPClient *http.Client //发送请求的HTTP客户端
+}
+
+// New create a config with the default urls
+func New(client *http.Client, accessKey, secretKey string) *Config {
+	return &Config{
+		HTTPClient: client,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		MarketURL:  MARKET_URL,
+		TradeURL:   TRADE_URL,
+	}
+}
@@ -1,11 +1,13 @@
 package models
 
 type SymbolsData struct {
-	BaseCurrency    string `json:"base-currency"`    // 基础币种
-	QuoteCurrency   string `json:"quote-currency"`   // 计价币种
-	PricePrecision  int    `json:"price-precision"`  // 价格精度位数(0为个位)
-	AmountPrecision int    `json:"amount-precision"` // 数量精度位数(0为个位)
-	SymbolPartition string `json:"symbol-partition"` // 交易区, main: 主区, innovation: 创新区, bifurcation: 分叉区
+	BaseCurrency    string  `json:"base-currency"`    // 基础币种
+	QuoteCurrency   string  `json:"quote-currency"`   // 计价币种
+	PricePrecision  int     `json:"price-precision"`  // 价格精度位数(0为个位)
+	AmountPrecision int     `json:"amount-precision"` // 数量精度位数(0为个位)
+	SymbolPartition string  `json:"symbol-partition"` // 交易区, main: 主区, innovation: 创新区, bifurcation: 分叉区
+	MinOrderAmt     float64 `json:"min-order-amt"`    // 最小下单数量
+	MinOrderValue   float64 `json:"min-order-value"`  // 最小下单金额
 }
 
 type SymbolsReturn struct {
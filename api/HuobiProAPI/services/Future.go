@@ -0,0 +1,35 @@
+package services
+
+import (
+	"net/http"
+
+	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/config"
+	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/untils"
+)
+
+// NewFutureClient create a huobi future (hbdm) api client, the market and the trade urls are both api.hbdm.com
+func NewFutureClient(client *http.Client, accessKey, secretKey string) *Client {
+	c := NewClient(client, accessKey, secretKey)
+	c.MarketURL = config.FUTURE_URL
+	c.TradeURL = config.FUTURE_URL
+	return c
+}
+
+//------------------------------------------------------------------------------------------
+// 合约API, 返回原始的JSON, 格式和现货一致: {"status": "ok", "data": ..., "ts": ...}
+
+// 合约的行情请求, 不需要签名
+// strRequest: API路由路径, /market/depth......
+// mapParams: map类型的请求参数
+// return: 请求结果
+func (c *Client) FutureGet(strRequest string, mapParams map[string]string) string {
+	return untils.HttpGetRequest(c.HTTPClient, c.MarketURL+strRequest, mapParams)
+}
+
+// 合约的交易请求, 都是签名后的POST请求
+// strRequest: API路由路径, /api/v1/contract_order......
+// mapParams: map类型的请求参数
+// return: 请求结果
+func (c *Client) FuturePost(strRequest string, mapParams map[string]string) string {
+	return untils.ApiKeyPost(c.Config, mapParams, strRequest)
+}
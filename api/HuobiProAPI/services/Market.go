@@ -3,7 +3,9 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/config"
 	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/models"
@@ -12,6 +14,16 @@ import (
 
 // 批量操作的API下个版本再封装
 
+// Client 火币的API客户端, 带有自己的密钥, 账户ID和请求地址
+type Client struct {
+	*config.Config
+}
+
+// NewClient create a huobi api client with the default urls
+func NewClient(client *http.Client, accessKey, secretKey string) *Client {
+	return &Client{Config: config.New(client, accessKey, secretKey)}
+}
+
 //------------------------------------------------------------------------------------------
 // 交易API
 
@@ -20,16 +32,16 @@ import (
 // strPeriod: K线类型, 1min, 5min, 15min......
 // nSize: 获取数量, [1-2000]
 // return: KLineReturn 对象
-func GetKLine(strSymbol, strPeriod string, nSize int) (r models.KLineReturn, err error) {
+func (c *Client) GetKLine(strSymbol, strPeriod string, nSize int) (r models.KLineReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
 	mapParams["period"] = strPeriod
 	mapParams["size"] = strconv.Itoa(nSize)
 
 	strRequestUrl := "/market/history/kline"
-	strUrl := config.MARKET_URL + strRequestUrl
+	strUrl := c.MarketURL + strRequestUrl
 
-	jsonKLineReturn := untils.HttpGetRequest(strUrl, mapParams)
+	jsonKLineReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, mapParams)
 	err = json.Unmarshal([]byte(jsonKLineReturn), &r)
 
 	return
@@ -38,14 +50,14 @@ func GetKLine(strSymbol, strPeriod string, nSize int) (r models.KLineReturn, err
 // 获取聚合行情
 // strSymbol: 交易对, btcusdt, bccbtc......
 // return: TickReturn对象
-func GetTicker(strSymbol string) (r models.TickerReturn, err error) {
+func (c *Client) GetTicker(strSymbol string) (r models.TickerReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
 
 	strRequestUrl := "/market/detail/merged"
-	strUrl := config.MARKET_URL + strRequestUrl
+	strUrl := c.MarketURL + strRequestUrl
 
-	jsonTickReturn := untils.HttpGetRequest(strUrl, mapParams)
+	jsonTickReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, mapParams)
 	err = json.Unmarshal([]byte(jsonTickReturn), &r)
 
 	return
@@ -55,15 +67,15 @@ func GetTicker(strSymbol string) (r models.TickerReturn, err error) {
 // strSymbol: 交易对, btcusdt, bccbtc......
 // strType: Depth类型, step0、step1......stpe5 (合并深度0-5, 0时不合并)
 // return: MarketDepthReturn对象
-func GetMarketDepth(strSymbol, strType string) (r models.MarketDepthReturn, err error) {
+func (c *Client) GetMarketDepth(strSymbol, strType string) (r models.MarketDepthReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
 	mapParams["type"] = strType
 
 	strRequestUrl := "/market/depth"
-	strUrl := config.MARKET_URL + strRequestUrl
+	strUrl := c.MarketURL + strRequestUrl
 
-	jsonMarketDepthReturn := untils.HttpGetRequest(strUrl, mapParams)
+	jsonMarketDepthReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, mapParams)
 	err = json.Unmarshal([]byte(jsonMarketDepthReturn), &r)
 
 	return
@@ -72,14 +84,14 @@ func GetMarketDepth(strSymbol, strType string) (r models.MarketDepthReturn, err
 // 获取交易细节信息
 // strSymbol: 交易对, btcusdt, bccbtc......
 // return: TradeDetailReturn对象
-func GetTradeDetail(strSymbol string) (r models.TradeDetailReturn, err error) {
+func (c *Client) GetTradeDetail(strSymbol string) (r models.TradeDetailReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
 
 	strRequestUrl := "/market/trade"
-	strUrl := config.MARKET_URL + strRequestUrl
+	strUrl := c.MarketURL + strRequestUrl
 
-	jsonTradeDetailReturn := untils.HttpGetRequest(strUrl, mapParams)
+	jsonTradeDetailReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, mapParams)
 	err = json.Unmarshal([]byte(jsonTradeDetailReturn), &r)
 
 	return
@@ -89,15 +101,15 @@ func GetTradeDetail(strSymbol string) (r models.TradeDetailReturn, err error) {
 // strSymbol: 交易对, btcusdt, bccbtc......
 // nSize: 获取交易记录的数量, 范围1-2000
 // return: TradeReturn对象
-func GetTrade(strSymbol string, nSize int) (r models.TradeReturn, err error) {
+func (c *Client) GetTrade(strSymbol string, nSize int) (r models.TradeReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
 	mapParams["size"] = strconv.Itoa(nSize)
 
 	strRequestUrl := "/market/history/trade"
-	strUrl := config.MARKET_URL + strRequestUrl
+	strUrl := c.MarketURL + strRequestUrl
 
-	jsonTradeReturn := untils.HttpGetRequest(strUrl, mapParams)
+	jsonTradeReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, mapParams)
 	err = json.Unmarshal([]byte(jsonTradeReturn), &r)
 
 	return
@@ -106,14 +118,14 @@ func GetTrade(strSymbol string, nSize int) (r models.TradeReturn, err error) {
 // 获取Market Detail 24小时成交量数据
 // strSymbol: 交易对, btcusdt, bccbtc......
 // return: MarketDetailReturn对象
-func GetMarketDetail(strSymbol string) (r models.MarketDetailReturn, err error) {
+func (c *Client) GetMarketDetail(strSymbol string) (r models.MarketDetailReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
 
 	strRequestUrl := "/market/detail"
-	strUrl := config.MARKET_URL + strRequestUrl
+	strUrl := c.MarketURL + strRequestUrl
 
-	jsonMarketDetailReturn := untils.HttpGetRequest(strUrl, mapParams)
+	jsonMarketDetailReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, mapParams)
 	err = json.Unmarshal([]byte(jsonMarketDetailReturn), &r)
 
 	return
@@ -124,11 +136,11 @@ func GetMarketDetail(strSymbol string) (r models.MarketDetailReturn, err error)
 
 // 查询系统支持的所有交易及精度
 // return: SymbolsReturn对象
-func GetSymbols() (r models.SymbolsReturn, err error) {
+func (c *Client) GetSymbols() (r models.SymbolsReturn, err error) {
 	strRequestUrl := "/v1/common/symbols"
-	strUrl := config.TRADE_URL + strRequestUrl
+	strUrl := c.TradeURL + strRequestUrl
 
-	jsonSymbolsReturn := untils.HttpGetRequest(strUrl, nil)
+	jsonSymbolsReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, nil)
 	err = json.Unmarshal([]byte(jsonSymbolsReturn), &r)
 
 	return
@@ -136,11 +148,11 @@ func GetSymbols() (r models.SymbolsReturn, err error) {
 
 // 查询系统支持的所有币种
 // return: CurrencysReturn对象
-func GetCurrencys() (r models.CurrencysReturn, err error) {
+func (c *Client) GetCurrencys() (r models.CurrencysReturn, err error) {
 	strRequestUrl := "/v1/common/currencys"
-	strUrl := config.TRADE_URL + strRequestUrl
+	strUrl := c.TradeURL + strRequestUrl
 
-	jsonCurrencysReturn := untils.HttpGetRequest(strUrl, nil)
+	jsonCurrencysReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, nil)
 	err = json.Unmarshal([]byte(jsonCurrencysReturn), &r)
 
 	return
@@ -148,11 +160,11 @@ func GetCurrencys() (r models.CurrencysReturn, err error) {
 
 // 查询系统当前时间戳
 // return: TimestampReturn对象
-func GetTimestamp() (r models.TimestampReturn, err error) {
+func (c *Client) GetTimestamp() (r models.TimestampReturn, err error) {
 	strRequest := "/v1/common/timestamp"
-	strUrl := config.TRADE_URL + strRequest
+	strUrl := c.TradeURL + strRequest
 
-	jsonTimestampReturn := untils.HttpGetRequest(strUrl, nil)
+	jsonTimestampReturn := untils.HttpGetRequest(c.HTTPClient, strUrl, nil)
 	err = json.Unmarshal([]byte(jsonTimestampReturn), &r)
 
 	return
@@ -163,10 +175,10 @@ func GetTimestamp() (r models.TimestampReturn, err error) {
 
 // 查询当前用户的所有账户, 根据包含的私钥查询
 // return: AccountsReturn对象
-func GetAccounts() (r models.AccountsReturn, err error) {
+func (c *Client) GetAccounts() (r models.AccountsReturn, err error) {
 	strRequest := "/v1/account/accounts"
 
-	jsonAccountsReturn := untils.ApiKeyGet(make(map[string]string), strRequest)
+	jsonAccountsReturn := untils.ApiKeyGet(c.Config, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonAccountsReturn), &r)
 
 	return
@@ -175,10 +187,10 @@ func GetAccounts() (r models.AccountsReturn, err error) {
 // 根据账户ID查询账户余额
 // nAccountID: 账户ID, 不知道的话可以通过GetAccounts()获取, 可以只现货账户, C2C账户, 期货账户
 // return: BalanceReturn对象
-func GetAccountBalance(strAccountID string) (r models.BalanceReturn, err error) {
+func (c *Client) GetAccountBalance(strAccountID string) (r models.BalanceReturn, err error) {
 	strRequest := fmt.Sprintf("/v1/account/accounts/%s/balance", strAccountID)
 
-	jsonBanlanceReturn := untils.ApiKeyGet(make(map[string]string), strRequest)
+	jsonBanlanceReturn := untils.ApiKeyGet(c.Config, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonBanlanceReturn), &r)
 
 	return
@@ -190,7 +202,7 @@ func GetAccountBalance(strAccountID string) (r models.BalanceReturn, err error)
 // 下单
 // params: 下单信息
 // return: PlaceReturn对象
-func Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
+func (c *Client) Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["account-id"] = params.AccountID
 	mapParams["amount"] = params.Amount
@@ -205,7 +217,7 @@ func Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
 
 	strRequest := "/v1/order/orders/place"
 
-	jsonPlaceReturn := untils.ApiKeyPost(mapParams, strRequest)
+	jsonPlaceReturn := untils.ApiKeyPost(c.Config, mapParams, strRequest)
 	err = json.Unmarshal([]byte(jsonPlaceReturn), &r)
 
 	return
@@ -214,27 +226,27 @@ func Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
 // 申请撤销一个订单请求
 // strOrderID: 订单ID
 // return: PlaceReturn对象
-func SubmitCancel(strOrderID string) (r models.PlaceReturn, err error) {
+func (c *Client) SubmitCancel(strOrderID string) (r models.PlaceReturn, err error) {
 	strRequest := fmt.Sprintf("/v1/order/orders/%s/submitcancel", strOrderID)
 
-	jsonPlaceReturn := untils.ApiKeyPost(make(map[string]string), strRequest)
+	jsonPlaceReturn := untils.ApiKeyPost(c.Config, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonPlaceReturn), &r)
 
 	return
 }
 
 // 根据订单ID查询订单详情
-func GetOrderDetail(strOrderID string) (r models.OrderDetailReturn, err error) {
+func (c *Client) GetOrderDetail(strOrderID string) (r models.OrderDetailReturn, err error) {
 	strRequest := fmt.Sprintf("/v1/order/orders/%s", strOrderID)
 
-	jsonOrderReturn := untils.ApiKeyGet(make(map[string]string), strRequest)
+	jsonOrderReturn := untils.ApiKeyGet(c.Config, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonOrderReturn), &r)
 
 	return
 }
 
 // 列出当前所有挂单
-func GetOrders(strSymbol string) (r models.OrdersReturn, err error) {
+func (c *Client) GetOrders(strSymbol string) (r models.OrdersReturn, err error) {
 	//pre-submitted 准备提交, submitted 已提交, partial-filled 部分成交, partial-canceled 部分成交撤销, filled 完全成交, canceled 已撤销
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
@@ -242,8 +254,39 @@ func GetOrders(strSymbol string) (r models.OrdersReturn, err error) {
 
 	strRequest := "/v1/order/orders"
 
-	jsonOrdersReturn := untils.ApiKeyGet(mapParams, strRequest)
+	jsonOrdersReturn := untils.ApiKeyGet(c.Config, mapParams, strRequest)
 	err = json.Unmarshal([]byte(jsonOrdersReturn), &r)
 
 	return
 }
+
+// 查询当前成交、历史成交
+func (c *Client) GetMatchResults(strSymbol string) (r models.MatchResultsReturn, err error) {
+	mapParams := make(map[string]string)
+	mapParams["symbol"] = strSymbol
+
+	strRequest := "/v1/order/matchresults"
+
+	jsonMatchResultsReturn := untils.ApiKeyGet(c.Config, mapParams, strRequest)
+	err = json.Unmarshal([]byte(jsonMatchResultsReturn), &r)
+
+	return
+}
+
+// 私有 websocket 的鉴权消息, 参考官方文档的 auth 请求
+// strRequestPath: websocket的路由路径, /ws/v1
+func (c *Client) WSAuth(strRequestPath string) map[string]string {
+	mapParams := map[string]string{
+		"AccessKeyId":      c.AccessKey,
+		"SignatureMethod":  "HmacSHA256",
+		"SignatureVersion": "2",
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05"),
+	}
+	mapParams2Sign := make(map[string]string)
+	for key, value := range mapParams {
+		mapParams2Sign[key] = value
+	}
+	mapParams["op"] = "auth"
+	mapParams["Signature"] = untils.CreateSign(mapParams2Sign, "GET", untils.HostName(c.TradeURL), strRequestPath, c.SecretKey)
+	return mapParams
+}
@@ -18,10 +18,11 @@ import (
 )
 
 // Http Get请求基础函数, 通过封装Go语言Http请求, 支持火币网REST API的HTTP Get请求
+// httpClient: 发送请求的HTTP客户端
 // strUrl: 请求的URL
 // strParams: string类型的请求参数, user=lxz&pwd=lxz
 // return: 请求结果
-func HttpGetRequest(strUrl string, mapParams map[string]string) string {
+func HttpGetRequest(httpClient *http.Client, strUrl string, mapParams map[string]string) string {
 
 	//=============================================================
 	// create a socks5 dialer
@@ -41,8 +42,6 @@ func HttpGetRequest(strUrl string, mapParams map[string]string) string {
 	//os.Setenv("HTTPS_PROXY", "https://127.0.0.1:6667")
 
 	//==========================================================
-	//
-	httpClient := &http.Client{}
 
 	var strRequestUrl string
 	if nil == mapParams {
@@ -61,10 +60,10 @@ func HttpGetRequest(strUrl string, mapParams map[string]string) string {
 
 	// 发出请求
 	response, err := httpClient.Do(request)
-	defer response.Body.Close()
 	if nil != err {
 		return err.Error()
 	}
+	defer response.Body.Close()
 
 	// 解析响应内容
 	body, err := ioutil.ReadAll(response.Body)
@@ -76,10 +75,11 @@ func HttpGetRequest(strUrl string, mapParams map[string]string) string {
 }
 
 // Http POST请求基础函数, 通过封装Go语言Http请求, 支持火币网REST API的HTTP POST请求
+// httpClient: 发送请求的HTTP客户端
 // strUrl: 请求的URL
 // mapParams: map类型的请求参数
 // return: 请求结果
-func HttpPostRequest(strUrl string, mapParams map[string]string) string {
+func HttpPostRequest(httpClient *http.Client, strUrl string, mapParams map[string]string) string {
 
 	//=============================================================
 	// create a socks5 dialer
@@ -99,8 +99,6 @@ func HttpPostRequest(strUrl string, mapParams map[string]string) string {
 	//os.Setenv("HTTPS_PROXY", "https://127.0.0.1:6667")
 
 	//==========================================================
-	//
-	httpClient := &http.Client{}
 
 	jsonParams := ""
 	if nil != mapParams {
@@ -117,10 +115,10 @@ func HttpPostRequest(strUrl string, mapParams map[string]string) string {
 	request.Header.Add("Accept-Language", "zh-cn")
 
 	response, err := httpClient.Do(request)
-	defer response.Body.Close()
 	if nil != err {
 		return err.Error()
 	}
+	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body)
 	if nil != err {
@@ -131,45 +129,58 @@ func HttpPostRequest(strUrl string, mapParams map[string]string) string {
 }
 
 // 进行签名后的HTTP GET请求, 参考官方Python Demo写的
+// cfg: 账户的API配置
 // mapParams: map类型的请求参数, key:value
 // strRequest: API路由路径
 // return: 请求结果
-func ApiKeyGet(mapParams map[string]string, strRequestPath string) string {
+func ApiKeyGet(cfg *config.Config, mapParams map[string]string, strRequestPath string) string {
 	strMethod := "GET"
 	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05")
 
-	mapParams["AccessKeyId"] = config.ACCESS_KEY
+	mapParams["AccessKeyId"] = cfg.AccessKey
 	mapParams["SignatureMethod"] = "HmacSHA256"
 	mapParams["SignatureVersion"] = "2"
 	mapParams["Timestamp"] = timestamp
 
-	hostName := "api.huobi.pro"
-	mapParams["Signature"] = CreateSign(mapParams, strMethod, hostName, strRequestPath, config.SECRET_KEY)
+	hostName := HostName(cfg.TradeURL)
+	mapParams["Signature"] = CreateSign(mapParams, strMethod, hostName, strRequestPath, cfg.SecretKey)
 
-	strUrl := config.TRADE_URL + strRequestPath
-	return HttpGetRequest(strUrl, MapValueEncodeURI(mapParams))
+	strUrl := cfg.TradeURL + strRequestPath
+	return HttpGetRequest(cfg.HTTPClient, strUrl, MapValueEncodeURI(mapParams))
 }
 
 // 进行签名后的HTTP POST请求, 参考官方Python Demo写的
+// cfg: 账户的API配置
 // mapParams: map类型的请求参数, key:value
 // strRequest: API路由路径
 // return: 请求结果
-func ApiKeyPost(mapParams map[string]string, strRequestPath string) string {
+func ApiKeyPost(cfg *config.Config, mapParams map[string]string, strRequestPath string) string {
 	strMethod := "POST"
 	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05")
 
 	mapParams2Sign := make(map[string]string)
-	mapParams2Sign["AccessKeyId"] = config.ACCESS_KEY
+	mapParams2Sign["AccessKeyId"] = cfg.AccessKey
 	mapParams2Sign["SignatureMethod"] = "HmacSHA256"
 	mapParams2Sign["SignatureVersion"] = "2"
 	mapParams2Sign["Timestamp"] = timestamp
 
-	hostName := "api.huobi.pro"
+	hostName := HostName(cfg.TradeURL)
 
-	mapParams2Sign["Signature"] = CreateSign(mapParams2Sign, strMethod, hostName, strRequestPath, config.SECRET_KEY)
-	strUrl := config.TRADE_URL + strRequestPath + "?" + Map2UrlQuery(MapValueEncodeURI(mapParams2Sign))
+	mapParams2Sign["Signature"] = CreateSign(mapParams2Sign, strMethod, hostName, strRequestPath, cfg.SecretKey)
+	strUrl := cfg.TradeURL + strRequestPath + "?" + Map2UrlQuery(MapValueEncodeURI(mapParams2Sign))
 
-	return HttpPostRequest(strUrl, mapParams)
+	return HttpPostRequest(cfg.HTTPClient, strUrl, mapParams)
+}
+
+// 取得请求地址中的主机名, 用于签名
+// strUrl: 请求地址, https://api.huobi.pro
+// return: 主机名, api.huobi.pro
+func HostName(strUrl string) string {
+	u, err := url.Parse(strUrl)
+	if err != nil {
+		return strUrl
+	}
+	return u.Host
 }
 
 // 构造签名
@@ -219,13 +230,19 @@ func MapValueEncodeURI(mapValue map[string]string) map[string]string {
 	return mapValue
 }
 
-// 将map格式的请求参数转换为字符串格式的
+// 将map格式的请求参数转换为字符串格式的, 参数按ASCII码排序, 签名需要固定的顺序
 // mapParams: map格式的参数键值对
 // return: 查询字符串
 func Map2UrlQuery(mapParams map[string]string) string {
+	var keys []string
+	for key := range mapParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	var strParams string
-	for key, value := range mapParams {
-		strParams += (key + "=" + value + "&")
+	for _, key := range keys {
+		strParams += (key + "=" + mapParams[key] + "&")
 	}
 
 	if 0 < len(strParams) {
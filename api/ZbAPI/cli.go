@@ -1,37 +1,79 @@
 package ZbAPI
 
 import (
-	"github.com/go-resty/resty"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 )
 
-type configure struct {
-	ACCESS_KEY string
-	SECRET_KEY string
-	dataURL    string
-	tradeURL   string
-}
-
-// Config 中币接口配置信息
-var (
-	Config                  configure
-	dataClient, tradeClient httpClient
+// API请求地址, 要带最后的/
+const (
+	DATA_URL  = "http://api.zb.com/data/v1/"
+	TRADE_URL = "https://trade.zb.com/api/"
 )
 
-func init() {
+// Client 中币的API客户端, 每个客户端使用自己的密钥, 可以同时运行任意多个账户
+type Client struct {
+	AccessKey  string
+	SecretKey  string
+	DataURL    string //行情API请求地址, 要带最后的/
+	TradeURL   string //交易API请求地址, 要带最后的/
+	httpClient *http.Client
+}
 
-	// os.Setenv("HTTP_PROXY", "http://127.0.0.1:6667")
-	// os.Setenv("HTTPS_PROXY", "https://127.0.0.1:6667")
+// NewClient create a zb api client with the default urls
+func NewClient(client *http.Client, accessKey, secretKey string) *Client {
+	return &Client{
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		DataURL:    DATA_URL,
+		TradeURL:   TRADE_URL,
+		httpClient: client,
+	}
+}
 
-	Config.ACCESS_KEY = ""
-	Config.SECRET_KEY = ""
-	Config.dataURL = "http://api.zb.com/data/v1/"
-	Config.tradeURL = "https://trade.zb.com/api/"
+// 发送GET请求, 返回响应内容
+func (c *Client) get(strUrl string, params map[string]string) ([]byte, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	req, err := http.NewRequest("GET", strUrl+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("[GET %s] HTTP Status: %d, Info: %s", strUrl, resp.StatusCode, body)
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("[GET %s] empty response", strUrl)
+	}
+	return body, nil
+}
 
-	c1 := resty.New().SetDebug(false).SetHostURL(Config.dataURL)
-	c2 := resty.New().SetDebug(false).SetHostURL(Config.tradeURL)
-	dataClient = httpClient{c1}
-	tradeClient = httpClient{c2}
+// 行情API请求
+func (c *Client) data(api string, params map[string]string) ([]byte, error) {
+	return c.get(c.DataURL+api, params)
+}
 
-	dataClient.handleQueryParams()
-	tradeClient.handleQueryParams()
+// 交易API请求, 自动加上密钥和签名
+func (c *Client) trade(method string, params map[string]string) ([]byte, error) {
+	params["accesskey"] = c.AccessKey
+	params["method"] = method
+	params["sign"] = hmacSign(c.SecretKey, sortParams(params))
+	params["reqTime"] = strconv.FormatInt(time.Now().UnixNano()/1000000, 10)
+	return c.get(c.TradeURL+method, params)
 }
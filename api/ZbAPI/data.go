@@ -1,6 +1,6 @@
 package ZbAPI
 
-//==================================================//
+// ==================================================//
 type depthOrder []float64
 type respDepth struct {
 	Timestamp int          `mapstructure:"timestamp"`
@@ -8,7 +8,14 @@ type respDepth struct {
 	Bids      []depthOrder `mapstructure:"bids"`
 }
 
-//==================================================//
+// ==================================================//
+type market struct {
+	AmountScale int `json:"amountScale"`
+	PriceScale  int `json:"priceScale"`
+}
+type respMarkets map[string]market
+
+// ==================================================//
 type klineData []float64
 type respKline struct {
 	Symbol    string      `json:"symbol"`
@@ -16,7 +23,7 @@ type respKline struct {
 	MoneyType string      `json:"moneyType"`
 }
 
-//==================================================//
+// ==================================================//
 type respTrades []trade
 type trade struct {
 	Amount    string `json:"amount"`
@@ -27,7 +34,7 @@ type trade struct {
 	TxType    string `json:"type"`
 }
 
-//==================================================//
+// ==================================================//
 type ticker struct {
 	Vol  string `json:"vol"`
 	Last string `json:"last"`
@@ -42,7 +49,7 @@ type respTicker struct {
 	Date   string `json:"date"`
 }
 
-//==================================================//
+// ==================================================//
 type accountInfoCoin struct {
 	EnName        string `mapstructure:"enName"`
 	Freez         string `mapstructure:"freez"`
@@ -70,7 +77,7 @@ type respAccountInfo struct {
 	Message string            `json:"message"`
 }
 
-//==================================================//
+// ==================================================//
 type respOrders []order
 type order struct {
 	Currency    string  `json:"currency"`
@@ -79,6 +86,7 @@ type order struct {
 	Status      int     `json:"status"`
 	TotalAmount float64 `json:"total_amount"`
 	TradeAmount float64 `json:"trade_amount"`
+	Fees        float64 `json:"fees"`
 	TradeDate   int64   `json:"trade_date"`
 	TradeMoney  string  `json:"trade_money"`
 	TradePrice  float64 `json:"trade_price"`
@@ -87,13 +95,13 @@ type order struct {
 	Message     string  `json:"message"`
 }
 
-//==================================================//
+// ==================================================//
 type respSimple struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
 
-//==================================================//
+// ==================================================//
 type respOrder struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -8,12 +8,12 @@ import (
 )
 
 // 市场深度
-// depth("depth", "btc_usdt", "20")
-func depth(api, market, size string) (*respDepth, error) {
-	resp, err := dataClient.SetQueryParams(map[string]string{
+// GetDepth("btc_usdt", "20")
+func (c *Client) GetDepth(market, size string) (*respDepth, error) {
+	body, err := c.data("depth", map[string]string{
 		"market": market,
 		"size":   size,
-	}).R().Get(api)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +21,7 @@ func depth(api, market, size string) (*respDepth, error) {
 		res          respDepth
 		mapInterface map[string]interface{}
 	)
-	err = json.Unmarshal(resp.Body(), &mapInterface)
+	err = json.Unmarshal(body, &mapInterface)
 	if err != nil {
 		return nil, err
 	}
@@ -29,55 +29,68 @@ func depth(api, market, size string) (*respDepth, error) {
 	return &res, err
 }
 
-func GetDepth(market, size string) (*respDepth, error) {
-	return depth("depth", market, size)
+// 所有市场的交易规则
+func (c *Client) GetMarkets() (*respMarkets, error) {
+	body, err := c.data("markets", map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	var res respMarkets
+	err = json.Unmarshal(body, &res)
+	return &res, err
 }
 
 // 行情
-// getTicker("ticker", "btc_usdt")
-func getTicker(api, market string) *respTicker {
-	resp, _ := dataClient.SetQueryParams(map[string]string{
+// GetTicker("btc_usdt")
+func (c *Client) GetTicker(market string) (*respTicker, error) {
+	body, err := c.data("ticker", map[string]string{
 		"market": market,
-	}).R().Get(api)
+	})
+	if err != nil {
+		return nil, err
+	}
 	var res respTicker
-	json.Unmarshal(resp.Body(), &res)
-	return &res
+	err = json.Unmarshal(body, &res)
+	return &res, err
 }
 
-// K线
-// kline("kline", "btc_usdt", "1min", "10")
-func kline(api, market, timeType, size string) *respKline {
-	resp, _ := dataClient.SetQueryParams(map[string]string{
+// K线, since 为毫秒时间戳, 为 0 时返回最新的K线
+// GetKline("btc_usdt", "1min", "10", 0)
+func (c *Client) GetKline(market, timeType, size string, since int64) (*respKline, error) {
+	params := map[string]string{
 		"market": market,
 		"type":   timeType,
 		"size":   size,
-	}).R().Get(api)
-	var (
-		res          respKline
-		mapInterface map[string]interface{}
-	)
-	json.Unmarshal(resp.Body(), &res)
-	mapstructure.Decode(mapInterface, &res)
-	return &res
+	}
+	if since > 0 {
+		params["since"] = fmt.Sprint(since)
+	}
+	body, err := c.data("kline", params)
+	if err != nil {
+		return nil, err
+	}
+	var res respKline
+	err = json.Unmarshal(body, &res)
+	return &res, err
 }
 
 // 历史成交
-// trades("trades", "btc_usdt")
-func trades(api, market string) *respTrades {
-	resp, _ := dataClient.SetQueryParams(map[string]string{
+// GetTrades("btc_usdt")
+func (c *Client) GetTrades(market string) (*respTrades, error) {
+	body, err := c.data("trades", map[string]string{
 		"market": market,
-	}).R().Get(api)
+	})
+	if err != nil {
+		return nil, err
+	}
 	var res respTrades
-	json.Unmarshal(resp.Body(), &res)
-	return &res
+	err = json.Unmarshal(body, &res)
+	return &res, err
 }
 
 // 获取用户信息
-func accountInfo(api, sign string) (*respAccountInfo, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
-		"method": api,
-		"sign":   sign,
-	}).R().Get(api)
+func (c *Client) GetAccountInfo() (*respAccountInfo, error) {
+	body, err := c.trade("getAccountInfo", map[string]string{})
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +98,7 @@ func accountInfo(api, sign string) (*respAccountInfo, error) {
 		res          respAccountInfo
 		mapInterface map[string]interface{}
 	)
-	err = json.Unmarshal(resp.Body(), &mapInterface)
+	err = json.Unmarshal(body, &mapInterface)
 	if err != nil {
 		return nil, err
 	}
@@ -93,63 +106,39 @@ func accountInfo(api, sign string) (*respAccountInfo, error) {
 	return &res, err
 }
 
-func GetAccountInfo() (*respAccountInfo, error) {
+// 委托下单, orderType 为空时是普通的限价单, 1: PostOnly, 2: IOC
+func (c *Client) CreateOrder(amount, currency, tradeType, price, orderType string) (*respOrder, error) {
 	params := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
-		"method":    "getAccountInfo",
-	}
-	sorted := sortParams(params)
-	sign := hmacSign(sorted)
-	return accountInfo("getAccountInfo", sign)
-}
-
-// 委托下单
-func createOrder(api, amount, currency, tradeType, price, sign string) (*respOrder, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
 		"amount":    amount,
 		"currency":  currency,
-		"method":    api,
 		"price":     price,
 		"tradeType": tradeType,
-		"sign":      sign,
-	}).R().Get(api)
+	}
+	if orderType != "" {
+		params["orderType"] = orderType
+	}
+	body, err := c.trade("order", params)
 	if err != nil {
 		return nil, err
 	}
 	var res respOrder
-	err = json.Unmarshal(resp.Body(), &res)
+	err = json.Unmarshal(body, &res)
 	return &res, err
 }
 
-func CreateOrder(amount, currency, tradeType, price string) (*respOrder, error) {
-	createOrderParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
-		"amount":    amount,
-		"currency":  currency,
-		"price":     price,
-		"tradeType": tradeType,
-		"method":    "order",
-	}
-	createOrderSorted := sortParams(createOrderParams)
-	createOrderSign := hmacSign(createOrderSorted)
-	return createOrder("order", amount, currency, tradeType, price, createOrderSign)
-}
-
 // 获取委托买单和卖单
-func getOrders(api, currency, sign string) (*respOrders, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) getOrders(method, currency string) (*respOrders, error) {
+	body, err := c.trade(method, map[string]string{
 		"currency":  currency,
-		"method":    api,
 		"pageIndex": "1",
 		"pageSize":  "10",
-		"sign":      sign,
-	}).R().Get(api)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if resp.Body()[0] == '{' {
+	if body[0] == '{' {
 		var res respSimple
-		err = json.Unmarshal(resp.Body(), &res)
+		err = json.Unmarshal(body, &res)
 		if err != nil {
 			return nil, err
 		}
@@ -158,75 +147,44 @@ func getOrders(api, currency, sign string) (*respOrders, error) {
 	}
 
 	var res respOrders
-	err = json.Unmarshal(resp.Body(), &res)
+	err = json.Unmarshal(body, &res)
 	return &res, err
 }
 
-func GetOrders(currency string) (*respOrders, error) {
-	orderParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
-		"currency":  currency,
-		"method":    "getUnfinishedOrdersIgnoreTradeType",
-		"pageIndex": "1",
-		"pageSize":  "10",
-	}
-	orderSorted := sortParams(orderParams)
-	orderSign := hmacSign(orderSorted)
-	return getOrders("getUnfinishedOrdersIgnoreTradeType", currency, orderSign)
+// 获取未成交的委托买单和卖单
+func (c *Client) GetOrders(currency string) (*respOrders, error) {
+	return c.getOrders("getUnfinishedOrdersIgnoreTradeType", currency)
+}
+
+// 获取最近的委托买单和卖单, 包括已成交的
+func (c *Client) GetOrdersHistory(currency string) (*respOrders, error) {
+	return c.getOrders("getOrdersIgnoreTradeType", currency)
 }
 
 // 取消委托
-func cancelOrder(api, id, currency, sign string) (*respSimple, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) CancelOrder(id, currency string) (*respSimple, error) {
+	body, err := c.trade("cancelOrder", map[string]string{
 		"currency": currency,
-		"method":   api,
 		"id":       id,
-		"sign":     sign,
-	}).R().Get(api)
+	})
 	if err != nil {
 		return nil, err
 	}
 	var res respSimple
-	err = json.Unmarshal(resp.Body(), &res)
+	err = json.Unmarshal(body, &res)
 	return &res, err
 }
 
-func CancelOrder(id, currency string) (*respSimple, error) {
-	cancelParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
-		"currency":  currency,
-		"id":        id,
-		"method":    "cancelOrder",
-	}
-	cancelSorted := sortParams(cancelParams)
-	cancelSign := hmacSign(cancelSorted)
-	return cancelOrder("cancelOrder", id, currency, cancelSign)
-}
-
 // 获取委托订单
-func getOrder(api, id, currency, sign string) (*order, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) GetOrder(id, currency string) (*order, error) {
+	body, err := c.trade("getOrder", map[string]string{
 		"currency": currency,
-		"method":   api,
 		"id":       id,
-		"sign":     sign,
-	}).R().Get(api)
+	})
 	if err != nil {
 		return nil, err
 	}
 	var res order
-	err = json.Unmarshal(resp.Body(), &res)
+	err = json.Unmarshal(body, &res)
 	return &res, err
 }
-
-func GetOrder(id, currency string) (*order, error) {
-	orderParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
-		"currency":  currency,
-		"id":        id,
-		"method":    "getOrder",
-	}
-	orderSorted := sortParams(orderParams)
-	orderSign := hmacSign(orderSorted)
-	return getOrder("getOrder", id, currency, orderSign)
-}
@@ -2,11 +2,15 @@ package api
 
 // Option is an exchange option
 type Option struct {
-	TraderID  int64
-	Type      string
-	Name      string
-	AccessKey string
-	SecretKey string
+	TraderID   int64
+	Type       string
+	Name       string
+	AccessKey  string
+	SecretKey  string
+	Passphrase string //API密钥的口令, okex 和 coinbase 需要
+	Settings   string //交易所的附加设置, JSON 格式
+	ProxyURL   string //代理地址, 支持 http://、https:// 和 socks5://, 为空时使用环境变量 HTTP_PROXY 和 HTTPS_PROXY
+	BaseURL    string //API请求地址, 覆盖交易所默认的地址, 可以指向测试网或者本地的模拟服务器
 }
 
 // Exchange interface
@@ -14,17 +18,68 @@ type Exchange interface {
 	Log(...interface{})                                                                                   //向管理台发送这个交易所的打印信息
 	GetType() string                                                                                      //获取交易所类型,是火币还是OKEY等。。。
 	GetName() string                                                                                      //获取交易所名称,自定义的
-	SetLimit(times interface{}) float64                                                                   //设置交易所的API访问频率,和 E.AutoSleep() 配合使用
-	AutoSleep()                                                                                           //自动休眠以满足设置的交易所的API访问频率
+	SetLimit(times interface{}) float64                                                                   //设置交易所每秒恢复的请求权重
+	AutoSleep()                                                                                           //等待预支的请求权重恢复, 每个请求都会自动等待交易所的API访问频率限制
+	GetRateLimit() interface{}                                                                            //获取交易所的API访问频率和剩余的请求权重, 同一个交易所和API KEY的所有策略共享
 	GetMinAmount(stock string) float64                                                                    //获取交易所的最小交易数量
+	GetMarkets() interface{}                                                                              //获取交易所支持的所有交易对及其精度、最小交易数量等交易规则
 	GetAccount() interface{}                                                                              //获取交易所的账户资金信息
-	Trade(tradeType string, stockType string, price, amount interface{}, msgs ...interface{}) interface{} //如果 Price <= 0 自动设置为市价单，数量参数也有所不同,如果成功返回订单的 ID,如果失败返回 false
+	Trade(tradeType string, stockType string, price, amount interface{}, msgs ...interface{}) interface{} //如果 Price <= 0 自动设置为市价单，现货市价买单的数量是计价币种的金额, 第一个 msg 可以是订单选项 {postOnly, timeInForce},如果成功返回订单的 ID,如果失败返回 false
 	GetOrder(stockType, id string) interface{}                                                            //返回订单信息
 	GetOrders(stockType string) interface{}                                                               //返回所有的未完成订单列表
-	GetTrades(stockType string) interface{}                                                               //返回最近的已完成订单列表
+	GetTrades(stockType string) interface{}                                                               //返回最近的成交记录列表
 	CancelOrder(order Order) bool                                                                         //取消一笔订单
 	GetTicker(stockType string, sizes ...interface{}) interface{}                                         //获取交易所的最新市场行情数据
 	GetRecords(stockType, period string, sizes ...interface{}) interface{}                                //返回交易所的最新K线数据列表
+	GetLastError() interface{}                                                                            //返回最近一次调用失败的错误信息, 可以根据 Kind 判断错误类型
+	Capabilities() Capabilities                                                                           //返回交易所支持的方法、K线周期、订单类型等功能
+}
+
+// TypedExchange is the typed counterpart of Exchange, every method returns an *Error on failure,
+// the interface{} methods of Exchange are the thin shims of it for the javascript
+type TypedExchange interface {
+	Account() (Account, error)                                                                                 //获取交易所的账户资金信息
+	PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) //下单, 成功返回订单的 ID
+	Order(stockType, id string) (Order, error)                                                                 //返回订单信息
+	Orders(stockType string) ([]Order, error)                                                                  //返回所有的未完成订单列表
+	Trades(stockType string) ([]Trade, error)                                                                  //返回最近的成交记录列表
+	Cancel(order Order) error                                                                                  //取消一笔订单
+	Ticker(stockType string, size int) (Ticker, error)                                                         //获取交易所的最新市场行情数据, size <= 0 时使用默认的深度
+	Records(stockType, period string, size int) ([]Record, error)                                              //返回交易所的最新K线数据列表, size <= 0 时使用默认的数量
+}
+
+// Streamer is implemented by the exchanges which can stream the market data by websocket
+type Streamer interface {
+	Stream(stockType string) error   //订阅深度和成交数据, 之后 GetTicker 从本地维护的订单簿读取
+	Subscribe(stockType string) bool //Stream 的 javascript 版本, 失败时返回 false
+	CloseStreams()                   //关闭所有的订阅
+}
+
+// EventSource is implemented by the exchanges which can push the order and balance changes
+type EventSource interface {
+	Listen() error            //启动私有的 websocket 推送, 订单和资金的变化保存在事件队列中
+	Events() ([]Event, error) //取出事件队列中的所有事件, 第一次调用时自动 Listen
+	GetEvents() interface{}   //Events 的 javascript 版本, 事件交给回调函数之后返回错误
+	UseCallbacks()            //事件只交给策略的 onOrder 和 onBalance, 之后 GetEvents 返回错误
+}
+
+// TradeFeed is implemented by the exchanges which can get the latest public trades of the market
+type TradeFeed interface {
+	MarketTrades(stockType string, size int) ([]Trade, error) //返回市场上最新的公开成交记录, 按时间排序
+}
+
+// FuturesExchange is implemented by the futures exchanges, the javascript can use these methods only on them
+type FuturesExchange interface {
+	SetLeverage(leverage interface{}) bool                            //设置之后下单使用的杠杆倍数
+	SetMarginMode(mode string) bool                                   //设置保证金模式, crossed: 全仓, fixed: 逐仓
+	Positions(stockType string) ([]Position, error)                   //返回持仓列表
+	GetPositions(stockType string) interface{}                        //Positions 的 javascript 版本
+	ClosePosition(position Position, msgs ...interface{}) interface{} //以市价平掉一个持仓的全部可平数量, 成功返回订单的 ID
+}
+
+// Clock is implemented by the exchanges running on a virtual clock, like backtest
+type Clock interface {
+	Advance(interval int64) bool //虚拟时钟前进 interval 毫秒, 历史数据用完时返回 false
 }
 
 var (
@@ -0,0 +1,504 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/miaolz123/conver"
+)
+
+// periodSeconds the length of every period in seconds
+var periodSeconds = map[string]int64{
+	"M":   60,
+	"M3":  180,
+	"M5":  300,
+	"M15": 900,
+	"M30": 1800,
+	"H":   3600,
+	"H2":  7200,
+	"H4":  14400,
+	"H6":  21600,
+	"H12": 43200,
+	"D":   86400,
+	"D3":  259200,
+	"W":   604800,
+}
+
+// backtestSettings is the settings of a backtest exchange, e.g.
+// {"source": "okex", "period": "M", "start": "2018-06-01 00:00:00", "end": "2018-07-01 00:00:00", "balance": {"USDT": 10000}, "fee": 0.002}
+type backtestSettings struct {
+	Source  string             `json:"source"`  //历史数据来自哪个交易所
+	Period  string             `json:"period"`  //历史数据的K线周期
+	Start   string             `json:"start"`   //回测开始时间
+	End     string             `json:"end"`     //回测结束时间
+	Balance map[string]float64 `json:"balance"` //初始资金
+	Fee     float64            `json:"fee"`     //手续费率
+}
+
+// Backtest the exchange struct of backtest, it replays stored records on a virtual clock
+type Backtest struct {
+	settings  backtestSettings
+	records   map[string][]Record //每个货币类型的历史K线
+	cursors   map[string]int      //每个货币类型下一根还没有走完的K线
+	start     int64
+	end       int64
+	now       int64 //虚拟时钟, unix时间戳
+	mutex     sync.Mutex
+	sim       *simulator
+	logger    model.Logger
+	option    Option
+	lastError *Error
+
+	limit float64
+}
+
+// NewBacktest create an exchange struct of backtest
+func NewBacktest(opt Option) Exchange {
+	logger := model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type}
+	settings := backtestSettings{
+		Period:  "M",
+		Balance: map[string]float64{"USDT": 10000.0},
+		Fee:     0.002,
+	}
+	if opt.Settings != "" {
+		if err := json.Unmarshal([]byte(opt.Settings), &settings); err != nil {
+			logger.Log(constant.ERROR, "", 0.0, 0.0, "NewBacktest() error, ", err)
+		}
+	}
+	e := &Backtest{
+		settings: settings,
+		records:  make(map[string][]Record),
+		cursors:  make(map[string]int),
+		sim:      newSimulator(settings.Balance, settings.Fee, logger),
+		logger:   logger,
+		option:   opt,
+
+		limit: 10.0,
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", settings.Start, time.Local); err == nil {
+		e.start = t.Unix()
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", settings.End, time.Local); err == nil {
+		e.end = t.Unix()
+	}
+	e.now = e.start
+	// 成交都发生在持有 e.mutex 的时候, 可以直接读取虚拟时钟
+	e.sim.now = func() int64 {
+		return e.now
+	}
+	return e
+}
+
+// Log print something to console
+func (e *Backtest) Log(msgs ...interface{}) {
+	e.logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
+}
+
+// GetType get the type of this exchange
+func (e *Backtest) GetType() string {
+	return e.option.Type
+}
+
+// GetName get the name of this exchange
+func (e *Backtest) GetName() string {
+	return e.option.Name
+}
+
+// SetLimit set the limit calls amount per second of this exchange
+func (e *Backtest) SetLimit(times interface{}) float64 {
+	e.limit = conver.Float64Must(times)
+	return e.limit
+}
+
+// AutoSleep the virtual clock is driven by G.Sleep(), so there is nothing to wait for
+func (e *Backtest) AutoSleep() {
+}
+
+// GetRateLimit the backtest sends no request, so the budget is always full
+func (e *Backtest) GetRateLimit() interface{} {
+	return RateLimit{Rate: e.limit, Remaining: e.limit}
+}
+
+// Capabilities get what this exchange supports
+func (e *Backtest) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, map[string]string{e.settings.Period: ""}, simOrderTypes)
+}
+
+// GetMinAmount get the min trade amonut of this exchange
+func (e *Backtest) GetMinAmount(stock string) float64 {
+	return 0.0
+}
+
+// GetMarkets get the markets which have stored records of the period
+func (e *Backtest) GetMarkets() interface{} {
+	stockTypes := []string{}
+	dirs, err := ioutil.ReadDir(fmt.Sprintf("custom/records/%v", e.settings.Source))
+	if err != nil && !os.IsNotExist(err) {
+		return e.fail("GetMarkets", err)
+	}
+	for _, dir := range dirs {
+		if dir.IsDir() {
+			stockTypes = append(stockTypes, strings.Replace(dir.Name(), "_", "/", -1))
+		}
+	}
+	stored, err := model.RecordStockTypes(e.settings.Source, e.settings.Period)
+	if err != nil {
+		return e.fail("GetMarkets", err)
+	}
+	stockTypes = append(stockTypes, stored...)
+	markets := []Market{}
+	found := map[string]bool{}
+	for _, stockType := range stockTypes {
+		base, quote, err := splitStockType(stockType)
+		if err != nil || found[stockType] {
+			continue
+		}
+		found[stockType] = true
+		markets = append(markets, Market{
+			StockType:     stockType,
+			Symbol:        strings.Replace(stockType, "/", "_", -1),
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+		})
+	}
+	return markets
+}
+
+// GetTime get the unix timestamp of the virtual clock
+func (e *Backtest) GetTime() int64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.now
+}
+
+// Advance move the virtual clock forward interval milliseconds (one period if interval <= 0),
+// fill the orders crossed by the passing records and return false when the history runs out
+func (e *Backtest) Advance(interval int64) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	step := interval / 1000
+	if interval <= 0 {
+		step = periodLength(e.settings.Period)
+	} else if step < 1 {
+		step = 1
+	}
+	if len(e.records) == 0 && e.end <= 0 {
+		// 没有结束时间时虚拟时钟会一直运行
+		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Backtest finished, no records are loaded, get the ticker or the records before G.Sleep()")
+		return false
+	}
+	next := e.now + step
+	length := periodLength(e.settings.Period)
+	finished := len(e.records) > 0
+	for stockType, records := range e.records {
+		// 从上次的位置继续, 每根K线只经过一次
+		i := e.cursors[stockType]
+		for i < len(records) && records[i].Time+length <= e.now {
+			i++
+		}
+		for ; i < len(records) && records[i].Time+length <= next; i++ {
+			e.sim.match(stockType, records[i].Low, records[i].High)
+		}
+		e.cursors[stockType] = i
+		if len(records) > 0 && records[len(records)-1].Time+length > next {
+			finished = false
+		}
+	}
+	e.now = next
+	if finished || (e.end > 0 && e.now >= e.end) {
+		e.logger.Log(constant.INFO, "", 0.0, 0.0, "Backtest finished at ", time.Unix(e.now, 0).Format("2006-01-02 15:04:05"), ", account: ", e.sim.account())
+		return false
+	}
+	return true
+}
+
+// loadRecords load the stored records of stockType, the caller must hold the mutex
+func (e *Backtest) loadRecords(stockType string) (records []Record, err error) {
+	if records, ok := e.records[stockType]; ok {
+		return records, nil
+	}
+	if periodLength(e.settings.Period) <= 0 {
+		err = fmt.Errorf("unrecognized period: %v", e.settings.Period)
+		return
+	}
+	file := fmt.Sprintf("custom/records/%v/%v/%v.json", e.settings.Source, strings.Replace(stockType, "/", "_", -1), e.settings.Period)
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		//没有文件时从数据库读取下载或者导入的K线
+		if records, err = e.storedRecords(stockType); err != nil {
+			return
+		}
+		file = "the database"
+	} else if err != nil {
+		return
+	} else if err = json.Unmarshal(data, &records); err != nil {
+		return
+	}
+	if len(records) == 0 {
+		err = fmt.Errorf("no records of %v in %v", stockType, file)
+		return
+	}
+	if e.now == 0 {
+		e.now = records[0].Time + periodLength(e.settings.Period)
+	}
+	e.records[stockType] = records
+	return
+}
+
+// storedRecords get the records of stockType stored in the database before the end of the backtest
+func (e *Backtest) storedRecords(stockType string) ([]Record, error) {
+	stored, err := model.ListRecordsBetween(e.settings.Source, stockType, e.settings.Period, 0, e.end)
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	for _, r := range stored {
+		records = append(records, Record{
+			Time:   r.Time,
+			Open:   r.Open,
+			High:   r.High,
+			Low:    r.Low,
+			Close:  r.Close,
+			Volume: r.Volume,
+		})
+	}
+	return records, nil
+}
+
+// current get the last completed record of stockType, the caller must hold the mutex
+func (e *Backtest) current(stockType string) (record Record, err error) {
+	records, err := e.loadRecords(stockType)
+	if err != nil {
+		return
+	}
+	if i := e.completed(records); i > 0 {
+		return records[i-1], nil
+	}
+	err = fmt.Errorf("no record of %v before %v", stockType, time.Unix(e.now, 0).Format("2006-01-02 15:04:05"))
+	return
+}
+
+// completed get the number of the records completed before the virtual clock, the caller must hold the mutex
+func (e *Backtest) completed(records []Record) int {
+	length := periodLength(e.settings.Period)
+	return sort.Search(len(records), func(i int) bool {
+		return records[i].Time+length > e.now
+	})
+}
+
+// GetLastError get the last error of this exchange
+func (e *Backtest) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Backtest) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of the simulator
+func (e *Backtest) Account() (Account, error) {
+	return e.sim.account(), nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *Backtest) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order into the simulator
+func (e *Backtest) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	stockType = strings.ToUpper(stockType)
+	tradeType = strings.ToUpper(tradeType)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	record, err := e.current(stockType)
+	if err != nil {
+		return "", wrapError("Trade", err)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, simOrderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	id, err := e.sim.place(tradeType, stockType, price, amount, record.Close, record.Close, opts, msgs...)
+	if err != nil {
+		return "", wrapError("Trade", err)
+	}
+	return id, nil
+}
+
+// Trade place an order
+func (e *Backtest) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// Order get details of an order
+func (e *Backtest) Order(stockType, id string) (Order, error) {
+	order, err := e.sim.order(id)
+	if err != nil {
+		return order, wrapError("GetOrder", err)
+	}
+	return order, nil
+}
+
+// GetOrder get details of an order
+func (e *Backtest) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *Backtest) Orders(stockType string) ([]Order, error) {
+	return e.sim.openOrders(strings.ToUpper(stockType)), nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Backtest) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the recent fills
+func (e *Backtest) Trades(stockType string) ([]Trade, error) {
+	return e.sim.recentFills(strings.ToUpper(stockType)), nil
+}
+
+// GetTrades get the recent fills
+func (e *Backtest) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// Cancel cancel an order
+func (e *Backtest) Cancel(order Order) error {
+	order, err := e.sim.cancel(order.ID)
+	if err != nil {
+		return wrapError("CancelOrder", err)
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Backtest) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
+	return true
+}
+
+// Ticker get market ticker & depth, it is made from the close price of the last completed record
+func (e *Backtest) Ticker(stockType string, size int) (Ticker, error) {
+	stockType = strings.ToUpper(stockType)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	record, err := e.current(stockType)
+	if err != nil {
+		return Ticker{}, wrapError("GetTicker", err)
+	}
+	return Ticker{
+		Bids: []OrderBook{{Price: record.Close, Amount: record.Volume}},
+		Buy:  record.Close,
+		Mid:  record.Close,
+		Sell: record.Close,
+		Asks: []OrderBook{{Price: record.Close, Amount: record.Volume}},
+	}, nil
+}
+
+// GetTicker get market ticker & depth
+func (e *Backtest) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data completed before the virtual clock
+func (e *Backtest) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if period != e.settings.Period {
+		return resampleRecords(e.Records, map[string]string{e.settings.Period: ""}, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	records, err := e.loadRecords(stockType)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	last := e.completed(records)
+	first := last - size
+	if first < 0 {
+		first = 0
+	}
+	return append([]Record{}, records[first:last]...), nil
+}
+
+// GetRecords get candlestick data
+func (e *Backtest) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
+
+// Listen the events are pushed by the simulator, so there is nothing to start
+func (e *Backtest) Listen() error {
+	return nil
+}
+
+// Events take all the order and balance events of the simulator
+func (e *Backtest) Events() ([]Event, error) {
+	return e.sim.drainEvents(), nil
+}
+
+// GetEvents get the order and balance changes since the last call
+func (e *Backtest) GetEvents() interface{} {
+	if e.sim.callback {
+		return e.fail("GetEvents", errCallbacks)
+	}
+	events, err := e.Events()
+	if err != nil {
+		return e.fail("GetEvents", err)
+	}
+	return events
+}
+
+// UseCallbacks pass the events to onOrder and onBalance of the script only, GetEvents fails after it
+func (e *Backtest) UseCallbacks() {
+	e.sim.callback = true
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+func TestBacktestAdvance(t *testing.T) {
+	e := NewBacktest(Option{Type: constant.Backtest, Name: "backtest", Settings: `{"period": "M"}`}).(*Backtest)
+	// 没有加载K线也没有结束时间时回测立即结束
+	if e.Advance(0) {
+		t.Fatal("expect the backtest to finish without records")
+	}
+	start := int64(1687255200)
+	for i := int64(0); i < 5; i++ {
+		e.records["BTC/USDT"] = append(e.records["BTC/USDT"], Record{Time: start + i*60, Open: 100, High: 101, Low: 99, Close: 100})
+	}
+	e.now = start + 60
+	for i := 0; i < 3; i++ {
+		if !e.Advance(0) {
+			t.Fatalf("step %d: expect the backtest to go on", i)
+		}
+	}
+	if e.cursors["BTC/USDT"] != 4 {
+		t.Errorf("expect the cursor at 4, got %d", e.cursors["BTC/USDT"])
+	}
+	records, err := e.Records("BTC/USDT", "M", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Time != start+180 {
+		t.Errorf("unexpected records %+v", records)
+	}
+	if e.Advance(0) {
+		t.Error("expect the backtest to finish when the records run out")
+	}
+}
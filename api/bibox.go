@@ -7,9 +7,9 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
@@ -17,27 +17,50 @@ import (
 	"github.com/miaolz123/conver"
 )
 
+// biboxErrorKinds the error codes of bibox.io
+var biboxErrorKinds = map[string]string{
+	"3012": ErrAuth,
+	"3016": ErrInvalidSymbol,
+	"3025": ErrAuth,
+	"4003": ErrRateLimited,
+}
+
 // BIBOX the exchange struct of bibox.io
 type BIBOX struct {
 	stockTypeMap     map[string]string
-	stocksTypeMap    map[string][2]string
 	tradeTypeMap     map[string]string
 	orderSideMap     map[int64]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
 	host             string
 	logger           model.Logger
 	option           Option
+	lastError        *Error
+
+	limiter    *rateLimiter
+	httpClient *http.Client
+}
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+// biboxPeriods the periods of the records the exchange supports
+var biboxPeriods = map[string]string{
+	"M":   "1min",
+	"M5":  "5min",
+	"M15": "15min",
+	"M30": "30min",
+	"H":   "1hour",
+	"D":   "1day",
+	"W":   "1week",
 }
 
+// biboxOrderTypes the order types the exchange supports
+var biboxOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket}
+
 // NewBibox create an exchange struct of bibox.io
 func NewBibox(opt Option) Exchange {
-	return &BIBOX{
+	e := &BIBOX{
 		stockTypeMap: map[string]string{
 			"BTC/USDT":  "BTC_USDT",
 			"ETH/USDT":  "ETH_USDT",
@@ -45,13 +68,6 @@ func NewBibox(opt Option) Exchange {
 			"ONT/USDT":  "ONT_USDT",
 			"QTUM/USDT": "QTUM_USDT",
 		},
-		stocksTypeMap: map[string][2]string{
-			"BTC/USDT":  [2]string{"BTC", "USDT"},
-			"ETH/USDT":  [2]string{"ETH", "USDT"},
-			"EOS/USDT":  [2]string{"EOS", "USDT"},
-			"ONT/USDT":  [2]string{"ONT", "USDT"},
-			"QTUM/USDT": [2]string{"QTUM", "USDT"},
-		},
 		tradeTypeMap: map[string]string{
 			"buy":         constant.TradeTypeBuy,
 			"sell":        constant.TradeTypeSell,
@@ -62,15 +78,7 @@ func NewBibox(opt Option) Exchange {
 			1: constant.TradeTypeBuy,
 			2: constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "1min",
-			"M5":  "5min",
-			"M15": "15min",
-			"M30": "30min",
-			"H":   "1hour",
-			"D":   "1day",
-			"W":   "1week",
-		},
+		recordsPeriodMap: biboxPeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT":  0.001,
 			"ETH/USDT":  0.001,
@@ -78,14 +86,17 @@ func NewBibox(opt Option) Exchange {
 			"ONT/USDT":  0.001,
 			"QTUM/USDT": 0.001,
 		},
-		records: make(map[string][]Record),
-		host:    "https://api.bibox365.com/v1/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: biboxOrderTypes,
+		host:       baseURLOf(opt, "https://api.bibox365.com/v1/"),
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter:    limiterOf(opt),
+		httpClient: newHTTPClient(opt),
 	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -103,31 +114,66 @@ func (e *BIBOX) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *BIBOX) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *BIBOX) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *BIBOX) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *BIBOX) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *BIBOX) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the markets of this exchange
+func (e *BIBOX) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the pair list of bibox.io, it has no trading rules so the min amounts are kept
+func (e *BIBOX) loadMarkets() (markets []Market, err error) {
+	resp, err := get(e.httpClient, e.host+"mdata?cmd=pairList")
+	if err != nil {
+		return
+	}
+	json, err := simplejson.NewJson(resp)
+	if err != nil {
+		return
+	}
+	pairsJSON := json.Get("result")
+	for i := 0; i < len(pairsJSON.MustArray()); i++ {
+		symbol := pairsJSON.GetIndex(i).Get("pair").MustString()
+		base, quote, err := splitStockType(strings.Replace(symbol, "_", "/", -1))
+		if err != nil {
+			continue
+		}
+		markets = append(markets, Market{
+			StockType:     base + "/" + quote,
+			Symbol:        symbol,
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			MinAmount:     e.minAmountMap[base+"/"+quote],
+		})
+	}
+	return
 }
 
 func (e *BIBOX) getAuthJSON(url string, params []string) (json *simplejson.Json, err error) {
-	e.lastTimes++
-	resp, err := post_gateio(url, params, e.option.AccessKey, signSha512(params, e.option.SecretKey))
+	resp, err := post_gateio(e.httpClient, url, params, e.option.AccessKey, signSha512(params, e.option.SecretKey))
 	if err != nil {
 		return
 	}
@@ -135,7 +181,6 @@ func (e *BIBOX) getAuthJSON(url string, params []string) (json *simplejson.Json,
 }
 
 func (e *BIBOX) getSign(params string) string {
-	e.lastTimes++
 	key := []byte(e.option.SecretKey)
 	mac := hmac.New(md5.New, key)
 	mac.Write([]byte(params))
@@ -152,47 +197,64 @@ type UserAssetsBody struct {
 	Select int `json:"select"`
 }
 
-// GetAccount get the account detail of this exchange
-func (e *BIBOX) GetAccount() interface{} {
+// request sign the cmds, post them to the api and return the result of the first cmd
+func (e *BIBOX) request(method, api string, cmds interface{}) (result *simplejson.Json, err error) {
+	data, _ := json.Marshal(cmds)
+	forms := []string{
+		"cmds=" + string(data),
+		"apikey=" + e.option.AccessKey,
+		"sign=" + e.getSign(string(data)),
+	}
+	resp, err := post(e.httpClient, e.host+api, forms)
+	if err != nil {
+		err = wrapError(method, err)
+		return
+	}
+	jsonResp, err := simplejson.NewJson(resp)
+	if err != nil {
+		err = wrapError(method, err)
+		return
+	}
+	if jsonResp.Get("error").Interface() != nil {
+		err = newCodeError(method, biboxErrorKinds, jsonResp.Get("error").Get("code").Interface(), jsonResp.Get("error").Get("msg").MustString())
+		return
+	}
+	return jsonResp.Get("result").GetIndex(0).Get("result"), nil
+}
+
+// GetLastError get the last error of this exchange
+func (e *BIBOX) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *BIBOX) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
 
+// Account get the account detail of this exchange
+func (e *BIBOX) Account() (Account, error) {
 	param := UserAsset{
 		Cmd: "transfer/assets",
 		Body: UserAssetsBody{
 			Select: 1,
 		},
 	}
-	params := []UserAsset{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"transfer", forms)
+	jsons, err := e.request("GetAccount", "transfer", []UserAsset{param})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, err
 	}
-
-	jsonResp, err := simplejson.NewJson(resp)
+	balancesArray, err := jsons.Get("assets_list").Array()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, wrapError("GetAccount", err)
 	}
 
-	jsons := jsonResp.Get("result").GetIndex(0)
-	balancesArray, err := jsons.Get("result").Get("assets_list").Array()
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
-	}
-
-	result := map[string]float64{
+	result := Account{
 		"USDT":       0,
 		"FrozenUSDT": 0,
 		"BTC":        0,
@@ -206,8 +268,8 @@ func (e *BIBOX) GetAccount() interface{} {
 		"QTUM":       0,
 		"FrozenQTUM": 0,
 	}
-	for i, _ := range balancesArray {
-		balance := jsons.Get("result").Get("assets_list").GetIndex(i)
+	for i := range balancesArray {
+		balance := jsons.Get("assets_list").GetIndex(i)
 		symbol := strings.ToUpper(balance.Get("coin_symbol").MustString())
 		avail := balance.Get("balance").MustString()
 		freeze := balance.Get("freeze").MustString()
@@ -216,28 +278,46 @@ func (e *BIBOX) GetAccount() interface{} {
 		result["Frozen"+symbol] = conver.Float64Must(freeze)
 	}
 
-	return result
+	return result, nil
 }
 
-// Trade place an order
-func (e *BIBOX) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// GetAccount get the account detail of this exchange
+func (e *BIBOX) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency
+func (e *BIBOX) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	_, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", 1, constant.BUY, stockType, price, amount, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", 2, constant.SELL, stockType, price, amount, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
+	}
+}
+
+// Trade place an order
+func (e *BIBOX) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
 	}
+	return id
 }
 
 type OrderTrade struct {
@@ -253,112 +333,39 @@ type OrderDetail struct {
 	Order_side   int     `json:"order_side"`
 	Price        float64 `json:"price"`
 	Amount       float64 `json:"amount"`
+	Money        float64 `json:"money,omitempty"` //市价买单花费的计价币数量
 }
 
-func (e *BIBOX) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	param := OrderTrade{
-		Cmd:   "orderpending/trade",
-		Index: 1,
-		Body: OrderDetail{
-			Pair:         e.stockTypeMap[stockType],
-			Account_type: 0,
-			Order_type:   2,
-			Order_side:   1,
-			Price:        price,
-			Amount:       amount,
-		},
-	}
-	params := []OrderTrade{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"orderpending", forms)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "buy() error, ", err)
-		return false
-	}
-
-	fmt.Println("buy: ", string(resp))
-	/// get result:
-	jsonResp, err := simplejson.NewJson(resp)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "buy() error, ", err)
-		return false
-	}
-
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "buy() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
-		return false
-	}
-
-	jsons := jsonResp.Get("result").GetIndex(0)
-	orderID := jsons.Get("result").MustInt64()
-	// index := jsons.Get("index").MustInt64()
-	// cmd := jsons.Get("cmd").MustString()
-
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return fmt.Sprint(orderID)
-}
-
-func (e *BIBOX) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
+func (e *BIBOX) place(method string, orderSide int, logType, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	param := OrderTrade{
 		Cmd:   "orderpending/trade",
 		Index: 1,
 		Body: OrderDetail{
-			Pair:         e.stockTypeMap[stockType],
+			Pair:         e.markets.symbol(stockType),
 			Account_type: 0,
 			Order_type:   2,
-			Order_side:   2,
+			Order_side:   orderSide,
 			Price:        price,
 			Amount:       amount,
 		},
 	}
-	params := []OrderTrade{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"orderpending", forms)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "sell() error, ", err)
-		return false
+	// order_type: 1 市价单, 2 限价单
+	if price <= 0 {
+		param.Body.Order_type = 1
+		param.Body.Price = 0.0
+		if orderSide == 1 {
+			param.Body.Amount = 0.0
+			param.Body.Money = amount
+		}
 	}
-
-	fmt.Println("sell: ", string(resp))
-	/// get result:
-	jsonResp, err := simplejson.NewJson(resp)
+	jsons, err := e.request(method, "orderpending", []OrderTrade{param})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "sell() error, ", err)
-		return false
+		return "", err
 	}
+	orderID := jsons.MustInt64()
 
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "sell() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
-		return false
-	}
-
-	jsons := jsonResp.Get("result").GetIndex(0)
-	orderID := jsons.Get("result").MustInt64()
-	// index := jsons.Get("index").MustInt64()
-	// cmd := jsons.Get("cmd").MustString()
-
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return fmt.Sprint(orderID)
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return fmt.Sprint(orderID), nil
 }
 
 type OrderRequest struct {
@@ -370,63 +377,39 @@ type OrderRequestBody struct {
 	ID int64 `json:"id"`
 }
 
-// GetOrder get details of an order
-func (e *BIBOX) GetOrder(stockType, id string) interface{} {
+// Order get details of an order
+func (e *BIBOX) Order(stockType, id string) (Order, error) {
 	idInt, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return Order{}, newError(ErrUnknown, "GetOrder", "invalid order id: ", id)
+	}
 	param := OrderRequest{
 		Cmd: "orderpending/order",
 		Body: OrderRequestBody{
 			ID: idInt,
 		},
 	}
-	params := []OrderRequest{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"orderpending", forms)
+	orderJSON, err := e.request("GetOrder", "orderpending", []OrderRequest{param})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
+		return Order{}, err
 	}
+	return Order{
+		ID:         fmt.Sprint(orderJSON.Get("id").MustInt64()),
+		Price:      conver.Float64Must(orderJSON.Get("price").Interface()),
+		Amount:     conver.Float64Must(orderJSON.Get("amount").Interface()),
+		DealAmount: conver.Float64Must(orderJSON.Get("deal_amount").Interface()),
+		TradeType:  e.orderSideMap[orderJSON.Get("order_side").MustInt64()],
+		StockType:  orderJSON.Get("pair").MustString(),
+	}, nil
+}
 
-	fmt.Println("GetOrder resp: ", string(resp))
-	/// get result:
-	jsonResp, err := simplejson.NewJson(resp)
+// GetOrder get details of an order
+func (e *BIBOX) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
-	}
-
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
-		return false
-	}
-
-	jsons := jsonResp.Get("result").GetIndex(0)
-	orderJSON := jsons.Get("result")
-	orderID := orderJSON.Get("id").MustInt64()
-	price := conver.Float64Must(orderJSON.Get("price").Interface())
-	amount := conver.Float64Must(orderJSON.Get("amount").Interface())
-	dealAmount := conver.Float64Must(orderJSON.Get("deal_amount").Interface())
-	tradeType := e.orderSideMap[orderJSON.Get("order_side").MustInt64()]
-	symbol := orderJSON.Get("pair").MustString()
-
-	return Order{
-		ID:         fmt.Sprint(orderID),
-		Price:      price,
-		Amount:     amount,
-		DealAmount: dealAmount,
-		TradeType:  tradeType,
-		StockType:  symbol,
+		return e.fail("GetOrder", err)
 	}
+	return order
 }
 
 type OrderPendingRequest struct {
@@ -443,163 +426,113 @@ type OrderPendingBody struct {
 	Currency_symbol string `json:"currency_symbol"`
 }
 
+// Orders get all unfilled orders
+func (e *BIBOX) Orders(stockType string) ([]Order, error) {
+	return e.list("GetOrders", "orderpending/orderPendingList", stockType)
+}
+
 // GetOrders get all unfilled orders
 func (e *BIBOX) GetOrders(stockType string) interface{} {
-	ordersBid := e.getOrders(1, stockType).([]Order)
-	ordersAsk := e.getOrders(2, stockType).([]Order)
-	orders := append(ordersBid, ordersAsk...)
-
-	fmt.Println("GetOrders orders: ", orders)
-
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
 	return orders
 }
 
-func (e *BIBOX) getOrders(tradeType int, stockType string) interface{} {
-	coins, ok := e.stocksTypeMap[stockType]
+// Trades get the recent fills
+func (e *BIBOX) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	market, ok := e.markets.get(stockType)
 	if !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	param := OrderPendingRequest{
-		Cmd: "orderpending/orderPendingList",
-		Body: OrderPendingBody{
-			Account_type:    0,
-			Order_side:      tradeType,
-			Page:            1,
-			Size:            1000,
-			Coin_symbol:     coins[0],
-			Currency_symbol: coins[1],
-		},
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
 	}
-	params := []OrderPendingRequest{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"orderpending", forms)
+	tradesJSON, err := e.items("GetTrades", "orderpending/orderHistoryList", market)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
-	}
-
-	fmt.Println("GetOrders resp: ", string(resp))
-	/// get result:
-	jsonResp, err := simplejson.NewJson(resp)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
-	}
-
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
-		return false
-	}
-
-	orders := []Order{}
-	jsons := jsonResp.Get("result").GetIndex(0)
-	ordersJSON := jsons.Get("result").Get("items")
-	count := len(ordersJSON.MustArray())
-	for i := 0; i < count; i++ {
-		orderJSON := ordersJSON.GetIndex(i)
-		orders = append(orders, Order{
-
-			ID:         fmt.Sprint(orderJSON.Get("id").MustInt64()),
-			Price:      conver.Float64Must(orderJSON.Get("price").Interface()),
-			Amount:     conver.Float64Must(orderJSON.Get("amount").Interface()),
-			DealAmount: conver.Float64Must(orderJSON.Get("deal_amount").Interface()),
-			TradeType:  e.orderSideMap[orderJSON.Get("order_side").MustInt64()],
-			StockType:  orderJSON.Get("coin_symbol").MustString() + orderJSON.Get("currency_symbol").MustString(),
+		return nil, err
+	}
+	trades := []Trade{}
+	for _, tradeJSON := range tradesJSON {
+		tradeType := e.orderSideMap[tradeJSON.Get("order_side").MustInt64()]
+		feeCurrency := strings.ToUpper(tradeJSON.Get("fee_symbol").MustString())
+		if feeCurrency == "" {
+			feeCurrency = feeCurrencyOf(stockType, tradeType)
+		}
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(tradeJSON.Get("id").MustInt64()),
+			OrderID:     fmt.Sprint(tradeJSON.Get("order_id").Interface()),
+			Price:       conver.Float64Must(tradeJSON.Get("price").Interface()),
+			Amount:      conver.Float64Must(tradeJSON.Get("amount").Interface()),
+			Fee:         conver.Float64Must(tradeJSON.Get("fee").Interface()),
+			FeeCurrency: feeCurrency,
+			Time:        tradeJSON.Get("createdAt").MustInt64() / 1000,
+			TradeType:   tradeType,
+			StockType:   stockType,
 		})
 	}
-
-	return orders
+	return trades, nil
 }
 
-// GetTrades get all filled orders recently
+// GetTrades get the recent fills
 func (e *BIBOX) GetTrades(stockType string) interface{} {
-
-	ordersBid := e.getTrades(1, stockType).([]Order)
-	ordersAsk := e.getTrades(2, stockType).([]Order)
-	orders := append(ordersBid, ordersAsk...)
-
-	fmt.Println("GetTrades orders: ", orders)
-
-	return orders
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
 }
 
-func (e *BIBOX) getTrades(tradeType int, stockType string) interface{} {
-	coins, ok := e.stocksTypeMap[stockType]
+// list get the bid and ask orders of the cmd
+func (e *BIBOX) list(method, cmd, stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	market, ok := e.markets.get(stockType)
 	if !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "getTrades() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	param := OrderPendingRequest{
-		Cmd: "orderpending/pendingHistoryList",
-		Body: OrderPendingBody{
-			Account_type:    0,
-			Order_side:      tradeType,
-			Page:            1,
-			Size:            1000,
-			Coin_symbol:     coins[0],
-			Currency_symbol: coins[1],
-		},
+		return nil, newError(ErrInvalidSymbol, method, "unrecognized stockType: ", stockType)
 	}
-	params := []OrderPendingRequest{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"orderpending", forms)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "getTrades() error, ", err)
-		return false
-	}
-
-	fmt.Println("getTrades resp: ", string(resp))
-	/// get result:
-	jsonResp, err := simplejson.NewJson(resp)
+	ordersJSON, err := e.items(method, cmd, market)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "getTrades() error, ", err)
-		return false
+		return nil, err
 	}
-
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "getTrades() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
-		return false
-	}
-
 	orders := []Order{}
-	jsons := jsonResp.Get("result").GetIndex(0)
-	ordersJSON := jsons.Get("result").Get("items")
-	count := len(ordersJSON.MustArray())
-	for i := 0; i < count; i++ {
-		orderJSON := ordersJSON.GetIndex(i)
+	for _, orderJSON := range ordersJSON {
 		orders = append(orders, Order{
-
 			ID:         fmt.Sprint(orderJSON.Get("id").MustInt64()),
 			Price:      conver.Float64Must(orderJSON.Get("price").Interface()),
 			Amount:     conver.Float64Must(orderJSON.Get("amount").Interface()),
 			DealAmount: conver.Float64Must(orderJSON.Get("deal_amount").Interface()),
 			TradeType:  e.orderSideMap[orderJSON.Get("order_side").MustInt64()],
-			StockType:  orderJSON.Get("coin_symbol").MustString() + orderJSON.Get("currency_symbol").MustString(),
+			StockType:  stockType,
 		})
 	}
-
-	return orders
+	return orders, nil
+}
+
+// items get the items of the cmd on both the bid and the ask side
+func (e *BIBOX) items(method, cmd string, market Market) ([]*simplejson.Json, error) {
+	items := []*simplejson.Json{}
+	for _, orderSide := range []int{1, 2} {
+		param := OrderPendingRequest{
+			Cmd: cmd,
+			Body: OrderPendingBody{
+				Account_type:    0,
+				Order_side:      orderSide,
+				Page:            1,
+				Size:            1000,
+				Coin_symbol:     market.BaseCurrency,
+				Currency_symbol: market.QuoteCurrency,
+			},
+		}
+		jsons, err := e.request(method, "orderpending", []OrderPendingRequest{param})
+		if err != nil {
+			return nil, err
+		}
+		itemsJSON := jsons.Get("items")
+		count := len(itemsJSON.MustArray())
+		for i := 0; i < count; i++ {
+			items = append(items, itemsJSON.GetIndex(i))
+		}
+	}
+	return items, nil
 }
 
 type OrderCancelRequest struct {
@@ -612,9 +545,12 @@ type OrderCancelBody struct {
 	ID int64 `json:"id"`
 }
 
-// CancelOrder cancel an order
-func (e *BIBOX) CancelOrder(order Order) bool {
+// Cancel cancel an order
+func (e *BIBOX) Cancel(order Order) error {
 	idInt, err := strconv.ParseInt(order.ID, 10, 64)
+	if err != nil {
+		return newError(ErrUnknown, "CancelOrder", "invalid order id: ", order.ID)
+	}
 	param := OrderCancelRequest{
 		Cmd:   "orderpending/cancelTrade",
 		Index: 1,
@@ -622,77 +558,45 @@ func (e *BIBOX) CancelOrder(order Order) bool {
 			ID: idInt,
 		},
 	}
-	params := []OrderCancelRequest{}
-	params = append(params, param)
-	cmds, _ := json.Marshal(&params)
-
-	forms := []string{}
-	cmdsItem := "cmds=" + string(cmds)
-	keyItem := "apikey=" + e.option.AccessKey
-	signItem := "sign=" + e.getSign(string(cmds))
-	forms = append(forms, cmdsItem)
-	forms = append(forms, keyItem)
-	forms = append(forms, signItem)
-
-	resp, err := post(e.host+"orderpending", forms)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
-	}
-
-	fmt.Println("CancelOrder resp: ", string(resp))
-	/// get result:
-	jsonResp, err := simplejson.NewJson(resp)
+	jsons, err := e.request("CancelOrder", "orderpending", []OrderCancelRequest{param})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
-	}
-
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
-		return false
+		return err
 	}
 
-	jsons := jsonResp.Get("result").GetIndex(0)
-	msg := jsons.Get("result").MustString()
-
-	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, msg)
-	return true
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, jsons.MustString())
+	return nil
 }
 
-// GetTicker get market ticker & depth
-func (e *BIBOX) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+// CancelOrder cancel an order
+func (e *BIBOX) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
 	}
-	return ticker
+	return true
 }
 
-// getTicker get market ticker & depth
-func (e *BIBOX) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *BIBOX) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	resp, err := get(fmt.Sprintf("%s%s?cmd=depth&pair=%s&size=10", e.host, "mdata", e.stockTypeMap[stockType]))
+	if size <= 0 {
+		size = 10
+	}
+	resp, err := get(e.httpClient, fmt.Sprintf("%s%s?cmd=depth&pair=%s&size=%d", e.host, "mdata", e.markets.symbol(stockType), size))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
-
-	fmt.Println("getTicker resp: ", string(resp))
-	/// get result:
 	jsonResp, err := simplejson.NewJson(resp)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "getTicker() error, ", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
-
-	if result := jsonResp.Get("error").Interface(); result != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "getTicker() error, the error message => ", jsonResp.Get("error").Get("msg").MustString())
+	if jsonResp.Get("error").Interface() != nil {
+		err = newCodeError("GetTicker", biboxErrorKinds, jsonResp.Get("error").Get("code").Interface(), jsonResp.Get("error").Get("msg").MustString())
 		return
 	}
 
@@ -716,7 +620,7 @@ func (e *BIBOX) getTicker(stockType string, sizes ...interface{}) (ticker Ticker
 	}
 
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
 
@@ -727,7 +631,62 @@ func (e *BIBOX) getTicker(stockType string, sizes ...interface{}) (ticker Ticker
 	return
 }
 
+// GetTicker get market ticker & depth
+func (e *BIBOX) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data
+func (e *BIBOX) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	} else if size > 1000 {
+		size = 1000
+	}
+	fetch := e.store.missing(stockType, period, size)
+	resp, err := get(e.httpClient, fmt.Sprintf("%s%s?cmd=kline&pair=%s&period=%s&size=%d", e.host, "mdata", e.markets.symbol(stockType), e.recordsPeriodMap[period], fetch))
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	jsonResp, err := simplejson.NewJson(resp)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	if jsonResp.Get("error").Interface() != nil {
+		return nil, newCodeError("GetRecords", biboxErrorKinds, jsonResp.Get("error").Get("code").Interface(), jsonResp.Get("error").Get("msg").MustString())
+	}
+	klinesJSON := jsonResp.Get("result")
+	recordsNew := []Record{}
+	for i := 0; i < len(klinesJSON.MustArray()); i++ {
+		kline := klinesJSON.GetIndex(i)
+		recordsNew = append(recordsNew, Record{
+			Time:   conver.Int64Must(kline.Get("time").Interface()) / 1000,
+			Open:   conver.Float64Must(kline.Get("open").Interface()),
+			High:   conver.Float64Must(kline.Get("high").Interface()),
+			Low:    conver.Float64Must(kline.Get("low").Interface()),
+			Close:  conver.Float64Must(kline.Get("close").Interface()),
+			Volume: conver.Float64Must(kline.Get("vol").Interface()),
+		})
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
 // GetRecords get candlestick data
 func (e *BIBOX) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
-	return nil
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
 }
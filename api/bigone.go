@@ -1,8 +1,6 @@
 package api
 
 import (
-	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
@@ -14,26 +12,39 @@ import (
 
 // BigOne the exchange struct of big.one
 type BigOne struct {
+	client           *BigoneAPI.Bigone
 	stockTypeMap     map[string]string
 	tradeTypeMap     map[string]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
 	logger           model.Logger
 	option           Option
+	lastError        *Error
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+	limiter *rateLimiter
 }
 
-var bo *BigoneAPI.Bigone
+// bigOnePeriods the periods of the records the exchange supports
+var bigOnePeriods = map[string]string{
+	"M":   "min1",
+	"M5":  "min5",
+	"M15": "min15",
+	"M30": "min30",
+	"H":   "hour1",
+	"D":   "day1",
+	"W":   "week1",
+}
+
+// bigOneOrderTypes the order types the exchange supports
+var bigOneOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC}
 
 // NewBigOne create an exchange struct of big.one
 func NewBigOne(opt Option) Exchange {
-	bo = BigoneAPI.New(http.DefaultClient, opt.AccessKey, opt.SecretKey)
-	//...
-	return &BigOne{
+	e := &BigOne{
+		client: BigoneAPI.New(newHTTPClient(opt), opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
 			"BTC/USDT": "BTC-USDT",
 			"ONE/USDT": "ONE-USDT",
@@ -46,15 +57,7 @@ func NewBigOne(opt Option) Exchange {
 			"BID": constant.TradeTypeBuy,
 			"ASK": constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "001",
-			"M5":  "005",
-			"M15": "015",
-			"M30": "030",
-			"H":   "060",
-			"D":   "100",
-			"W":   "200",
-		},
+		recordsPeriodMap: bigOnePeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT": 0.001,
 			"ONE/USDT": 0.001,
@@ -63,13 +66,16 @@ func NewBigOne(opt Option) Exchange {
 			"BCH/USDT": 0.001,
 			"EOS/ETH":  0.001,
 		},
-		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: bigOneOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter: limiterOf(opt),
 	}
+	e.client.BaseURL = baseURLOf(opt, e.client.BaseURL)
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -87,41 +93,89 @@ func (e *BigOne) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *BigOne) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *BigOne) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *BigOne) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *BigOne) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes, "GetOrder")
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *BigOne) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
 }
 
-// GetAccount get the account detail of this exchange
-func (e *BigOne) GetAccount() interface{} {
-	result, err := bo.GetAccount()
+// GetMarkets get all the markets of this exchange
+func (e *BigOne) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from big.one
+func (e *BigOne) loadMarkets() (markets []Market, err error) {
+	result, err := e.client.GetMarkets()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return
 	}
 	if len(result.Errors) > 0 {
-		//log.Printf("response error : %v", result.Errors)
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", result.Errors[0].Message)
-		return false
+		err = newCodeError("GetMarkets", nil, result.Errors[0].Code, result.Errors[0].Message)
+		return
+	}
+	for _, m := range result.Data {
+		base := strings.ToUpper(m.BaseAsset.Symbol)
+		quote := strings.ToUpper(m.QuoteAsset.Symbol)
+		markets = append(markets, Market{
+			StockType:       base + "/" + quote,
+			Symbol:          m.Name,
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  m.QuoteScale,
+			AmountPrecision: m.BaseScale,
+			TickSize:        stepOf(m.QuoteScale),
+			LotSize:         stepOf(m.BaseScale),
+			MinAmount:       stepOf(m.BaseScale),
+		})
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *BigOne) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
 	}
-	accInfo := make(map[string]float64)
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *BigOne) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *BigOne) Account() (Account, error) {
+	result, err := e.client.GetAccount()
+	if err != nil {
+		return nil, wrapError("GetAccount", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, newCodeError("GetAccount", nil, result.Errors[0].Code, result.Errors[0].Message)
+	}
+	accInfo := Account{}
 	for _, v := range result.Data {
 		available := conver.Float64Must(v.Balance)
 		freez := conver.Float64Must(v.LockedBalance)
@@ -132,81 +186,97 @@ func (e *BigOne) GetAccount() interface{} {
 			accInfo["Frozen"+strings.ToUpper(v.AssetID)] = freez
 		}
 	}
-	return accInfo
+	return accInfo, nil
 }
 
-// Trade place an order
-func (e *BigOne) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// GetAccount get the account detail of this exchange
+func (e *BigOne) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency
+func (e *BigOne) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", "BID", constant.BUY, stockType, price, amount, opts, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", "ASK", constant.SELL, stockType, price, amount, opts, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
 }
 
-func (e *BigOne) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := bo.LimitBuy(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType])
+// Trade place an order
+func (e *BigOne) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
+		return e.fail("Trade", err)
 	}
-	if len(result.Errors) > 0 {
-		//log.Printf("response error : %v", result.Errors)
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", result.Errors[0].Message)
-		return false
-	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return result.Data.ID
+	return id
 }
 
-func (e *BigOne) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := bo.LimitSell(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType])
+func (e *BigOne) place(method, side, logType, stockType string, price, amount float64, opts OrderOptions, msgs ...interface{}) (string, error) {
+	orderType, orderPrice := "LIMIT", conver.StringMust(price)
+	if price <= 0 {
+		orderType, orderPrice = "MARKET", ""
+		price = 0.0
+	}
+	options := map[string]string{}
+	if opts.PostOnly {
+		options["post_only"] = "true"
+	} else if opts.TimeInForce == constant.TimeInForceIOC {
+		options["immediate_or_cancel"] = "true"
+	}
+	result, err := e.client.PlaceOrder(conver.StringMust(amount), orderPrice, e.markets.symbol(stockType), orderType, side, options)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
+		return "", wrapError(method, err)
 	}
 	if len(result.Errors) > 0 {
-		//log.Printf("response error : %v", result.Errors)
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", result.Errors[0].Message)
-		return false
+		return "", newCodeError(method, nil, result.Errors[0].Code, result.Errors[0].Message)
 	}
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return result.Data.ID
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return result.Data.ID, nil
+}
+
+// Order get details of an order
+func (e *BigOne) Order(stockType, id string) (Order, error) {
+	return Order{}, newError(ErrNotSupported, "GetOrder", "not supported yet")
 }
 
 // GetOrder get details of an order
 func (e *BigOne) GetOrder(stockType, id string) interface{} {
-	return nil
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
 }
 
-// GetOrders get all unfilled orders
-func (e *BigOne) GetOrders(stockType string) interface{} {
+// Orders get all unfilled orders
+func (e *BigOne) Orders(stockType string) ([]Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
-	result, err := bo.GetUnfinishOrders(e.stockTypeMap[stockType])
+	result, err := e.client.GetUnfinishOrders(e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
+		return nil, wrapError("GetOrders", err)
 	}
 	if len(result.Errors) > 0 {
-		//log.Printf("response error : %v", result.Errors)
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", result.Errors[0].Message)
-		return false
+		return nil, newCodeError("GetOrders", nil, result.Errors[0].Code, result.Errors[0].Message)
 	}
 	orders := []Order{}
 	for _, v := range result.Data.Edges {
@@ -220,40 +290,91 @@ func (e *BigOne) GetOrders(stockType string) interface{} {
 			StockType:  stockType,
 		})
 	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *BigOne) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
 	return orders
 }
 
-// GetTrades get all filled orders recently
+// Trades get the recent fills
+// the fills are taken from the filled orders, big.one does not return the fees of them
+func (e *BigOne) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	result, err := e.client.GetOrderHistorys(e.markets.symbol(stockType))
+	if err != nil {
+		return nil, wrapError("GetTrades", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, newCodeError("GetTrades", nil, result.Errors[0].Code, result.Errors[0].Message)
+	}
+	trades := []Trade{}
+	for _, v := range result.Data.Edges {
+		n := v.Node
+		tradeType := e.tradeTypeMap[n.Side]
+		t, _ := time.Parse(time.RFC3339, n.UpdatedAt)
+		trades = append(trades, Trade{
+			ID:          n.ID,
+			OrderID:     n.ID,
+			Price:       conver.Float64Must(n.AvgDealPrice),
+			Amount:      conver.Float64Must(n.FilledAmount),
+			FeeCurrency: feeCurrencyOf(stockType, tradeType),
+			Time:        t.Unix(),
+			TradeType:   tradeType,
+			StockType:   stockType,
+		})
+	}
+	return trades, nil
+}
+
+// GetTrades get the recent fills
 func (e *BigOne) GetTrades(stockType string) interface{} {
-	return nil
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
 }
 
-// CancelOrder cancel an order
-func (e *BigOne) CancelOrder(order Order) bool {
-	result, err := bo.CancelOrder(order.ID, e.stockTypeMap[order.StockType])
+// Cancel cancel an order
+func (e *BigOne) Cancel(order Order) error {
+	result, err := e.client.CancelOrder(order.ID, e.markets.symbol(order.StockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+		return wrapError("CancelOrder", err)
 	}
 	if len(result.Errors) > 0 {
-		//log.Printf("response error : %v", result.Errors)
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", result.Errors[0].Message)
-		return false
+		return newCodeError("CancelOrder", nil, result.Errors[0].Code, result.Errors[0].Message)
 	}
 	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *BigOne) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *BigOne) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *BigOne) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	result, err := bo.GetDepth(e.stockTypeMap[stockType])
+	result, err := e.client.GetDepth(e.markets.symbol(stockType))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	for _, bid := range result.Data.Bids {
@@ -269,9 +390,15 @@ func (e *BigOne) getTicker(stockType string, sizes ...interface{}) (ticker Ticke
 		})
 	}
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
+	if size > 0 && len(ticker.Bids) > size {
+		ticker.Bids = ticker.Bids[:size]
+	}
+	if size > 0 && len(ticker.Asks) > size {
+		ticker.Asks = ticker.Asks[:size]
+	}
 	ticker.Buy = ticker.Bids[0].Price
 	ticker.Sell = ticker.Asks[0].Price
 	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
@@ -280,15 +407,57 @@ func (e *BigOne) getTicker(stockType string, sizes ...interface{}) (ticker Ticke
 
 // GetTicker get market ticker & depth
 func (e *BigOne) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
+// Records get candlestick data
+func (e *BigOne) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	fetch := e.store.missing(stockType, period, size)
+	result, err := e.client.GetCandles(e.markets.symbol(stockType), e.recordsPeriodMap[period], fetch)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	if result.Code != 0 {
+		return nil, newCodeError("GetRecords", nil, result.Code, result.Message)
+	}
+	recordsNew := []Record{}
+	for i := len(result.Data); i > 0; i-- {
+		candle := result.Data[i-1]
+		t, err := time.Parse(time.RFC3339, candle.Time)
+		if err != nil {
+			continue
+		}
+		recordsNew = append(recordsNew, Record{
+			Time:   t.Unix(),
+			Open:   conver.Float64Must(candle.Open),
+			High:   conver.Float64Must(candle.High),
+			Low:    conver.Float64Must(candle.Low),
+			Close:  conver.Float64Must(candle.Close),
+			Volume: conver.Float64Must(candle.Volume),
+		})
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
 // GetRecords get candlestick data
 func (e *BigOne) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
-	return nil
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
 }
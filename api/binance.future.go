@@ -0,0 +1,782 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/HunterUPP/QuantBot/api/BinanceAPI"
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
+)
+
+// binanceFutureErrorKinds the error codes of binance futures
+var binanceFutureErrorKinds = map[string]string{
+	"-1003": ErrRateLimited,
+	"-1021": ErrAuth,
+	"-1022": ErrAuth,
+	"-1121": ErrInvalidSymbol,
+	"-2014": ErrAuth,
+	"-2015": ErrAuth,
+	"-2018": ErrInsufficientBalance,
+	"-2019": ErrInsufficientBalance,
+}
+
+// BinanceFuture the exchange struct of binance USDⓈ-M futures, the stockTypes are like BTC/USDT and the amount is in the base currency
+type BinanceFuture struct {
+	client           *BinanceAPI.FuturesClient
+	stockTypeMap     map[string]string
+	orderSideMap     map[string][2]string //交易类型对应的 side 和 positionSide
+	recordsPeriodMap map[string]string
+	minAmountMap     map[string]float64
+	orderTypes       []string //支持的订单类型
+	depthLimits      []int    //币安支持的深度档数
+	markets          *marketCache
+	store            *recordStore
+	logger           model.Logger
+	option           Option
+	lastError        *Error
+
+	mutex      sync.Mutex
+	leverage   int             //下单使用的杠杆倍数, 为 0 时使用交易所的设置
+	marginMode string          //保证金模式, 为空时使用交易所的设置
+	applied    map[string]bool //已经设置了杠杆倍数和保证金模式的交易对
+	hedgeMode  *bool           //是否是双向持仓模式, 第一次下单时获取
+
+	limiter *rateLimiter
+}
+
+// binanceFuturePeriods the periods of the records the exchange supports
+var binanceFuturePeriods = map[string]string{
+	"M":   "1m",
+	"M3":  "3m",
+	"M5":  "5m",
+	"M15": "15m",
+	"M30": "30m",
+	"H":   "1h",
+	"H2":  "2h",
+	"H4":  "4h",
+	"H6":  "6h",
+	"H12": "12h",
+	"D":   "1d",
+	"D3":  "3d",
+	"W":   "1w",
+}
+
+// binanceFutureOrderTypes the order types the exchange supports
+var binanceFutureOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
+// NewBinanceFuture create an exchange struct of binance USDⓈ-M futures
+func NewBinanceFuture(opt Option) Exchange {
+	e := &BinanceFuture{
+		client: BinanceAPI.NewFuturesClient(newHTTPClient(opt), opt.AccessKey, opt.SecretKey),
+		stockTypeMap: map[string]string{
+			"BTC/USDT": "BTCUSDT",
+			"ETH/USDT": "ETHUSDT",
+			"EOS/USDT": "EOSUSDT",
+		},
+		orderSideMap: map[string][2]string{
+			constant.TradeTypeLong:       {"BUY", "LONG"},
+			constant.TradeTypeShort:      {"SELL", "SHORT"},
+			constant.TradeTypeLongClose:  {"SELL", "LONG"},
+			constant.TradeTypeShortClose: {"BUY", "SHORT"},
+		},
+		recordsPeriodMap: binanceFuturePeriods,
+		minAmountMap: map[string]float64{
+			"BTC/USDT": 0.001,
+			"ETH/USDT": 0.001,
+			"EOS/USDT": 0.1,
+		},
+		orderTypes:  binanceFutureOrderTypes,
+		depthLimits: []int{5, 10, 20, 50, 100, 500, 1000},
+		logger:      model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:      opt,
+
+		applied: make(map[string]bool),
+		limiter: limiterOf(opt),
+	}
+	e.client.BaseURL = baseURLOf(opt, e.client.BaseURL)
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
+}
+
+// Log print something to console
+func (e *BinanceFuture) Log(msgs ...interface{}) {
+	e.logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
+}
+
+// GetType get the type of this exchange
+func (e *BinanceFuture) GetType() string {
+	return e.option.Type
+}
+
+// GetName get the name of this exchange
+func (e *BinanceFuture) GetName() string {
+	return e.option.Name
+}
+
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
+func (e *BinanceFuture) SetLimit(times interface{}) float64 {
+	return e.limiter.setRate(conver.Float64Must(times))
+}
+
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
+func (e *BinanceFuture) AutoSleep() {
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *BinanceFuture) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *BinanceFuture) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
+}
+
+// GetMinAmount get the min trade amonut of this exchange
+func (e *BinanceFuture) GetMinAmount(stock string) float64 {
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the contracts of this exchange
+func (e *BinanceFuture) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// request send a request and parse the json response, the error codes of binance are converted to the error kinds
+func (e *BinanceFuture) request(method, httpMethod, path string, params url.Values, signed bool) (*simplejson.Json, error) {
+	data, err := e.client.Request(httpMethod, path, params, signed)
+	if apiErr, ok := err.(*BinanceAPI.APIError); ok {
+		return nil, newCodeError(method, binanceFutureErrorKinds, apiErr.Code, apiErr.Msg)
+	} else if err != nil {
+		return nil, wrapError(method, err)
+	}
+	json, err := simplejson.NewJson(data)
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	return json, nil
+}
+
+// loadMarkets load the perpetual contracts from the exchange info of binance futures
+func (e *BinanceFuture) loadMarkets() (markets []Market, err error) {
+	json, err := e.request("GetMarkets", "GET", "fapi/v1/exchangeInfo", nil, false)
+	if err != nil {
+		return
+	}
+	symbols := json.Get("symbols")
+	for i := 0; i < len(symbols.MustArray()); i++ {
+		s := symbols.GetIndex(i)
+		if s.Get("status").MustString() != "TRADING" || s.Get("contractType").MustString() != "PERPETUAL" {
+			continue
+		}
+		m := Market{
+			StockType:     s.Get("baseAsset").MustString() + "/" + s.Get("quoteAsset").MustString(),
+			Symbol:        s.Get("symbol").MustString(),
+			BaseCurrency:  s.Get("baseAsset").MustString(),
+			QuoteCurrency: s.Get("quoteAsset").MustString(),
+		}
+		filters := s.Get("filters")
+		for j := 0; j < len(filters.MustArray()); j++ {
+			f := filters.GetIndex(j)
+			switch f.Get("filterType").MustString() {
+			case "PRICE_FILTER":
+				m.TickSize = conver.Float64Must(f.Get("tickSize").Interface())
+				m.PricePrecision = precisionOf(m.TickSize)
+			case "LOT_SIZE":
+				m.LotSize = conver.Float64Must(f.Get("stepSize").Interface())
+				m.AmountPrecision = precisionOf(m.LotSize)
+				m.MinAmount = conver.Float64Must(f.Get("minQty").Interface())
+			case "MIN_NOTIONAL":
+				m.MinNotional = conver.Float64Must(f.Get("notional").Interface())
+			}
+		}
+		markets = append(markets, m)
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *BinanceFuture) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *BinanceFuture) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the margin balances of this exchange, the frozen amount is the margin used by the positions and the orders
+func (e *BinanceFuture) Account() (Account, error) {
+	json, err := e.request("GetAccount", "GET", "fapi/v2/account", nil, true)
+	if err != nil {
+		return nil, err
+	}
+	account := Account{}
+	assets := json.Get("assets")
+	for i := 0; i < len(assets.MustArray()); i++ {
+		asset := assets.GetIndex(i)
+		key := strings.ToUpper(asset.Get("asset").MustString())
+		available := conver.Float64Must(asset.Get("availableBalance").Interface())
+		account[key] = available
+		account["Frozen"+key] = math.Max(conver.Float64Must(asset.Get("walletBalance").Interface())-available, 0.0)
+	}
+	return account, nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *BinanceFuture) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// SetLeverage set the leverage of the later orders, it is sent to binance before the next order of every symbol
+func (e *BinanceFuture) SetLeverage(leverage interface{}) bool {
+	l := conver.IntMust(leverage)
+	if l < 1 || l > 125 {
+		return e.fail("SetLeverage", newError(ErrNotSupported, "SetLeverage", "unrecognized leverage: ", leverage))
+	}
+	e.mutex.Lock()
+	e.leverage = l
+	e.applied = make(map[string]bool)
+	e.mutex.Unlock()
+	return true
+}
+
+// SetMarginMode set the margin mode, it is sent to binance before the next order of every symbol
+func (e *BinanceFuture) SetMarginMode(mode string) bool {
+	switch mode {
+	case constant.MarginModeCrossed, constant.MarginModeFixed:
+	default:
+		return e.fail("SetMarginMode", newError(ErrNotSupported, "SetMarginMode", "unrecognized margin mode: ", mode))
+	}
+	e.mutex.Lock()
+	e.marginMode = mode
+	e.applied = make(map[string]bool)
+	e.mutex.Unlock()
+	return true
+}
+
+// apply send the leverage and the margin mode of a symbol to binance if they are changed
+func (e *BinanceFuture) apply(symbol string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.applied[symbol] {
+		return nil
+	}
+	if e.marginMode != "" {
+		marginType := "CROSSED"
+		if e.marginMode == constant.MarginModeFixed {
+			marginType = "ISOLATED"
+		}
+		params := url.Values{}
+		params.Set("symbol", symbol)
+		params.Set("marginType", marginType)
+		if _, err := e.request("SetMarginMode", "POST", "fapi/v1/marginType", params, true); err != nil && !strings.Contains(err.Error(), "No need to change") {
+			return err
+		}
+	}
+	if e.leverage > 0 {
+		params := url.Values{}
+		params.Set("symbol", symbol)
+		params.Set("leverage", fmt.Sprint(e.leverage))
+		if _, err := e.request("SetLeverage", "POST", "fapi/v1/leverage", params, true); err != nil {
+			return err
+		}
+	}
+	e.applied[symbol] = true
+	return nil
+}
+
+// isHedgeMode check if the account holds the long and short positions separately
+func (e *BinanceFuture) isHedgeMode() (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.hedgeMode == nil {
+		json, err := e.request("Trade", "GET", "fapi/v1/positionSide/dual", nil, true)
+		if err != nil {
+			return false, err
+		}
+		hedge := json.Get("dualSidePosition").MustBool()
+		e.hedgeMode = &hedge
+	}
+	return *e.hedgeMode, nil
+}
+
+// Positions get the positions of stockType, the long and short positions are separate in the hedge mode
+func (e *BinanceFuture) Positions(stockType string) ([]Position, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetPositions", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	json, err := e.request("GetPositions", "GET", "fapi/v2/positionRisk", params, true)
+	if err != nil {
+		return nil, err
+	}
+	positions := []Position{}
+	for i := 0; i < len(json.MustArray()); i++ {
+		positionJSON := json.GetIndex(i)
+		amount := conver.Float64Must(positionJSON.Get("positionAmt").Interface())
+		if amount == 0.0 {
+			continue
+		}
+		tradeType := constant.TradeTypeLong
+		if positionJSON.Get("positionSide").MustString() == "SHORT" || amount < 0 {
+			tradeType = constant.TradeTypeShort
+		}
+		position := Position{
+			Price:            conver.Float64Must(positionJSON.Get("entryPrice").Interface()),
+			Leverage:         conver.IntMust(positionJSON.Get("leverage").Interface()),
+			Amount:           math.Abs(amount),
+			ConfirmAmount:    math.Abs(amount),
+			Profit:           conver.Float64Must(positionJSON.Get("unRealizedProfit").Interface()),
+			MarginMode:       constant.MarginModeCrossed,
+			LiquidationPrice: conver.Float64Must(positionJSON.Get("liquidationPrice").Interface()),
+			TradeType:        tradeType,
+			StockType:        stockType,
+		}
+		if positionJSON.Get("marginType").MustString() == "isolated" {
+			position.MarginMode = constant.MarginModeFixed
+			position.Margin = conver.Float64Must(positionJSON.Get("isolatedMargin").Interface())
+		} else if position.Leverage > 0 {
+			position.Margin = math.Abs(conver.Float64Must(positionJSON.Get("notional").Interface())) / float64(position.Leverage)
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+// GetPositions get the positions of stockType
+func (e *BinanceFuture) GetPositions(stockType string) interface{} {
+	positions, err := e.Positions(stockType)
+	if err != nil {
+		return e.fail("GetPositions", err)
+	}
+	return positions
+}
+
+// ClosePosition close all the amount of a position at the market price
+func (e *BinanceFuture) ClosePosition(position Position, msgs ...interface{}) interface{} {
+	tradeType := constant.TradeTypeLongClose
+	if position.TradeType == constant.TradeTypeShort {
+		tradeType = constant.TradeTypeShortClose
+	}
+	id, err := e.PlaceOrder(tradeType, position.StockType, 0.0, position.Amount, msgs...)
+	if err != nil {
+		return e.fail("ClosePosition", err)
+	}
+	return id
+}
+
+// FundingRate get the funding rate and the mark price of a perpetual contract
+func (e *BinanceFuture) FundingRate(stockType string) (FundingRate, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return FundingRate{}, newError(ErrInvalidSymbol, "GetFundingRate", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	json, err := e.request("GetFundingRate", "GET", "fapi/v1/premiumIndex", params, false)
+	if err != nil {
+		return FundingRate{}, err
+	}
+	return FundingRate{
+		Rate:      conver.Float64Must(json.Get("lastFundingRate").Interface()),
+		NextTime:  conver.Int64Must(json.Get("nextFundingTime").Interface()) / 1000,
+		MarkPrice: conver.Float64Must(json.Get("markPrice").Interface()),
+		StockType: stockType,
+	}, nil
+}
+
+// GetFundingRate get the funding rate and the mark price of a perpetual contract
+func (e *BinanceFuture) GetFundingRate(stockType string) interface{} {
+	rate, err := e.FundingRate(stockType)
+	if err != nil {
+		return e.fail("GetFundingRate", err)
+	}
+	return rate
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount is always in the base currency
+func (e *BinanceFuture) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	tradeType = strings.ToUpper(tradeType)
+	stockType = strings.ToUpper(stockType)
+	side, ok := e.orderSideMap[tradeType]
+	if !ok {
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
+	}
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	symbol := e.markets.symbol(stockType)
+	if err := e.apply(symbol); err != nil {
+		return "", err
+	}
+	hedge, err := e.isHedgeMode()
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("side", side[0])
+	params.Set("quantity", conver.StringMust(amount))
+	if hedge {
+		params.Set("positionSide", side[1])
+	} else if tradeType == constant.TradeTypeLongClose || tradeType == constant.TradeTypeShortClose {
+		// 单向持仓模式下平仓单只能减少持仓
+		params.Set("reduceOnly", "true")
+	}
+	if price > 0 {
+		// 只做 maker 的订单是 GTX
+		params.Set("type", "LIMIT")
+		params.Set("timeInForce", constant.TimeInForceGTC)
+		if opts.PostOnly {
+			params.Set("timeInForce", "GTX")
+		} else if opts.TimeInForce != "" {
+			params.Set("timeInForce", opts.TimeInForce)
+		}
+		params.Set("price", conver.StringMust(price))
+	} else {
+		params.Set("type", "MARKET")
+		price = 0.0
+	}
+	json, err := e.request("Trade", "POST", "fapi/v1/order", params, true)
+	if err != nil {
+		return "", err
+	}
+	e.logger.Log(tradeType, stockType, price, amount, msgs...)
+	return fmt.Sprint(json.Get("orderId").Interface()), nil
+}
+
+// Trade place an order
+func (e *BinanceFuture) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// tradeTypeOf get the trade type of an order by its side and position side
+func (e *BinanceFuture) tradeTypeOf(orderJSON *simplejson.Json) string {
+	side := orderJSON.Get("side").MustString()
+	positionSide := orderJSON.Get("positionSide").MustString()
+	if positionSide == "BOTH" {
+		positionSide = "LONG"
+		if (side == "SELL") != orderJSON.Get("reduceOnly").MustBool() {
+			positionSide = "SHORT"
+		}
+	}
+	for tradeType, s := range e.orderSideMap {
+		if s[0] == side && s[1] == positionSide {
+			return tradeType
+		}
+	}
+	return side
+}
+
+// parseOrder convert an order of binance to Order
+func (e *BinanceFuture) parseOrder(stockType string, orderJSON *simplejson.Json) Order {
+	return Order{
+		ID:         fmt.Sprint(orderJSON.Get("orderId").Interface()),
+		Price:      conver.Float64Must(orderJSON.Get("price").Interface()),
+		Amount:     conver.Float64Must(orderJSON.Get("origQty").Interface()),
+		DealAmount: conver.Float64Must(orderJSON.Get("executedQty").Interface()),
+		TradeType:  e.tradeTypeOf(orderJSON),
+		StockType:  stockType,
+	}
+}
+
+// Order get details of an order
+func (e *BinanceFuture) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	params.Set("orderId", id)
+	json, err := e.request("GetOrder", "GET", "fapi/v1/order", params, true)
+	if err != nil {
+		return Order{}, err
+	}
+	return e.parseOrder(stockType, json), nil
+}
+
+// GetOrder get details of an order
+func (e *BinanceFuture) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *BinanceFuture) Orders(stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	json, err := e.request("GetOrders", "GET", "fapi/v1/openOrders", params, true)
+	if err != nil {
+		return nil, err
+	}
+	orders := []Order{}
+	for i := 0; i < len(json.MustArray()); i++ {
+		orders = append(orders, e.parseOrder(stockType, json.GetIndex(i)))
+	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *BinanceFuture) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the recent fills
+func (e *BinanceFuture) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	params.Set("limit", "200")
+	json, err := e.request("GetTrades", "GET", "fapi/v1/userTrades", params, true)
+	if err != nil {
+		return nil, err
+	}
+	var reduceOnly map[string]bool
+	trades := []Trade{}
+	for i := 0; i < len(json.MustArray()); i++ {
+		tradeJSON := json.GetIndex(i)
+		if tradeJSON.Get("positionSide").MustString() == "BOTH" {
+			// 单向持仓模式的成交记录没有 reduceOnly, 从订单中获取, 有已实现盈亏的成交也是平仓
+			if reduceOnly == nil {
+				if reduceOnly, err = e.reduceOnlyOrders(stockType); err != nil {
+					return nil, err
+				}
+			}
+			orderID := fmt.Sprint(tradeJSON.Get("orderId").Interface())
+			tradeJSON.Set("reduceOnly", reduceOnly[orderID] || conver.Float64Must(tradeJSON.Get("realizedPnl").Interface()) != 0)
+		}
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(tradeJSON.Get("id").Interface()),
+			OrderID:     fmt.Sprint(tradeJSON.Get("orderId").Interface()),
+			Price:       conver.Float64Must(tradeJSON.Get("price").Interface()),
+			Amount:      conver.Float64Must(tradeJSON.Get("qty").Interface()),
+			Fee:         conver.Float64Must(tradeJSON.Get("commission").Interface()),
+			FeeCurrency: tradeJSON.Get("commissionAsset").MustString(),
+			Time:        conver.Int64Must(tradeJSON.Get("time").Interface()) / 1000,
+			TradeType:   e.tradeTypeOf(tradeJSON),
+			StockType:   stockType,
+		})
+	}
+	return trades, nil
+}
+
+// reduceOnlyOrders get whether the recent orders of stockType are reduce-only keyed by their ids
+func (e *BinanceFuture) reduceOnlyOrders(stockType string) (map[string]bool, error) {
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	params.Set("limit", "500")
+	json, err := e.request("GetTrades", "GET", "fapi/v1/allOrders", params, true)
+	if err != nil {
+		return nil, err
+	}
+	reduceOnly := map[string]bool{}
+	for i := 0; i < len(json.MustArray()); i++ {
+		orderJSON := json.GetIndex(i)
+		reduceOnly[fmt.Sprint(orderJSON.Get("orderId").Interface())] = orderJSON.Get("reduceOnly").MustBool()
+	}
+	return reduceOnly, nil
+}
+
+// GetTrades get the recent fills
+func (e *BinanceFuture) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// Cancel cancel an order
+func (e *BinanceFuture) Cancel(order Order) error {
+	if !e.markets.has(order.StockType) {
+		return newError(ErrInvalidSymbol, "CancelOrder", "unrecognized stockType: ", order.StockType)
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(order.StockType))
+	params.Set("orderId", order.ID)
+	if _, err := e.request("CancelOrder", "DELETE", "fapi/v1/order", params, true); err != nil {
+		return err
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *BinanceFuture) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
+	return true
+}
+
+// Ticker get market ticker & depth
+func (e *BinanceFuture) Ticker(stockType string, size int) (ticker Ticker, err error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
+		return
+	}
+	if size <= 0 {
+		size = 10
+	}
+	// 币安只支持固定的深度档数, 取不少于 size 的最小值
+	limit := e.depthLimits[len(e.depthLimits)-1]
+	for _, l := range e.depthLimits {
+		if l >= size {
+			limit = l
+			break
+		}
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	params.Set("limit", fmt.Sprint(limit))
+	json, err := e.request("GetTicker", "GET", "fapi/v1/depth", params, false)
+	if err != nil {
+		return
+	}
+	for i, side := range []string{"bids", "asks"} {
+		levels := json.Get(side)
+		for j := 0; j < len(levels.MustArray()) && j < size; j++ {
+			level := OrderBook{
+				Price:  conver.Float64Must(levels.GetIndex(j).GetIndex(0).Interface()),
+				Amount: conver.Float64Must(levels.GetIndex(j).GetIndex(1).Interface()),
+			}
+			if i == 0 {
+				ticker.Bids = append(ticker.Bids, level)
+			} else {
+				ticker.Asks = append(ticker.Asks, level)
+			}
+		}
+	}
+	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
+		return
+	}
+	ticker.Buy = ticker.Bids[0].Price
+	ticker.Sell = ticker.Asks[0].Price
+	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
+	return
+}
+
+// GetTicker get market ticker & depth
+func (e *BinanceFuture) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data
+func (e *BinanceFuture) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	recordsNew, err := e.klines(stockType, period, 0, e.store.missing(stockType, period, size))
+	if err != nil {
+		return nil, err
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *BinanceFuture) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	return e.klines(stockType, period, since, size)
+}
+
+// klines get the latest size records, or the records since a unix timestamp if since > 0
+func (e *BinanceFuture) klines(stockType, period string, since int64, size int) ([]Record, error) {
+	if size > 1500 {
+		size = 1500
+	}
+	params := url.Values{}
+	params.Set("symbol", e.markets.symbol(stockType))
+	params.Set("interval", e.recordsPeriodMap[period])
+	params.Set("limit", fmt.Sprint(size))
+	if since > 0 {
+		params.Set("startTime", fmt.Sprint(since*1000))
+	}
+	json, err := e.request("GetRecords", "GET", "fapi/v1/klines", params, false)
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	for i := 0; i < len(json.MustArray()); i++ {
+		kline := json.GetIndex(i)
+		records = append(records, Record{
+			Time:   conver.Int64Must(kline.GetIndex(0).Interface()) / 1000,
+			Open:   conver.Float64Must(kline.GetIndex(1).Interface()),
+			High:   conver.Float64Must(kline.GetIndex(2).Interface()),
+			Low:    conver.Float64Must(kline.GetIndex(3).Interface()),
+			Close:  conver.Float64Must(kline.GetIndex(4).Interface()),
+			Volume: conver.Float64Must(kline.GetIndex(5).Interface()),
+		})
+	}
+	return records, nil
+}
+
+// GetRecords get candlestick data
+func (e *BinanceFuture) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
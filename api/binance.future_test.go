@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/HunterUPP/QuantBot/api/internal/testenv" //测试的配置, 数据库在内存中
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+// binanceFutureResponses the responses of the mock binance futures server keyed by path
+var binanceFutureResponses = map[string]string{
+	"/fapi/v1/exchangeInfo": `{"symbols": [{"symbol": "BTCUSDT", "status": "TRADING", "contractType": "PERPETUAL", "baseAsset": "BTC", "quoteAsset": "USDT",
+		"filters": [{"filterType": "PRICE_FILTER", "tickSize": "0.10"}, {"filterType": "LOT_SIZE", "stepSize": "0.001", "minQty": "0.001"}]}]}`,
+	"/fapi/v1/userTrades": `[
+		{"id": 1, "orderId": 11, "side": "BUY", "positionSide": "BOTH", "price": "30000", "qty": "0.01", "realizedPnl": "0", "commission": "0.12", "commissionAsset": "USDT", "time": 1687255200000},
+		{"id": 2, "orderId": 12, "side": "SELL", "positionSide": "BOTH", "price": "30100", "qty": "0.01", "realizedPnl": "1", "commission": "0.12", "commissionAsset": "USDT", "time": 1687255260000},
+		{"id": 3, "orderId": 13, "side": "SELL", "positionSide": "BOTH", "price": "30200", "qty": "0.01", "realizedPnl": "0", "commission": "0.12", "commissionAsset": "USDT", "time": 1687255320000},
+		{"id": 4, "orderId": 14, "side": "BUY", "positionSide": "BOTH", "price": "30000", "qty": "0.01", "realizedPnl": "2", "commission": "0.12", "commissionAsset": "USDT", "time": 1687255380000},
+		{"id": 5, "orderId": 15, "side": "SELL", "positionSide": "BOTH", "price": "30300", "qty": "0.01", "realizedPnl": "0", "commission": "0.12", "commissionAsset": "USDT", "time": 1687255440000},
+		{"id": 6, "orderId": 16, "side": "SELL", "positionSide": "LONG", "price": "30300", "qty": "0.01", "realizedPnl": "3", "commission": "0.12", "commissionAsset": "USDT", "time": 1687255500000}
+	]`,
+	"/fapi/v1/allOrders": `[
+		{"orderId": 11, "side": "BUY", "positionSide": "BOTH", "reduceOnly": false},
+		{"orderId": 12, "side": "SELL", "positionSide": "BOTH", "reduceOnly": false},
+		{"orderId": 13, "side": "SELL", "positionSide": "BOTH", "reduceOnly": false},
+		{"orderId": 14, "side": "BUY", "positionSide": "BOTH", "reduceOnly": true},
+		{"orderId": 15, "side": "SELL", "positionSide": "BOTH", "reduceOnly": true},
+		{"orderId": 16, "side": "SELL", "positionSide": "LONG", "reduceOnly": false}
+	]`,
+}
+
+func TestBinanceFutureTradesOneWay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := binanceFutureResponses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			body = `{"code": -5000, "msg": "Path not found"}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	e := NewBinanceFuture(Option{Type: constant.BinanceFuture, Name: "binance", AccessKey: "key", SecretKey: "secret", BaseURL: server.URL}).(*BinanceFuture)
+	trades, err := e.Trades("BTC/USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单向持仓模式下平仓的成交来自只减仓的订单或者有已实现盈亏
+	expect := []string{
+		constant.TradeTypeLong,
+		constant.TradeTypeLongClose,
+		constant.TradeTypeShort,
+		constant.TradeTypeShortClose,
+		constant.TradeTypeLongClose,
+		constant.TradeTypeLongClose,
+	}
+	if len(trades) != len(expect) {
+		t.Fatalf("expect %d trades, got %d", len(expect), len(trades))
+	}
+	for i, trade := range trades {
+		if trade.TradeType != expect[i] {
+			t.Errorf("trade %s: expect %s, got %s", trade.ID, expect[i], trade.TradeType)
+		}
+	}
+}
@@ -2,55 +2,78 @@ package api
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
-	"github.com/miaolz123/conver"
 	"github.com/HunterUPP/QuantBot/api/BinanceAPI"
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
+	"golang.org/x/net/websocket"
 )
 
+// binanceErrorKinds the error codes of binance.com
+var binanceErrorKinds = map[string]string{
+	"-1002": ErrAuth,
+	"-1003": ErrRateLimited,
+	"-1015": ErrRateLimited,
+	"-1022": ErrAuth,
+	"-1121": ErrInvalidSymbol,
+	"-2014": ErrAuth,
+	"-2015": ErrAuth,
+}
+
 // Binance the exchange struct of binance.com
 type Binance struct {
+	client           *BinanceAPI.Client
 	stockTypeMap     map[string]string
 	tradeTypeMap     map[string]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	streams          *stream
+	userStream       *userStream
+	store            *recordStore
 	logger           model.Logger
 	option           Option
+	lastError        *Error
+
+	limiter *rateLimiter
+}
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+// binancePeriods the periods of the records the exchange supports
+var binancePeriods = map[string]string{
+	"M":   "1m",
+	"M5":  "5m",
+	"M15": "15m",
+	"M30": "30m",
+	"H":   "1h",
+	"D":   "1d",
+	"W":   "1w",
 }
 
+// binanceOrderTypes the order types the exchange supports
+var binanceOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
 // NewBinance create an exchange struct of Binance.com
 func NewBinance(opt Option) Exchange {
-	BinanceAPI.ACCESS_KEY = opt.AccessKey
-	BinanceAPI.SECRET_KEY = opt.SecretKey
-	return &Binance{
+	e := &Binance{
+		client: BinanceAPI.NewClient(newHTTPClient(opt), opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
-			"BTC/USDT":  "BTC",
-			"ETH/USDT":  "ETH",
-			"EOS/USDT":  "EOS",
-			"ONT/USDT":  "ONT",
-			"QTUM/USDT": "QTUM",
+			"BTC/USDT":  "BTCUSDT",
+			"ETH/USDT":  "ETHUSDT",
+			"EOS/USDT":  "EOSUSDT",
+			"ONT/USDT":  "ONTUSDT",
+			"QTUM/USDT": "QTUMUSDT",
 		},
 		tradeTypeMap: map[string]string{
 			"BUY":  constant.TradeTypeBuy,
 			"SELL": constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "001",
-			"M5":  "005",
-			"M15": "015",
-			"M30": "030",
-			"H":   "060",
-			"D":   "100",
-			"W":   "200",
-		},
+		recordsPeriodMap: binancePeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT":  0.001,
 			"ETH/USDT":  0.001,
@@ -58,13 +81,18 @@ func NewBinance(opt Option) Exchange {
 			"ONT/USDT":  0.001,
 			"QTUM/USDT": 0.001,
 		},
-		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: binanceOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter: limiterOf(opt),
 	}
+	e.client.BaseURL = baseURLOf(opt, e.client.BaseURL)
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	e.streams = newStream(&binanceStream{client: e.client, proxy: opt.ProxyURL}, e.logger)
+	e.userStream = newUserStream(&binanceUserStream{client: e.client, proxy: opt.ProxyURL, markets: e.markets, tradeTypeMap: e.tradeTypeMap}, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -82,191 +110,364 @@ func (e *Binance) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *Binance) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *Binance) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *Binance) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *Binance) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *Binance) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
 }
 
-// GetAccount get the account detail of this exchange
-func (e *Binance) GetAccount() interface{} {
-	accountsMap, err := BinanceAPI.GetAccount()
+// GetMarkets get all the markets of this exchange
+func (e *Binance) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the exchange info of binance
+func (e *Binance) loadMarkets() (markets []Market, err error) {
+	info, err := e.client.GetExchangeInfo()
+	if err != nil {
+		return
+	}
+	for _, s := range info.Symbols {
+		if s.Status != "TRADING" {
+			continue
+		}
+		m := Market{
+			StockType:     s.BaseAsset + "/" + s.QuoteAsset,
+			Symbol:        s.Symbol,
+			BaseCurrency:  s.BaseAsset,
+			QuoteCurrency: s.QuoteAsset,
+		}
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "PRICE_FILTER":
+				m.TickSize = conver.Float64Must(f.TickSize)
+				m.PricePrecision = precisionOf(m.TickSize)
+			case "LOT_SIZE":
+				m.LotSize = conver.Float64Must(f.StepSize)
+				m.AmountPrecision = precisionOf(m.LotSize)
+				m.MinAmount = conver.Float64Must(f.MinQty)
+			case "MIN_NOTIONAL":
+				m.MinNotional = conver.Float64Must(f.MinNotional)
+			}
+		}
+		markets = append(markets, m)
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *Binance) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Binance) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *Binance) Account() (Account, error) {
+	accountsMap, err := e.client.GetAccount()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, wrapError("GetAccount", err)
 	}
-	if _, ok := accountsMap["code"]; ok { //存在错误码
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", accountsMap["msg"].(string))
-		return false
+	if code, ok := accountsMap["code"]; ok { //存在错误码
+		return nil, newCodeError("GetAccount", binanceErrorKinds, code, accountsMap["msg"])
 	}
-	result := make(map[string]float64)
-	balances := accountsMap["balances"].([]interface{})
+	result := Account{}
+	balances, _ := accountsMap["balances"].([]interface{})
 	for _, n := range balances {
-		//log.Println(n)
-		b := n.(map[string]interface{}) //类型转换而已
-		key := strings.ToUpper(b["asset"].(string))
+		b, _ := n.(map[string]interface{}) //类型转换而已
+		key := strings.ToUpper(fmt.Sprint(b["asset"]))
 		result[key] = conver.Float64Must(b["free"])
 		result["Frozen"+key] = conver.Float64Must(b["locked"])
 	}
-	return result
+	return result, nil
 }
 
-// Trade place an order
-func (e *Binance) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// GetAccount get the account detail of this exchange
+func (e *Binance) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency,
+// the post-only orders are LIMIT_MAKER orders
+func (e *Binance) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", "BUY", constant.BUY, stockType, price, amount, opts, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", "SELL", constant.SELL, stockType, price, amount, opts, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
 }
 
-func (e *Binance) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := BinanceAPI.LimitBuy(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType]+"USDT")
+// Trade place an order
+func (e *Binance) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
+		return e.fail("Trade", err)
 	}
-	orderId := conver.Int64Must(result["orderId"])
-	if orderId <= 0 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", result["msg"].(string))
-		return false
-	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return fmt.Sprint(orderId)
+	return id
 }
 
-func (e *Binance) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := BinanceAPI.LimitSell(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType]+"USDT")
+func (e *Binance) place(method, side, logType, stockType string, price, amount float64, opts OrderOptions, msgs ...interface{}) (string, error) {
+	orderType := "MARKET"
+	params := url.Values{}
+	params.Set("quantity", conver.StringMust(amount))
+	switch {
+	case price <= 0:
+		price = 0.0
+		if side == "BUY" {
+			// 市价买单的数量是计价货币的金额
+			params.Del("quantity")
+			params.Set("quoteOrderQty", conver.StringMust(amount))
+		}
+	case opts.PostOnly:
+		orderType = "LIMIT_MAKER"
+		params.Set("price", conver.StringMust(price))
+	default:
+		orderType = "LIMIT"
+		params.Set("price", conver.StringMust(price))
+		params.Set("timeInForce", constant.TimeInForceGTC)
+		if opts.TimeInForce != "" {
+			params.Set("timeInForce", opts.TimeInForce)
+		}
+	}
+	result, err := e.client.PlaceOrder(e.markets.symbol(stockType), side, orderType, params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
+		return "", wrapError(method, err)
 	}
 	orderId := conver.Int64Must(result["orderId"])
 	if orderId <= 0 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", result["msg"].(string))
-		return false
+		return "", newCodeError(method, binanceErrorKinds, result["code"], result["msg"])
 	}
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return fmt.Sprint(orderId)
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return fmt.Sprint(orderId), nil
 }
 
-// GetOrder get details of an order
-func (e *Binance) GetOrder(stockType, id string) interface{} {
+// Order get details of an order
+func (e *Binance) Order(stockType, id string) (Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
 	}
-	result, err := BinanceAPI.GetOneOrder(id, e.stockTypeMap[stockType]+"USDT")
+	result, err := e.client.GetOneOrder(id, e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
+		return Order{}, wrapError("GetOrder", err)
 	}
-	if _, ok := result["code"]; ok { //存在错误码
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", result["msg"].(string))
-		return false
+	if code, ok := result["code"]; ok { //存在错误码
+		return Order{}, newCodeError("GetOrder", binanceErrorKinds, code, result["msg"])
 	}
 	return Order{
 		ID:         id,
-		Price:      conver.Float64Must(result["price"].(string)),
-		Amount:     conver.Float64Must(result["origQty"].(string)),
+		Price:      conver.Float64Must(result["price"]),
+		Amount:     conver.Float64Must(result["origQty"]),
 		DealAmount: conver.Float64Must(result["executedQty"]),
-		TradeType:  e.tradeTypeMap[result["side"].(string)],
+		TradeType:  e.tradeTypeMap[fmt.Sprint(result["side"])],
 		StockType:  stockType,
+	}, nil
+}
+
+// GetOrder get details of an order
+func (e *Binance) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
+	return order
 }
 
-// GetOrders get all unfilled orders
-func (e *Binance) GetOrders(stockType string) interface{} {
+// Orders get all unfilled orders
+func (e *Binance) Orders(stockType string) ([]Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
-	result, err := BinanceAPI.GetUnfinishOrders(e.stockTypeMap[stockType] + "USDT")
+	result, err := e.client.GetUnfinishOrders(e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
+		return nil, wrapError("GetOrders", err)
 	}
 	orders := []Order{}
 	for _, n := range result {
-		ord := n.(map[string]interface{})
+		ord, _ := n.(map[string]interface{})
 		orders = append(orders, Order{
 			ID:         fmt.Sprint(conver.Int64Must(ord["orderId"])),
 			Price:      conver.Float64Must(ord["price"]),
 			Amount:     conver.Float64Must(ord["origQty"]),
 			DealAmount: conver.Float64Must(ord["executedQty"]),
-			TradeType:  e.tradeTypeMap[ord["side"].(string)],
+			TradeType:  e.tradeTypeMap[fmt.Sprint(ord["side"])],
 			StockType:  stockType,
 		})
 	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Binance) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
 	return orders
 }
 
-// GetTrades get all filled orders recently
+// Trades get the recent fills
+func (e *Binance) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	result, err := e.client.GetMyTrades(e.markets.symbol(stockType), 200)
+	if err != nil {
+		return nil, wrapError("GetTrades", err)
+	}
+	trades := []Trade{}
+	for _, n := range result {
+		trd, _ := n.(map[string]interface{})
+		tradeType := constant.TradeTypeSell
+		if isBuyer, _ := trd["isBuyer"].(bool); isBuyer {
+			tradeType = constant.TradeTypeBuy
+		}
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(conver.Int64Must(trd["id"])),
+			OrderID:     fmt.Sprint(conver.Int64Must(trd["orderId"])),
+			Price:       conver.Float64Must(trd["price"]),
+			Amount:      conver.Float64Must(trd["qty"]),
+			Fee:         conver.Float64Must(trd["commission"]),
+			FeeCurrency: fmt.Sprint(trd["commissionAsset"]),
+			Time:        conver.Int64Must(trd["time"]) / 1000,
+			TradeType:   tradeType,
+			StockType:   stockType,
+		})
+	}
+	return trades, nil
+}
+
+// GetTrades get the recent fills
 func (e *Binance) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// MarketTrades get the latest public trades of the market
+func (e *Binance) MarketTrades(stockType string, size int) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetMarketTrades", "unrecognized stockType: ", stockType)
+	}
+	if size <= 0 {
+		size = 500
+	}
+	result, err := e.client.GetTrades(e.markets.symbol(stockType), size)
+	if err != nil {
+		return nil, wrapError("GetMarketTrades", err)
+	}
+	trades := []Trade{}
+	for _, n := range result {
+		trd, _ := n.(map[string]interface{})
+		tradeType := constant.TradeTypeBuy
+		if isBuyerMaker, _ := trd["isBuyerMaker"].(bool); isBuyerMaker {
+			tradeType = constant.TradeTypeSell //买方是挂单方时, 主动成交的是卖方
+		}
+		trades = append(trades, Trade{
+			ID:        fmt.Sprint(conver.Int64Must(trd["id"])),
+			Price:     conver.Float64Must(trd["price"]),
+			Amount:    conver.Float64Must(trd["qty"]),
+			Time:      conver.Int64Must(trd["time"]) / 1000,
+			TradeType: tradeType,
+			StockType: stockType,
+		})
+	}
+	return trades, nil
+}
+
+// Cancel cancel an order
+func (e *Binance) Cancel(order Order) error {
+	ok, err := e.client.CancelOrder(order.ID, e.markets.symbol(order.StockType))
+	if err != nil {
+		return wrapError("CancelOrder", err)
+	}
+	if !ok {
+		return newError(ErrUnknown, "CancelOrder", "order(id = "+order.ID+") can not be canceled")
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
 	return nil
 }
 
 // CancelOrder cancel an order
 func (e *Binance) CancelOrder(order Order) bool {
-	ok, err := BinanceAPI.CancelOrder(order.ID, e.stockTypeMap[order.StockType]+"USDT")
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
 	}
-	if ok {
-		e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
-	}
-	return ok
+	return true
 }
 
-// getTicker get market ticker & depth
-func (e *Binance) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *Binance) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	result, err := BinanceAPI.GetDepth(10, e.stockTypeMap[stockType]+"USDT")
+	if size <= 0 {
+		size = 10
+	}
+	if t, ok := e.streams.ticker(stockType, size); ok {
+		return t, nil
+	}
+	result, err := e.client.GetDepth(size, e.markets.symbol(stockType))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
-	if _, ok := result["code"]; ok { //存在错误码
-		err = fmt.Errorf("GetTicker() error, %+v", result["msg"].(string))
+	if code, ok := result["code"]; ok { //存在错误码
+		err = newCodeError("GetTicker", binanceErrorKinds, code, result["msg"])
 		return
 	}
-	bids := result["bids"].([]interface{})
-	asks := result["asks"].([]interface{})
+	bids, _ := result["bids"].([]interface{})
+	asks, _ := result["asks"].([]interface{})
 	for _, bid := range bids {
 		_bid := bid.([]interface{})
 		ticker.Bids = append(ticker.Bids, OrderBook{
@@ -282,7 +483,7 @@ func (e *Binance) getTicker(stockType string, sizes ...interface{}) (ticker Tick
 		})
 	}
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
 	ticker.Buy = ticker.Bids[0].Price
@@ -293,15 +494,261 @@ func (e *Binance) getTicker(stockType string, sizes ...interface{}) (ticker Tick
 
 // GetTicker get market ticker & depth
 func (e *Binance) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
+// Records get candlestick data
+func (e *Binance) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	result, err := e.client.GetKlines(e.markets.symbol(stockType), e.recordsPeriodMap[period], e.store.missing(stockType, period, size))
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	return e.store.merge(stockType, period, parseKlines(result), size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *Binance) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	result, err := e.client.GetKlinesSince(e.markets.symbol(stockType), e.recordsPeriodMap[period], since*1000, size)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	return parseKlines(result), nil
+}
+
+// parseKlines convert the klines of binance to records
+func parseKlines(result []interface{}) []Record {
+	records := []Record{}
+	for _, v := range result {
+		kline, ok := v.([]interface{})
+		if !ok || len(kline) < 6 {
+			continue
+		}
+		records = append(records, Record{
+			Time:   conver.Int64Must(kline[0]) / 1000,
+			Open:   conver.Float64Must(kline[1]),
+			High:   conver.Float64Must(kline[2]),
+			Low:    conver.Float64Must(kline[3]),
+			Close:  conver.Float64Must(kline[4]),
+			Volume: conver.Float64Must(kline[5]),
+		})
+	}
+	return records
+}
+
 // GetRecords get candlestick data
 func (e *Binance) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
+
+// Stream subscribe the depth and trade channels of stockType by websocket, GetTicker reads the local order book after it
+func (e *Binance) Stream(stockType string) error {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return newError(ErrInvalidSymbol, "Subscribe", "unrecognized stockType: ", stockType)
+	}
+	e.streams.subscribe(stockType, e.markets.symbol(stockType))
+	return nil
+}
+
+// Subscribe subscribe the market data of stockType by websocket
+func (e *Binance) Subscribe(stockType string) bool {
+	if err := e.Stream(stockType); err != nil {
+		return e.fail("Subscribe", err)
+	}
+	return true
+}
+
+// CloseStreams close all the websocket streams
+func (e *Binance) CloseStreams() {
+	e.streams.close()
+	e.userStream.close()
+}
+
+// Listen start the user data stream, the order and balance changes are kept in the event queue
+func (e *Binance) Listen() error {
+	e.userStream.listen()
+	return nil
+}
+
+// Events take all the events out of the event queue, the user data stream is started at the first call
+func (e *Binance) Events() ([]Event, error) {
+	if err := e.Listen(); err != nil {
+		return nil, err
+	}
+	return e.userStream.drain(), nil
+}
+
+// GetEvents get the order and balance changes pushed since the last call
+func (e *Binance) GetEvents() interface{} {
+	if e.userStream.callback {
+		return e.fail("GetEvents", errCallbacks)
+	}
+	events, err := e.Events()
+	if err != nil {
+		return e.fail("GetEvents", err)
+	}
+	return events
+}
+
+// UseCallbacks pass the events to onOrder and onBalance of the script only, GetEvents fails after it
+func (e *Binance) UseCallbacks() {
+	e.userStream.callback = true
+}
+
+// binanceStream the websocket stream of binance.com, the diff depth events are applied to a rest snapshot
+type binanceStream struct {
+	client *BinanceAPI.Client
+	proxy  string //代理地址
+}
+
+func (p *binanceStream) dial(symbol string) (*websocket.Conn, error) {
+	symbol = strings.ToLower(symbol)
+	return dialStream(p.proxy, "wss://stream.binance.com:9443/stream?streams="+symbol+"@depth/"+symbol+"@trade")
+}
+
+func (p *binanceStream) handle(conn *websocket.Conn, message []byte, book *orderBook) error {
+	json, err := simplejson.NewJson(message)
+	if err != nil {
+		return err
+	}
+	data := json.Get("data")
+	switch data.Get("e").MustString() {
+	case "trade":
+		book.trade(conver.Float64Must(data.Get("p").Interface()))
+	case "depthUpdate":
+		seq, ready := book.sequence()
+		if !ready {
+			snapshot, err := p.client.GetDepth(100, data.Get("s").MustString())
+			if err != nil {
+				return err
+			}
+			bids, _ := snapshot["bids"].([]interface{})
+			asks, _ := snapshot["asks"].([]interface{})
+			seq = conver.Int64Must(snapshot["lastUpdateId"])
+			book.snapshot(bids, asks, seq)
+		}
+		first, final := data.Get("U").MustInt64(), data.Get("u").MustInt64()
+		if final <= seq { //快照之前的事件
+			return nil
+		}
+		if first > seq+1 { //序号不连续, 重新同步
+			return errResync
+		}
+		book.update(data.Get("b").MustArray(), data.Get("a").MustArray(), final)
+	}
 	return nil
 }
+
+// binanceOrderStatusMap the order status of binance.com
+var binanceOrderStatusMap = map[string]string{
+	"NEW":              constant.OrderStatusNew,
+	"PARTIALLY_FILLED": constant.OrderStatusPartial,
+	"FILLED":           constant.OrderStatusFilled,
+	"CANCELED":         constant.OrderStatusCanceled,
+	"PENDING_CANCEL":   constant.OrderStatusCanceled,
+	"REJECTED":         constant.OrderStatusCanceled,
+	"EXPIRED":          constant.OrderStatusCanceled,
+}
+
+// binanceUserStream the user data stream of binance.com, it is authorized by a listenKey
+type binanceUserStream struct {
+	client       *BinanceAPI.Client
+	markets      *marketCache
+	tradeTypeMap map[string]string
+	proxy        string        //代理地址
+	stop         chan struct{} //停止上一个连接的 listenKey 续期
+}
+
+func (p *binanceUserStream) dial(done chan struct{}) (*websocket.Conn, error) {
+	listenKey, err := p.client.StartUserDataStream()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialStream(p.proxy, "wss://stream.binance.com:9443/ws/"+listenKey)
+	if err != nil {
+		return nil, err
+	}
+	if p.stop != nil {
+		close(p.stop)
+	}
+	p.stop = make(chan struct{})
+	go func(stop chan struct{}) { //listenKey 60分钟没有续期就会过期
+		for {
+			select {
+			case <-done:
+				return
+			case <-stop:
+				return
+			case <-time.After(30 * time.Minute):
+				if err := p.client.KeepAliveUserDataStream(listenKey); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}(p.stop)
+	return conn, nil
+}
+
+func (p *binanceUserStream) handle(conn *websocket.Conn, message []byte) ([]Event, error) {
+	json, err := simplejson.NewJson(message)
+	if err != nil {
+		return nil, err
+	}
+	switch json.Get("e").MustString() {
+	case "executionReport":
+		return []Event{{
+			Type:   constant.EventOrder,
+			Time:   json.Get("E").MustInt64() / 1000,
+			Status: binanceOrderStatusMap[json.Get("X").MustString()],
+			Order: Order{
+				ID:         fmt.Sprint(json.Get("i").Interface()),
+				Price:      conver.Float64Must(json.Get("p").Interface()),
+				Amount:     conver.Float64Must(json.Get("q").Interface()),
+				DealAmount: conver.Float64Must(json.Get("z").Interface()),
+				TradeType:  p.tradeTypeMap[json.Get("S").MustString()],
+				StockType:  p.markets.stockTypeOf(json.Get("s").MustString()),
+			},
+		}}, nil
+	case "outboundAccountInfo", "outboundAccountPosition":
+		account := Account{}
+		balances := json.Get("B")
+		for i := 0; i < len(balances.MustArray()); i++ {
+			balance := balances.GetIndex(i)
+			currency := strings.ToUpper(balance.Get("a").MustString())
+			account[currency] = conver.Float64Must(balance.Get("f").Interface())
+			account["Frozen"+currency] = conver.Float64Must(balance.Get("l").Interface())
+		}
+		return []Event{{
+			Type:    constant.EventBalance,
+			Time:    json.Get("E").MustInt64() / 1000,
+			Account: account,
+		}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"sort"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+// Capabilities struct, what an exchange supports, the scripts and the UI check it before using a feature
+type Capabilities struct {
+	Type         string   //交易所类型
+	Methods      []string //支持的方法
+	Periods      []string //交易所原生支持的K线周期, 其它周期由这些周期在本地合并得到
+	OrderTypes   []string //支持的订单类型, LIMIT: 限价单, MARKET: 市价单(价格 <= 0), POST_ONLY、IOC 和 FOK: 可以在 Trade 的订单选项中使用
+	Futures      bool     //是否支持合约交易, 可以使用 GetPositions 和 LONG、SHORT 等交易类型
+	Streaming    bool     //是否可以用 Subscribe 订阅 websocket 行情
+	Events       bool     //是否可以用 GetEvents 获取订单和资金的变化
+	MarketTrades bool     //是否可以获取市场的公开成交
+	Backfill     bool     //是否可以从任意时间开始下载历史K线
+}
+
+// exchangeMethods the methods of Exchange available to the scripts
+var exchangeMethods = []string{
+	"Log", "GetType", "GetName", "SetLimit", "AutoSleep", "GetRateLimit", "GetMinAmount", "GetMarkets", "GetAccount",
+	"Trade", "GetOrder", "GetOrders", "GetTrades", "CancelOrder", "GetTicker", "GetRecords", "GetLastError", "Capabilities",
+}
+
+// clocker is implemented by the exchanges which can tell the time, like backtest
+type clocker interface {
+	GetTime() int64
+}
+
+// fundingRater is implemented by the perpetual futures exchanges, like binance.future
+type fundingRater interface {
+	GetFundingRate(stockType string) interface{}
+}
+
+// TypeCapabilities get what an exchange type supports from the static data of its adapter without creating an exchange,
+// false if the type is unrecognized, the periods of backtest are all the periods it can be set to
+func TypeCapabilities(exchangeType string) (Capabilities, bool) {
+	switch exchangeType {
+	case constant.Zb:
+		return newCapabilities(exchangeType, (*Zb)(nil), zbPeriods, zbOrderTypes), true
+	case constant.Okex:
+		return newCapabilities(exchangeType, (*OKEX)(nil), okexPeriods, okexOrderTypes), true
+	case constant.Huobi:
+		return newCapabilities(exchangeType, (*Huobi)(nil), huobiPeriods, huobiOrderTypes), true
+	case constant.Binance:
+		return newCapabilities(exchangeType, (*Binance)(nil), binancePeriods, binanceOrderTypes), true
+	case constant.GateIo:
+		return newCapabilities(exchangeType, (*GateIo)(nil), gateIoPeriods, gateIoOrderTypes), true
+	case constant.Bibox:
+		return newCapabilities(exchangeType, (*BIBOX)(nil), biboxPeriods, biboxOrderTypes), true
+	case constant.Poloniex:
+		return newCapabilities(exchangeType, (*Poloniex)(nil), poloniexPeriods, poloniexOrderTypes), true
+	case constant.OkexFuture:
+		return newCapabilities(exchangeType, (*OkexFuture)(nil), okexFuturePeriods, okexFutureOrderTypes), true
+	case constant.BinanceFuture:
+		return newCapabilities(exchangeType, (*BinanceFuture)(nil), binanceFuturePeriods, binanceFutureOrderTypes), true
+	case constant.HuobiFuture:
+		return newCapabilities(exchangeType, (*HuobiFuture)(nil), huobiFuturePeriods, huobiFutureOrderTypes), true
+	case constant.Kraken:
+		return newCapabilities(exchangeType, (*Kraken)(nil), krakenPeriods, krakenOrderTypes), true
+	case constant.Coinbase:
+		return newCapabilities(exchangeType, (*Coinbase)(nil), coinbasePeriods, coinbaseOrderTypes), true
+	case constant.BigOne:
+		return newCapabilities(exchangeType, (*BigOne)(nil), bigOnePeriods, bigOneOrderTypes, "GetOrder"), true
+	case constant.Backtest:
+		periods := map[string]string{}
+		for period := range periodSeconds {
+			periods[period] = ""
+		}
+		return newCapabilities(exchangeType, (*Backtest)(nil), periods, simOrderTypes), true
+	}
+	return Capabilities{}, false
+}
+
+// newCapabilities describe an exchange by the optional interfaces it implements, the unsupported methods are left out,
+// e can be a nil pointer of the adapter because only its type is checked
+func newCapabilities(exchangeType string, e interface{}, periods map[string]string, orderTypes []string, unsupported ...string) Capabilities {
+	c := Capabilities{
+		Type:       exchangeType,
+		Methods:    []string{},
+		Periods:    []string{},
+		OrderTypes: orderTypes,
+	}
+	skip := map[string]bool{}
+	for _, method := range unsupported {
+		skip[method] = true
+	}
+	for _, method := range exchangeMethods {
+		if !skip[method] {
+			c.Methods = append(c.Methods, method)
+		}
+	}
+	if _, ok := e.(Streamer); ok {
+		c.Streaming = true
+		c.Methods = append(c.Methods, "Subscribe")
+	}
+	if _, ok := e.(EventSource); ok {
+		c.Events = true
+		c.Methods = append(c.Methods, "GetEvents")
+	}
+	if _, ok := e.(FuturesExchange); ok {
+		c.Futures = true
+		c.Methods = append(c.Methods, "SetLeverage", "SetMarginMode", "GetPositions", "ClosePosition")
+	}
+	if _, ok := e.(fundingRater); ok {
+		c.Methods = append(c.Methods, "GetFundingRate")
+	}
+	if _, ok := e.(clocker); ok {
+		c.Methods = append(c.Methods, "GetTime")
+	}
+	_, c.MarketTrades = e.(TradeFeed)
+	_, c.Backfill = e.(RecordsPager)
+	for period := range periods {
+		c.Periods = append(c.Periods, period)
+	}
+	sort.Slice(c.Periods, func(i, j int) bool {
+		return periodLength(c.Periods[i]) < periodLength(c.Periods[j])
+	})
+	return c
+}
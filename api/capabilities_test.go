@@ -0,0 +1,44 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+func TestTypeCapabilities(t *testing.T) {
+	makers := map[string]func(Option) Exchange{
+		constant.Zb:            NewZb,
+		constant.Okex:          NewOKEX,
+		constant.Huobi:         NewHuobi,
+		constant.Binance:       NewBinance,
+		constant.GateIo:        NewGateIo,
+		constant.Bibox:         NewBibox,
+		constant.Poloniex:      NewPoloniex,
+		constant.OkexFuture:    NewOkexFuture,
+		constant.BinanceFuture: NewBinanceFuture,
+		constant.HuobiFuture:   NewHuobiFuture,
+		constant.Kraken:        NewKraken,
+		constant.Coinbase:      NewCoinbase,
+		constant.BigOne:        NewBigOne,
+	}
+	for _, exchangeType := range constant.ExchangeTypes {
+		c, ok := TypeCapabilities(exchangeType)
+		if !ok {
+			t.Errorf("no capabilities of %s", exchangeType)
+			continue
+		}
+		// 静态的功能和交易所实例的一致, 回测的K线周期由设置决定
+		maker, ok := makers[exchangeType]
+		if !ok {
+			continue
+		}
+		if expect := maker(Option{Type: exchangeType, Name: exchangeType}).Capabilities(); !reflect.DeepEqual(c, expect) {
+			t.Errorf("%s: expect %+v, got %+v", exchangeType, expect, c)
+		}
+	}
+	if _, ok := TypeCapabilities("unknown"); ok {
+		t.Error("expect no capabilities of an unknown type")
+	}
+}
@@ -0,0 +1,544 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/api/CoinbaseAPI"
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/miaolz123/conver"
+)
+
+// coinbaseErrorKinds the http status codes of coinbase, the other errors are guessed by the message
+var coinbaseErrorKinds = map[string]string{
+	"401": ErrAuth,
+	"403": ErrAuth,
+	"429": ErrRateLimited,
+}
+
+// coinbaseFillsLimit the max fills got by GetTrades, they are paged by 100
+var coinbaseFillsLimit = 300
+
+// Coinbase the exchange struct of Coinbase Exchange, the symbols are the product ids like BTC-USD
+type Coinbase struct {
+	client           *CoinbaseAPI.Client
+	stockTypeMap     map[string]string
+	tradeTypeMap     map[string]string
+	recordsPeriodMap map[string]string
+	minAmountMap     map[string]float64
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
+	logger           model.Logger
+	option           Option
+	lastError        *Error
+
+	limiter *rateLimiter
+}
+
+// coinbasePeriods the periods of the records the exchange supports
+var coinbasePeriods = map[string]string{
+	"M":   "60",
+	"M5":  "300",
+	"M15": "900",
+	"H":   "3600",
+	"H6":  "21600",
+	"D":   "86400",
+}
+
+// coinbaseOrderTypes the order types the exchange supports
+var coinbaseOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
+// NewCoinbase create an exchange struct of Coinbase Exchange, the api key needs its passphrase
+func NewCoinbase(opt Option) Exchange {
+	e := &Coinbase{
+		client: CoinbaseAPI.NewClient(newHTTPClient(opt), opt.AccessKey, opt.SecretKey, opt.Passphrase),
+		stockTypeMap: map[string]string{
+			"BTC/USD":  "BTC-USD",
+			"BTC/EUR":  "BTC-EUR",
+			"ETH/USD":  "ETH-USD",
+			"ETH/EUR":  "ETH-EUR",
+			"ETH/BTC":  "ETH-BTC",
+			"LTC/USD":  "LTC-USD",
+			"USDT/USD": "USDT-USD",
+		},
+		tradeTypeMap: map[string]string{
+			"buy":  constant.TradeTypeBuy,
+			"sell": constant.TradeTypeSell,
+		},
+		recordsPeriodMap: coinbasePeriods,
+		minAmountMap: map[string]float64{
+			"BTC/USD":  0.00001,
+			"BTC/EUR":  0.00001,
+			"ETH/USD":  0.0001,
+			"ETH/EUR":  0.0001,
+			"ETH/BTC":  0.0001,
+			"LTC/USD":  0.001,
+			"USDT/USD": 1.0,
+		},
+		orderTypes: coinbaseOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
+
+		limiter: limiterOf(opt),
+	}
+	e.client.BaseURL = baseURLOf(opt, e.client.BaseURL)
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
+}
+
+// Log print something to console
+func (e *Coinbase) Log(msgs ...interface{}) {
+	e.logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
+}
+
+// GetType get the type of this exchange
+func (e *Coinbase) GetType() string {
+	return e.option.Type
+}
+
+// GetName get the name of this exchange
+func (e *Coinbase) GetName() string {
+	return e.option.Name
+}
+
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
+func (e *Coinbase) SetLimit(times interface{}) float64 {
+	return e.limiter.setRate(conver.Float64Must(times))
+}
+
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
+func (e *Coinbase) AutoSleep() {
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *Coinbase) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *Coinbase) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
+}
+
+// GetMinAmount get the min trade amonut of this exchange
+func (e *Coinbase) GetMinAmount(stock string) float64 {
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the markets of this exchange
+func (e *Coinbase) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the products of coinbase, the products which can not be traded are left out
+func (e *Coinbase) loadMarkets() (markets []Market, err error) {
+	products, err := e.client.Products()
+	if err != nil {
+		return nil, e.wrap("GetMarkets", err)
+	}
+	for _, p := range products {
+		if p.TradingDisabled || p.Status != "online" {
+			continue
+		}
+		base := strings.ToUpper(p.BaseCurrency)
+		quote := strings.ToUpper(p.QuoteCurrency)
+		tickSize := conver.Float64Must(p.QuoteIncrement)
+		lotSize := conver.Float64Must(p.BaseIncrement)
+		// base_min_size 已经废弃, 没有时使用最小的数量单位
+		minAmount := conver.Float64Must(p.BaseMinSize)
+		if minAmount <= 0 {
+			minAmount = lotSize
+		}
+		markets = append(markets, Market{
+			StockType:       base + "/" + quote,
+			Symbol:          p.ID,
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  precisionOf(tickSize),
+			AmountPrecision: precisionOf(lotSize),
+			TickSize:        tickSize,
+			LotSize:         lotSize,
+			MinAmount:       minAmount,
+			MinNotional:     conver.Float64Must(p.MinMarketFunds),
+		})
+	}
+	return
+}
+
+// wrap convert the error of the client to the error kinds
+func (e *Coinbase) wrap(method string, err error) error {
+	if apiErr, ok := err.(*CoinbaseAPI.APIError); ok {
+		return newCodeError(method, coinbaseErrorKinds, apiErr.Status, apiErr.Message)
+	}
+	return wrapError(method, err)
+}
+
+// GetLastError get the last error of this exchange
+func (e *Coinbase) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Coinbase) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *Coinbase) Account() (Account, error) {
+	accounts, err := e.client.Accounts()
+	if err != nil {
+		return nil, e.wrap("GetAccount", err)
+	}
+	account := Account{}
+	for _, a := range accounts {
+		currency := strings.ToUpper(a.Currency)
+		account[currency] = conver.Float64Must(a.Available)
+		account["Frozen"+currency] = conver.Float64Must(a.Hold)
+	}
+	return account, nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *Coinbase) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency
+func (e *Coinbase) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	stockType = strings.ToUpper(stockType)
+	tradeType = strings.ToUpper(tradeType)
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	logType := constant.BUY
+	switch tradeType {
+	case constant.TradeTypeBuy:
+	case constant.TradeTypeSell:
+		logType = constant.SELL
+	default:
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	req := CoinbaseAPI.OrderRequest{
+		Type:      "market",
+		Side:      strings.ToLower(tradeType),
+		ProductID: e.markets.symbol(stockType),
+		Size:      conver.StringMust(amount),
+	}
+	if price > 0 {
+		req.Type = "limit"
+		req.Price = conver.StringMust(price)
+		req.PostOnly = opts.PostOnly
+		req.TimeInForce = opts.TimeInForce
+	} else if tradeType == constant.TradeTypeBuy {
+		// 市价买单的数量是计价货币的金额
+		req.Size = ""
+		req.Funds = conver.StringMust(amount)
+		price = 0.0
+	} else {
+		price = 0.0
+	}
+	order, err := e.client.PlaceOrder(req)
+	if err != nil {
+		return "", e.wrap("Trade", err)
+	}
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return order.ID, nil
+}
+
+// Trade place an order
+func (e *Coinbase) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// parseOrder convert an order of coinbase to Order
+func (e *Coinbase) parseOrder(stockType string, o CoinbaseAPI.Order) Order {
+	return Order{
+		ID:         o.ID,
+		Price:      conver.Float64Must(o.Price),
+		Amount:     conver.Float64Must(o.Size),
+		DealAmount: conver.Float64Must(o.FilledSize),
+		Fee:        conver.Float64Must(o.FillFees),
+		TradeType:  e.tradeTypeMap[o.Side],
+		StockType:  stockType,
+	}
+}
+
+// Order get details of an order
+func (e *Coinbase) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
+	}
+	order, err := e.client.GetOrder(id)
+	if err != nil {
+		return Order{}, e.wrap("GetOrder", err)
+	}
+	return e.parseOrder(stockType, order), nil
+}
+
+// GetOrder get details of an order
+func (e *Coinbase) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *Coinbase) Orders(stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
+	}
+	result, err := e.client.OpenOrders(e.markets.symbol(stockType))
+	if err != nil {
+		return nil, e.wrap("GetOrders", err)
+	}
+	orders := []Order{}
+	for _, o := range result {
+		orders = append(orders, e.parseOrder(stockType, o))
+	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Coinbase) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the latest fills in time order, the pages of fills are followed by the cursor until coinbaseFillsLimit fills,
+// the fee is charged in the quote currency
+func (e *Coinbase) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	fills, err := e.client.Fills(e.markets.symbol(stockType), coinbaseFillsLimit)
+	if err != nil {
+		return nil, e.wrap("GetTrades", err)
+	}
+	_, quote, _ := splitStockType(stockType)
+	trades := []Trade{}
+	for _, f := range fills {
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(f.TradeID),
+			OrderID:     f.OrderID,
+			Price:       conver.Float64Must(f.Price),
+			Amount:      conver.Float64Must(f.Size),
+			Fee:         conver.Float64Must(f.Fee),
+			FeeCurrency: quote,
+			Time:        f.CreatedAt.Unix(),
+			TradeType:   e.tradeTypeMap[f.Side],
+			StockType:   stockType,
+		})
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time < trades[j].Time
+	})
+	return trades, nil
+}
+
+// GetTrades get the recent fills
+func (e *Coinbase) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// MarketTrades get the latest public trades of the market, coinbase returns at most 1000 trades,
+// the side of a public trade is the side of the maker so the taker side is the other one
+func (e *Coinbase) MarketTrades(stockType string, size int) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetMarketTrades", "unrecognized stockType: ", stockType)
+	}
+	if size <= 0 || size > 1000 {
+		size = 1000
+	}
+	result, err := e.client.Trades(e.markets.symbol(stockType), size)
+	if err != nil {
+		return nil, e.wrap("GetMarketTrades", err)
+	}
+	trades := []Trade{}
+	for i := len(result) - 1; i >= 0; i-- {
+		t := result[i]
+		tradeType := constant.TradeTypeBuy
+		if t.Side == "buy" {
+			tradeType = constant.TradeTypeSell
+		}
+		trades = append(trades, Trade{
+			ID:        fmt.Sprint(t.TradeID),
+			Price:     conver.Float64Must(t.Price),
+			Amount:    conver.Float64Must(t.Size),
+			Time:      t.Time.Unix(),
+			TradeType: tradeType,
+			StockType: stockType,
+		})
+	}
+	return trades, nil
+}
+
+// Cancel cancel an order
+func (e *Coinbase) Cancel(order Order) error {
+	if err := e.client.CancelOrder(order.ID); err != nil {
+		return e.wrap("CancelOrder", err)
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Coinbase) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
+	return true
+}
+
+// Ticker get market ticker & depth
+func (e *Coinbase) Ticker(stockType string, size int) (ticker Ticker, err error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
+		return
+	}
+	if size <= 0 {
+		size = 20
+	}
+	book, err := e.client.Book(e.markets.symbol(stockType))
+	if err != nil {
+		err = e.wrap("GetTicker", err)
+		return
+	}
+	for i, levels := range [][][]json.Number{book.Bids, book.Asks} {
+		for j := 0; j < len(levels) && j < size; j++ {
+			if len(levels[j]) < 2 {
+				continue
+			}
+			price, _ := levels[j][0].Float64()
+			amount, _ := levels[j][1].Float64()
+			if i == 0 {
+				ticker.Bids = append(ticker.Bids, OrderBook{Price: price, Amount: amount})
+			} else {
+				ticker.Asks = append(ticker.Asks, OrderBook{Price: price, Amount: amount})
+			}
+		}
+	}
+	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
+		return
+	}
+	ticker.Buy = ticker.Bids[0].Price
+	ticker.Sell = ticker.Asks[0].Price
+	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
+	return
+}
+
+// GetTicker get market ticker & depth
+func (e *Coinbase) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data
+func (e *Coinbase) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	fetch := e.store.missing(stockType, period, size)
+	if fetch > 300 {
+		fetch = 300
+	}
+	since := time.Now().Unix() - int64(fetch-1)*periodLength(period)
+	recordsNew, err := e.candles(stockType, period, since-since%periodLength(period), fetch)
+	if err != nil {
+		return nil, err
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *Coinbase) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	return e.candles(stockType, period, since, size)
+}
+
+// candles get at most size records since a unix timestamp, coinbase returns at most 300 candles between start and end
+func (e *Coinbase) candles(stockType, period string, since int64, size int) ([]Record, error) {
+	if size <= 0 || size > 300 {
+		size = 300
+	}
+	length := periodLength(period)
+	candles, err := e.client.Candles(e.markets.symbol(stockType), length, since, since+int64(size-1)*length)
+	if err != nil {
+		return nil, e.wrap("GetRecords", err)
+	}
+	records := []Record{}
+	for _, c := range candles {
+		records = append(records, Record{
+			Time:   c.Time,
+			Open:   c.Open,
+			High:   c.High,
+			Low:    c.Low,
+			Close:  c.Close,
+			Volume: c.Volume,
+		})
+	}
+	return records, nil
+}
+
+// GetRecords get candlestick data
+func (e *Coinbase) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// error kinds
+const (
+	ErrInsufficientBalance = "INSUFFICIENT_BALANCE" //余额不足
+	ErrRateLimited         = "RATE_LIMITED"         //访问频率超过限制
+	ErrInvalidSymbol       = "INVALID_SYMBOL"       //不支持的货币类型
+	ErrNetwork             = "NETWORK"              //网络错误
+	ErrAuth                = "AUTH"                 //密钥或者签名错误
+	ErrNotSupported        = "NOT_SUPPORTED"        //交易所不支持这个功能
+	ErrUnknown             = "UNKNOWN"              //其它错误
+)
+
+// Error is the error returned by the typed methods of an exchange
+type Error struct {
+	Kind    string //错误类型
+	Method  string //出错的方法
+	Message string //错误信息
+}
+
+// Error implement the error interface, like "GetAccount() error, Insufficient funds"
+func (err *Error) Error() string {
+	return fmt.Sprintf("%v() error, %v", err.Method, err.Message)
+}
+
+// newError create an error of the kind
+func newError(kind, method string, msgs ...interface{}) *Error {
+	return &Error{Kind: kind, Method: method, Message: fmt.Sprint(msgs...)}
+}
+
+// wrapError create an error from the error of a request, the kind is guessed by its message
+func wrapError(method string, err error) *Error {
+	switch err := err.(type) {
+	case *Error:
+		return err
+	case net.Error:
+		return newError(ErrNetwork, method, err)
+	}
+	return newError(guessKind(err.Error()), method, err)
+}
+
+// newCodeError create an error from the error code of an exchange, the kinds maps error codes to error kinds
+func newCodeError(method string, kinds map[string]string, code interface{}, msgs ...interface{}) *Error {
+	message := fmt.Sprint(msgs...)
+	if message == "" {
+		message = fmt.Sprint("the error code is ", code)
+	}
+	if kind, ok := kinds[fmt.Sprint(code)]; ok {
+		return newError(kind, method, message)
+	}
+	return newError(guessKind(message), method, message)
+}
+
+// ErrorKind get the kind of an error returned by an exchange
+func ErrorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	return wrapError("", err).Kind
+}
+
+// guessKind guess the error kind by the keywords of the error message
+func guessKind(message string) string {
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "http status: 429"), strings.Contains(message, "http status: 418"),
+		strings.Contains(message, "too many"), strings.Contains(message, "too frequent"),
+		strings.Contains(message, "rate limit"), strings.Contains(message, "频繁"):
+		return ErrRateLimited
+	case strings.Contains(message, "insufficient"), strings.Contains(message, "not enough"),
+		strings.Contains(message, "balance-insufficient"), strings.Contains(message, "余额不足"):
+		return ErrInsufficientBalance
+	case strings.Contains(message, "http status: 401"), strings.Contains(message, "http status: 403"),
+		strings.Contains(message, "signature"), strings.Contains(message, "api key"),
+		strings.Contains(message, "apikey"), strings.Contains(message, "api-key"), strings.Contains(message, "access-key"),
+		strings.Contains(message, "unauthorized"), strings.Contains(message, "permission"),
+		strings.Contains(message, "签名"):
+		return ErrAuth
+	case strings.Contains(message, "unrecognized stocktype"), strings.Contains(message, "invalid symbol"),
+		strings.Contains(message, "symbol-error"), strings.Contains(message, "invalid pair"):
+		return ErrInvalidSymbol
+	case strings.Contains(message, "http error"), strings.Contains(message, "timeout"),
+		strings.Contains(message, "connection refused"), strings.Contains(message, "connection reset"),
+		strings.Contains(message, "no such host"), strings.Contains(message, "eof"):
+		return ErrNetwork
+	}
+	return ErrUnknown
+}
+
+// IsUnsent whether an error happened before the request reached the exchange, so the request can be sent again safely
+func IsUnsent(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, keyword := range []string{"connection refused", "no such host", "dial tcp", "dial udp", "proxyconnect"} {
+		if strings.Contains(message, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"golang.org/x/net/websocket"
+)
+
+// eventsLimit the max events kept in the queue, the oldest events are dropped
+var eventsLimit = 1000
+
+// Event struct, an order or balance change pushed by the user data stream
+type Event struct {
+	Type    string  //事件类型, ORDER: 订单变化, BALANCE: 资金变化
+	Time    int64   //unix时间戳
+	Status  string  //订单状态, NEW/PARTIALLY_FILLED/FILLED/CANCELED, Type 为 ORDER 时有效
+	Order   Order   //订单的最新状态, Type 为 ORDER 时有效
+	Account Account //变化了的资金, 如 {"BTC": 1, "FrozenBTC": 0}, Type 为 BALANCE 时有效
+}
+
+// userProtocol is the exchange-specific part of a user data stream
+type userProtocol interface {
+	dial(done chan struct{}) (*websocket.Conn, error)             //连接并订阅订单和资金频道, done 在关闭推送时关闭
+	handle(conn *websocket.Conn, message []byte) ([]Event, error) //把一条消息转换为事件
+}
+
+// userStream keeps the private websocket connection of an exchange and queues the events pushed by it
+type userStream struct {
+	mutex    sync.Mutex
+	protocol userProtocol
+	events   []Event       //事件队列
+	callback bool          //事件交给策略的回调函数
+	done     chan struct{} //关闭连接时关闭
+	conn     *websocket.Conn
+	logger   model.Logger
+}
+
+func newUserStream(protocol userProtocol, logger model.Logger) *userStream {
+	return &userStream{
+		protocol: protocol,
+		logger:   logger,
+	}
+}
+
+// errCallbacks the error of GetEvents when the events are passed to onOrder and onBalance of the script
+var errCallbacks = newError(ErrNotSupported, "GetEvents", "the events are passed to onOrder and onBalance, GetEvents can not be used with them")
+
+// listen start the user data stream, it does nothing if the stream is running already
+func (s *userStream) listen() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done != nil {
+		return
+	}
+	s.done = make(chan struct{})
+	go s.run(s.done)
+}
+
+// run keep the connection alive and queue the events pushed by it
+func (s *userStream) run(done chan struct{}) {
+	wait := time.Second
+	for !isDone(done) {
+		conn, err := s.protocol.dial(done)
+		if err != nil {
+			s.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetEvents() error, ", err)
+			time.Sleep(wait)
+			if wait < time.Minute {
+				wait *= 2
+			}
+			continue
+		}
+		s.mutex.Lock()
+		if isDone(done) {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		s.conn = conn
+		s.mutex.Unlock()
+		for !isDone(done) {
+			var message []byte
+			if err = websocket.Message.Receive(conn, &message); err != nil {
+				break
+			}
+			var events []Event
+			if events, err = s.protocol.handle(conn, message); err != nil {
+				break
+			}
+			s.push(events...)
+			wait = time.Second
+		}
+		conn.Close()
+		if err != nil && !isDone(done) {
+			s.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetEvents() error, ", err)
+			time.Sleep(wait)
+		}
+	}
+}
+
+// push put the events into the queue
+func (s *userStream) push(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, events...)
+	if len(s.events) > eventsLimit {
+		s.events = s.events[len(s.events)-eventsLimit:]
+	}
+}
+
+// drain take all the events out of the queue
+func (s *userStream) drain() []Event {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	events := s.events
+	s.events = []Event{}
+	if events == nil {
+		events = []Event{}
+	}
+	return events
+}
+
+// close close the connection
+func (s *userStream) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
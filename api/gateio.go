@@ -2,8 +2,8 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
-	"time"
 
 	"github.com/bitly/go-simplejson"
 	"github.com/miaolz123/conver"
@@ -11,31 +11,55 @@ import (
 	"github.com/HunterUPP/QuantBot/model"
 )
 
+// gateioErrorKinds the error codes of gateio.io
+var gateioErrorKinds = map[string]string{
+	"5":  ErrAuth,
+	"6":  ErrAuth,
+	"7":  ErrInvalidSymbol,
+	"21": ErrInsufficientBalance,
+}
+
 // GateIo the exchange struct of gateio.io
 type GateIo struct {
 	stockTypeMap     map[string]string
 	tradeTypeMap     map[string]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
 	host             string
 	logger           model.Logger
 	option           Option
+	lastError        *Error
+
+	limiter    *rateLimiter
+	httpClient *http.Client
+}
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+// gateIoPeriods the periods of the records the exchange supports
+var gateIoPeriods = map[string]string{
+	"M":   "60",
+	"M5":  "300",
+	"M15": "900",
+	"M30": "1800",
+	"H":   "3600",
+	"D":   "86400",
+	"W":   "604800",
 }
 
+// gateIoOrderTypes the order types the exchange supports
+var gateIoOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC}
+
 // NewGateIo create an exchange struct of gateio.io
 func NewGateIo(opt Option) Exchange {
-	return &GateIo{
+	e := &GateIo{
 		stockTypeMap: map[string]string{
-			"BTC/USDT":  "btc",
-			"ETH/USDT":  "eth",
-			"EOS/USDT":  "eos",
-			"ONT/USDT":  "ont",
-			"QTUM/USDT": "qtum",
+			"BTC/USDT":  "btc_usdt",
+			"ETH/USDT":  "eth_usdt",
+			"EOS/USDT":  "eos_usdt",
+			"ONT/USDT":  "ont_usdt",
+			"QTUM/USDT": "qtum_usdt",
 		},
 		tradeTypeMap: map[string]string{
 			"buy":         constant.TradeTypeBuy,
@@ -43,15 +67,7 @@ func NewGateIo(opt Option) Exchange {
 			"buy_market":  constant.TradeTypeBuy,
 			"sell_market": constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "1min",
-			"M5":  "5min",
-			"M15": "15min",
-			"M30": "30min",
-			"H":   "1hour",
-			"D":   "1day",
-			"W":   "1week",
-		},
+		recordsPeriodMap: gateIoPeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT":  0.001,
 			"ETH/USDT":  0.001,
@@ -59,14 +75,17 @@ func NewGateIo(opt Option) Exchange {
 			"ONT/USDT":  0.001,
 			"QTUM/USDT": 0.001,
 		},
-		records: make(map[string][]Record),
-		host:    "https://data.gateio.co/api2/1/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: gateIoOrderTypes,
+		host:       baseURLOf(opt, "https://data.gateio.co/api2/1/"),
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter:    limiterOf(opt),
+		httpClient: newHTTPClient(opt),
 	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -84,50 +103,108 @@ func (e *GateIo) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *GateIo) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *GateIo) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *GateIo) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *GateIo) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *GateIo) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the markets of this exchange
+func (e *GateIo) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the market info of gateio.io
+func (e *GateIo) loadMarkets() (markets []Market, err error) {
+	resp, err := get(e.httpClient, e.host+"marketinfo")
+	if err != nil {
+		return
+	}
+	json, err := simplejson.NewJson(resp)
+	if err != nil {
+		return
+	}
+	pairsJSON := json.Get("pairs")
+	for i := 0; i < len(pairsJSON.MustArray()); i++ {
+		for symbol := range pairsJSON.GetIndex(i).MustMap() {
+			base, quote, err := splitStockType(strings.Replace(symbol, "_", "/", -1))
+			if err != nil {
+				continue
+			}
+			marketJSON := pairsJSON.GetIndex(i).Get(symbol)
+			if marketJSON.Get("trade_disabled").MustInt() != 0 {
+				continue
+			}
+			pricePrecision := marketJSON.Get("decimal_places").MustInt()
+			amountPrecision := marketJSON.Get("amount_decimal_places").MustInt(8)
+			markets = append(markets, Market{
+				StockType:       base + "/" + quote,
+				Symbol:          symbol,
+				BaseCurrency:    base,
+				QuoteCurrency:   quote,
+				PricePrecision:  pricePrecision,
+				AmountPrecision: amountPrecision,
+				TickSize:        stepOf(pricePrecision),
+				LotSize:         stepOf(amountPrecision),
+				MinAmount:       marketJSON.Get("min_amount").MustFloat64(),
+			})
+		}
+	}
+	return
 }
 
 func (e *GateIo) getAuthJSON(url string, params []string) (json *simplejson.Json, err error) {
-	e.lastTimes++
-	resp, err := post_gateio(url, params, e.option.AccessKey, signSha512(params, e.option.SecretKey))
+	resp, err := post_gateio(e.httpClient, url, params, e.option.AccessKey, signSha512(params, e.option.SecretKey))
 	if err != nil {
 		return
 	}
 	return simplejson.NewJson(resp)
 }
 
-// GetAccount get the account detail of this exchange
-func (e *GateIo) GetAccount() interface{} {
+// GetLastError get the last error of this exchange
+func (e *GateIo) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *GateIo) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *GateIo) Account() (Account, error) {
 	json, err := e.getAuthJSON(e.host+"private/balances", []string{})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, wrapError("GetAccount", err)
 	}
 	if result := json.Get("result").MustString(); result != "true" {
-		err = fmt.Errorf("the error message => %s", json.Get("message").MustString())
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, newCodeError("GetAccount", gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
 	}
-	return map[string]float64{
+	return Account{
 		"USDT":       conver.Float64Must(json.GetPath("available", "USDT").Interface()),
 		"FrozenUSDT": conver.Float64Must(json.GetPath("locked", "USDT").Interface()),
 		"BTC":        conver.Float64Must(json.GetPath("available", "BTC").Interface()),
@@ -140,89 +217,99 @@ func (e *GateIo) GetAccount() interface{} {
 		"FrozenONT":  conver.Float64Must(json.GetPath("locked", "ONT").Interface()),
 		"QTUM":       conver.Float64Must(json.GetPath("available", "QTUM").Interface()),
 		"FrozenQTUM": conver.Float64Must(json.GetPath("locked", "QTUM").Interface()),
+	}, nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *GateIo) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
 	}
+	return account
 }
 
-// Trade place an order
-func (e *GateIo) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency,
+// gateio has no market orders so it is an IOC limit order at the price of the worst level of the order book it eats
+func (e *GateIo) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", "private/buy", constant.BUY, stockType, price, amount, opts, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", "private/sell", constant.SELL, stockType, price, amount, opts, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
 }
 
-func (e *GateIo) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	params := []string{
-		"currencyPair=" + e.stockTypeMap[stockType] + "_usdt",
-	}
-	rateParam := fmt.Sprintf("rate=%f", price)
-	amountParam := fmt.Sprintf("amount=%f", amount)
-	params = append(params, rateParam, amountParam)
-	json, err := e.getAuthJSON(e.host+"private/buy", params)
+// Trade place an order
+func (e *GateIo) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
-	}
-	if result := json.Get("result").MustString(); result != "true" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, the error message => ", json.Get("message").MustString())
-		return false
+		return e.fail("Trade", err)
 	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return fmt.Sprint(json.Get("orderNumber").Interface())
+	return id
 }
 
-func (e *GateIo) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
+func (e *GateIo) place(method, api, logType, stockType string, price, amount float64, opts OrderOptions, msgs ...interface{}) (string, error) {
+	orderPrice, orderAmount := price, amount
+	if price <= 0 {
+		var err error
+		orderPrice, orderAmount, err = marketOrderOf(method, e.Ticker, e.markets, stockType, logType, amount)
+		if err != nil {
+			return "", err
+		}
+		opts.TimeInForce = constant.TimeInForceIOC
+		price = 0.0
+	}
 	params := []string{
-		"currencyPair=" + e.stockTypeMap[stockType] + "_usdt",
+		"currencyPair=" + e.markets.symbol(stockType),
 	}
-	rateParam := fmt.Sprintf("rate=%f", price)
-	amountParam := fmt.Sprintf("amount=%f", amount)
+	rateParam := fmt.Sprintf("rate=%f", orderPrice)
+	amountParam := fmt.Sprintf("amount=%f", orderAmount)
 	params = append(params, rateParam, amountParam)
-	json, err := e.getAuthJSON(e.host+"private/sell", params)
+	// orderType: poc 只做 maker, ioc 立即成交剩余撤销
+	if opts.PostOnly {
+		params = append(params, "orderType=poc")
+	} else if opts.TimeInForce == constant.TimeInForceIOC {
+		params = append(params, "orderType=ioc")
+	}
+	json, err := e.getAuthJSON(e.host+api, params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
+		return "", wrapError(method, err)
 	}
 	if result := json.Get("result").MustString(); result != "true" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, the error message => ", json.Get("message").MustString())
-		return false
+		return "", newCodeError(method, gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
 	}
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return fmt.Sprint(json.Get("orderNumber").Interface())
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return fmt.Sprint(json.Get("orderNumber").Interface()), nil
 }
 
-// GetOrder get details of an order
-func (e *GateIo) GetOrder(stockType, id string) interface{} {
+// Order get details of an order
+func (e *GateIo) Order(stockType, id string) (Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
 	}
 	params := []string{
-		"currencyPair=" + e.stockTypeMap[stockType] + "_usdt",
+		"currencyPair=" + e.markets.symbol(stockType),
 		"orderNumber=" + id,
 	}
 	json, err := e.getAuthJSON(e.host+"private/getOrder", params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
+		return Order{}, wrapError("GetOrder", err)
 	}
 	if result := json.Get("result").MustString(); result != "true" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, the error message => ", json.Get("message").MustString())
-		return false
+		return Order{}, newCodeError("GetOrder", gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
 	}
 	orderJSON := json.Get("order")
 	return Order{
@@ -232,30 +319,39 @@ func (e *GateIo) GetOrder(stockType, id string) interface{} {
 		DealAmount: conver.Float64Must(orderJSON.Get("filledAmount").Interface()),
 		TradeType:  e.tradeTypeMap[orderJSON.Get("type").MustString()],
 		StockType:  stockType,
+	}, nil
+}
+
+// GetOrder get details of an order
+func (e *GateIo) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
+	return order
 }
 
-// GetOrders get all unfilled orders
-func (e *GateIo) GetOrders(stockType string) interface{} {
+// Orders get all unfilled orders
+func (e *GateIo) Orders(stockType string) ([]Order, error) {
 	stockType = strings.ToUpper(stockType)
-	orders := []Order{}
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
 	json, err := e.getAuthJSON(e.host+"private/openOrders", []string{})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
+		return nil, wrapError("GetOrders", err)
 	}
 	if result := json.Get("result").MustString(); result != "true" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, the error message => ", json.Get("message").MustString())
-		return false
+		return nil, newCodeError("GetOrders", gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
 	}
+	orders := []Order{}
 	ordersJSON := json.Get("orders")
 	count := len(ordersJSON.MustArray())
 	for i := 0; i < count; i++ {
 		orderJSON := ordersJSON.GetIndex(i)
+		if pair := orderJSON.Get("currencyPair").MustString(); pair != "" && pair != e.markets.symbol(stockType) {
+			continue
+		}
 		orders = append(orders, Order{
 			ID:         fmt.Sprint(orderJSON.Get("orderNumber").Interface()),
 			Price:      conver.Float64Must(orderJSON.Get("initialRate").Interface()),
@@ -265,52 +361,109 @@ func (e *GateIo) GetOrders(stockType string) interface{} {
 			StockType:  stockType,
 		})
 	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *GateIo) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
 	return orders
 }
 
-// GetTrades get all filled orders recently
+// Trades get the recent fills
+func (e *GateIo) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	json, err := e.getAuthJSON(e.host+"private/tradeHistory", []string{"currencyPair=" + e.markets.symbol(stockType)})
+	if err != nil {
+		return nil, wrapError("GetTrades", err)
+	}
+	if result := json.Get("result").MustString(); result != "true" {
+		return nil, newCodeError("GetTrades", gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
+	}
+	trades := []Trade{}
+	tradesJSON := json.Get("trades")
+	count := len(tradesJSON.MustArray())
+	for i := 0; i < count; i++ {
+		tradeJSON := tradesJSON.GetIndex(i)
+		tradeType := e.tradeTypeMap[tradeJSON.Get("type").MustString()]
+		feeCurrency := strings.ToUpper(tradeJSON.Get("fee_coin").MustString())
+		if feeCurrency == "" {
+			feeCurrency = feeCurrencyOf(stockType, tradeType)
+		}
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(tradeJSON.Get("tradeID").Interface()),
+			OrderID:     fmt.Sprint(tradeJSON.Get("orderNumber").Interface()),
+			Price:       conver.Float64Must(tradeJSON.Get("rate").Interface()),
+			Amount:      conver.Float64Must(tradeJSON.Get("amount").Interface()),
+			Fee:         conver.Float64Must(tradeJSON.Get("fee").Interface()),
+			FeeCurrency: feeCurrency,
+			Time:        conver.Int64Must(tradeJSON.Get("time_unix").Interface()),
+			TradeType:   tradeType,
+			StockType:   stockType,
+		})
+	}
+	return trades, nil
+}
+
+// GetTrades get the recent fills
 func (e *GateIo) GetTrades(stockType string) interface{} {
-	return nil
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
 }
 
-// CancelOrder cancel an order
-func (e *GateIo) CancelOrder(order Order) bool {
+// Cancel cancel an order
+func (e *GateIo) Cancel(order Order) error {
 	params := []string{
-		"currencyPair=" + e.stockTypeMap[order.StockType] + "_usdt",
+		"currencyPair=" + e.markets.symbol(order.StockType),
 		"orderNumber=" + order.ID,
 	}
 	json, err := e.getAuthJSON(e.host+"private/cancelOrder", params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+		return wrapError("CancelOrder", err)
 	}
 	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, the error message => ", json.Get("message").MustString())
-		return false
+		return newCodeError("CancelOrder", gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
 	}
 	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *GateIo) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *GateIo) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *GateIo) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	resp, err := get(fmt.Sprintf("http://data.gateio.io/api2/1/orderBook/%v_usdt", e.stockTypeMap[stockType]))
+	resp, err := get(e.httpClient, fmt.Sprintf("%vorderBook/%v", e.host, e.markets.symbol(stockType)))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	json, err := simplejson.NewJson(resp)
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	depthsJSON := json.Get("bids")
-	for i := 0; i < len(depthsJSON.MustArray()); i++ {
+	for i := 0; i < len(depthsJSON.MustArray()) && (size <= 0 || i < size); i++ {
 		depthJSON := depthsJSON.GetIndex(i)
 		ticker.Bids = append(ticker.Bids, OrderBook{
 			Price:  depthJSON.GetIndex(0).MustFloat64(),
@@ -318,7 +471,7 @@ func (e *GateIo) getTicker(stockType string, sizes ...interface{}) (ticker Ticke
 		})
 	}
 	depthsJSON = json.Get("asks")
-	for i := len(depthsJSON.MustArray()); i > 0; i-- {
+	for i := len(depthsJSON.MustArray()); i > 0 && (size <= 0 || len(ticker.Asks) < size); i-- {
 		depthJSON := depthsJSON.GetIndex(i - 1)
 		ticker.Asks = append(ticker.Asks, OrderBook{
 			Price:  depthJSON.GetIndex(0).MustFloat64(),
@@ -326,7 +479,7 @@ func (e *GateIo) getTicker(stockType string, sizes ...interface{}) (ticker Ticke
 		})
 	}
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
 	ticker.Buy = ticker.Bids[0].Price
@@ -337,15 +490,59 @@ func (e *GateIo) getTicker(stockType string, sizes ...interface{}) (ticker Ticke
 
 // GetTicker get market ticker & depth
 func (e *GateIo) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
+// Records get candlestick data
+func (e *GateIo) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	interval := conver.Int64Must(e.recordsPeriodMap[period])
+	rangeHour := interval*int64(e.store.missing(stockType, period, size))/3600 + 1
+	resp, err := get(e.httpClient, fmt.Sprintf("%vcandlestick2/%v?group_sec=%v&range_hour=%v", e.host, e.markets.symbol(stockType), interval, rangeHour))
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	json, err := simplejson.NewJson(resp)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	if result := json.Get("result").MustString(); result != "true" {
+		return nil, newCodeError("GetRecords", gateioErrorKinds, json.Get("code").Interface(), json.Get("message").MustString())
+	}
+	recordsNew := []Record{}
+	recordsJSON := json.Get("data")
+	for i := 0; i < len(recordsJSON.MustArray()); i++ {
+		recordJSON := recordsJSON.GetIndex(i)
+		recordsNew = append(recordsNew, Record{
+			Time:   conver.Int64Must(recordJSON.GetIndex(0).Interface()) / 1000,
+			Open:   conver.Float64Must(recordJSON.GetIndex(5).Interface()),
+			High:   conver.Float64Must(recordJSON.GetIndex(3).Interface()),
+			Low:    conver.Float64Must(recordJSON.GetIndex(4).Interface()),
+			Close:  conver.Float64Must(recordJSON.GetIndex(2).Interface()),
+			Volume: conver.Float64Must(recordJSON.GetIndex(1).Interface()),
+		})
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
 // GetRecords get candlestick data
 func (e *GateIo) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
-	return nil
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
 }
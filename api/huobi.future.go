@@ -0,0 +1,739 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/services"
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
+)
+
+// huobiFutureErrorKinds the error codes of huobi future (hbdm)
+var huobiFutureErrorKinds = map[string]string{
+	"1013": ErrInvalidSymbol,
+	"1032": ErrRateLimited,
+	"1047": ErrInsufficientBalance,
+	"1048": ErrInsufficientBalance,
+	"403":  ErrAuth,
+}
+
+// HuobiFuture the exchange struct of huobi future (hbdm), the stockTypes are like BTC.WEEK/USD and the amount is in contracts
+type HuobiFuture struct {
+	client           *services.Client
+	stockTypeMap     map[string]string
+	tradeTypeMap     map[string][2]string //交易类型对应的 direction 和 offset
+	tradeTypeLogMap  map[string]string
+	contractTypeMap  map[string][2]string //合约类型对应的 contract_type 和行情的交易对后缀
+	leverageMap      map[string]string
+	recordsPeriodMap map[string]string
+	minAmountMap     map[string]float64
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
+	logger           model.Logger
+	option           Option
+	lastError        *Error
+	leverage         string //下单使用的杠杆倍数, 需要和已有持仓的杠杆倍数一致
+
+	limiter *rateLimiter
+}
+
+// huobiFuturePeriods the periods of the records the exchange supports
+var huobiFuturePeriods = map[string]string{
+	"M":   "1min",
+	"M5":  "5min",
+	"M15": "15min",
+	"M30": "30min",
+	"H":   "60min",
+	"H4":  "4hour",
+	"D":   "1day",
+	"W":   "1week",
+}
+
+// huobiFutureOrderTypes the order types the exchange supports
+var huobiFutureOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
+// NewHuobiFuture create an exchange struct of huobi future (hbdm)
+func NewHuobiFuture(opt Option) Exchange {
+	e := &HuobiFuture{
+		client: services.NewFutureClient(newHTTPClient(opt), opt.AccessKey, opt.SecretKey),
+		stockTypeMap: map[string]string{
+			"BTC.WEEK/USD":   "BTC_CW",
+			"BTC.WEEK2/USD":  "BTC_NW",
+			"BTC.MONTH3/USD": "BTC_CQ",
+			"ETH.WEEK/USD":   "ETH_CW",
+			"ETH.WEEK2/USD":  "ETH_NW",
+			"ETH.MONTH3/USD": "ETH_CQ",
+		},
+		tradeTypeMap: map[string][2]string{
+			constant.TradeTypeLong:       {"buy", "open"},
+			constant.TradeTypeShort:      {"sell", "open"},
+			constant.TradeTypeLongClose:  {"sell", "close"},
+			constant.TradeTypeShortClose: {"buy", "close"},
+		},
+		tradeTypeLogMap: map[string]string{
+			constant.TradeTypeLong:       constant.LONG,
+			constant.TradeTypeShort:      constant.SHORT,
+			constant.TradeTypeLongClose:  constant.LONGCLOSE,
+			constant.TradeTypeShortClose: constant.SHORTCLOSE,
+		},
+		contractTypeMap: map[string][2]string{
+			"WEEK":   {"this_week", "CW"},
+			"WEEK2":  {"next_week", "NW"},
+			"MONTH3": {"quarter", "CQ"},
+			"MONTH6": {"next_quarter", "NQ"},
+		},
+		leverageMap: map[string]string{
+			"1":  "1",
+			"2":  "2",
+			"3":  "3",
+			"5":  "5",
+			"10": "10",
+			"20": "20",
+		},
+		recordsPeriodMap: huobiFuturePeriods,
+		minAmountMap: map[string]float64{
+			"BTC.WEEK/USD":   1.0,
+			"BTC.WEEK2/USD":  1.0,
+			"BTC.MONTH3/USD": 1.0,
+			"ETH.WEEK/USD":   1.0,
+			"ETH.WEEK2/USD":  1.0,
+			"ETH.MONTH3/USD": 1.0,
+		},
+		orderTypes: huobiFutureOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
+
+		leverage: "10",
+
+		limiter: limiterOf(opt),
+	}
+	e.client.MarketURL = baseURLOf(opt, e.client.MarketURL)
+	e.client.TradeURL = baseURLOf(opt, e.client.TradeURL)
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
+}
+
+// Log print something to console
+func (e *HuobiFuture) Log(msgs ...interface{}) {
+	e.logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
+}
+
+// GetType get the type of this exchange
+func (e *HuobiFuture) GetType() string {
+	return e.option.Type
+}
+
+// GetName get the name of this exchange
+func (e *HuobiFuture) GetName() string {
+	return e.option.Name
+}
+
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
+func (e *HuobiFuture) SetLimit(times interface{}) float64 {
+	return e.limiter.setRate(conver.Float64Must(times))
+}
+
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
+func (e *HuobiFuture) AutoSleep() {
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *HuobiFuture) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *HuobiFuture) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
+}
+
+// GetMinAmount get the min trade amonut of this exchange
+func (e *HuobiFuture) GetMinAmount(stock string) float64 {
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the contracts of this exchange, the amount is in contracts
+func (e *HuobiFuture) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// parse parse the response of hbdm and check its status
+func (e *HuobiFuture) parse(method, resp string) (*simplejson.Json, error) {
+	json, err := simplejson.NewJson([]byte(resp))
+	if err != nil {
+		// 请求失败时返回的是错误信息
+		return nil, newError(ErrUnknown, method, resp)
+	}
+	if json.Get("status").MustString() != "ok" {
+		code := json.Get("err_code").Interface()
+		msg := json.Get("err_msg").MustString()
+		if code == nil {
+			code = json.Get("err-code").Interface()
+			msg = json.Get("err-msg").MustString()
+		}
+		return nil, newCodeError(method, huobiFutureErrorKinds, code, msg)
+	}
+	return json, nil
+}
+
+// get send a public request
+func (e *HuobiFuture) get(method, path string, params map[string]string) (*simplejson.Json, error) {
+	return e.parse(method, e.client.FutureGet(path, params))
+}
+
+// post send a signed request
+func (e *HuobiFuture) post(method, path string, params map[string]string) (*simplejson.Json, error) {
+	return e.parse(method, e.client.FuturePost(path, params))
+}
+
+// loadMarkets load the listed contracts of hbdm
+func (e *HuobiFuture) loadMarkets() (markets []Market, err error) {
+	json, err := e.get("GetMarkets", "/api/v1/contract_contract_info", nil)
+	if err != nil {
+		return
+	}
+	contracts := json.Get("data")
+	for i := 0; i < len(contracts.MustArray()); i++ {
+		contractJSON := contracts.GetIndex(i)
+		if contractJSON.Get("contract_status").MustInt() != 1 {
+			continue
+		}
+		base := strings.ToUpper(contractJSON.Get("symbol").MustString())
+		for contractType, c := range e.contractTypeMap {
+			if c[0] != contractJSON.Get("contract_type").MustString() {
+				continue
+			}
+			tickSize := conver.Float64Must(contractJSON.Get("price_tick").Interface())
+			markets = append(markets, Market{
+				StockType:      base + "." + contractType + "/USD",
+				Symbol:         base + "_" + c[1],
+				BaseCurrency:   base,
+				QuoteCurrency:  "USD",
+				PricePrecision: precisionOf(tickSize),
+				TickSize:       tickSize,
+				LotSize:        1.0,
+				MinAmount:      1.0,
+			})
+		}
+	}
+	return
+}
+
+// contractOf get the coin and the contract_type of stockType, like BTC and this_week
+func (e *HuobiFuture) contractOf(stockType string) (string, string) {
+	symbol := e.markets.symbol(stockType)
+	parts := strings.Split(symbol, "_")
+	if len(parts) != 2 {
+		return symbol, ""
+	}
+	for _, c := range e.contractTypeMap {
+		if c[1] == parts[1] {
+			return parts[0], c[0]
+		}
+	}
+	return parts[0], ""
+}
+
+// GetLastError get the last error of this exchange
+func (e *HuobiFuture) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *HuobiFuture) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the margin of every coin, the frozen amount is the margin used by the positions and the orders
+func (e *HuobiFuture) Account() (Account, error) {
+	json, err := e.post("GetAccount", "/api/v1/contract_account_info", map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	account := Account{}
+	data := json.Get("data")
+	for i := 0; i < len(data.MustArray()); i++ {
+		accountJSON := data.GetIndex(i)
+		coin := strings.ToUpper(accountJSON.Get("symbol").MustString())
+		account[coin] = conver.Float64Must(accountJSON.Get("margin_available").Interface())
+		account["Frozen"+coin] = conver.Float64Must(accountJSON.Get("margin_frozen").Interface()) + conver.Float64Must(accountJSON.Get("margin_position").Interface())
+	}
+	return account, nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *HuobiFuture) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// SetLeverage set the leverage of the later orders, hbdm supports 1, 2, 3, 5, 10 and 20
+func (e *HuobiFuture) SetLeverage(leverage interface{}) bool {
+	l := fmt.Sprint(conver.IntMust(leverage))
+	if _, ok := e.leverageMap[l]; !ok {
+		return e.fail("SetLeverage", newError(ErrNotSupported, "SetLeverage", "unrecognized leverage: ", leverage))
+	}
+	e.leverage = l
+	return true
+}
+
+// SetMarginMode set the margin mode, hbdm only supports the crossed margin
+func (e *HuobiFuture) SetMarginMode(mode string) bool {
+	if mode != constant.MarginModeCrossed {
+		return e.fail("SetMarginMode", newError(ErrNotSupported, "SetMarginMode", "unrecognized margin mode: ", mode))
+	}
+	return true
+}
+
+// Positions get the positions of stockType, the long and short positions are separate
+func (e *HuobiFuture) Positions(stockType string) ([]Position, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetPositions", "unrecognized stockType: ", stockType)
+	}
+	coin, contractType := e.contractOf(stockType)
+	json, err := e.post("GetPositions", "/api/v1/contract_position_info", map[string]string{"symbol": coin})
+	if err != nil {
+		return nil, err
+	}
+	// 全仓模式的强平价格是整个账户的
+	accountJSON, err := e.post("GetPositions", "/api/v1/contract_account_info", map[string]string{"symbol": coin})
+	if err != nil {
+		return nil, err
+	}
+	liquidationPrice := conver.Float64Must(accountJSON.Get("data").GetIndex(0).Get("liquidation_price").Interface())
+	positions := []Position{}
+	data := json.Get("data")
+	for i := 0; i < len(data.MustArray()); i++ {
+		positionJSON := data.GetIndex(i)
+		if positionJSON.Get("contract_type").MustString() != contractType {
+			continue
+		}
+		tradeType := constant.TradeTypeLong
+		if positionJSON.Get("direction").MustString() == "sell" {
+			tradeType = constant.TradeTypeShort
+		}
+		positions = append(positions, Position{
+			Price:            conver.Float64Must(positionJSON.Get("cost_hold").Interface()),
+			Leverage:         conver.IntMust(positionJSON.Get("lever_rate").Interface()),
+			Amount:           conver.Float64Must(positionJSON.Get("volume").Interface()),
+			ConfirmAmount:    conver.Float64Must(positionJSON.Get("available").Interface()),
+			FrozenAmount:     conver.Float64Must(positionJSON.Get("frozen").Interface()),
+			Profit:           conver.Float64Must(positionJSON.Get("profit_unreal").Interface()),
+			Margin:           conver.Float64Must(positionJSON.Get("position_margin").Interface()),
+			MarginMode:       constant.MarginModeCrossed,
+			LiquidationPrice: liquidationPrice,
+			ContractType:     contractTypeOf(stockType),
+			TradeType:        tradeType,
+			StockType:        stockType,
+		})
+	}
+	return positions, nil
+}
+
+// GetPositions get the positions of stockType
+func (e *HuobiFuture) GetPositions(stockType string) interface{} {
+	positions, err := e.Positions(stockType)
+	if err != nil {
+		return e.fail("GetPositions", err)
+	}
+	return positions
+}
+
+// ClosePosition close all the available amount of a position by a market order
+func (e *HuobiFuture) ClosePosition(position Position, msgs ...interface{}) interface{} {
+	tradeType := constant.TradeTypeLongClose
+	if position.TradeType == constant.TradeTypeShort {
+		tradeType = constant.TradeTypeShortClose
+	}
+	amount := position.ConfirmAmount
+	if amount <= 0 {
+		return e.fail("ClosePosition", newError(ErrUnknown, "ClosePosition", "no available amount of the position"))
+	}
+	leverage := e.leverage
+	if position.Leverage > 0 {
+		leverage = fmt.Sprint(position.Leverage)
+	}
+	id, err := e.place(tradeType, position.StockType, 0.0, amount, leverage, msgs...)
+	if err != nil {
+		return e.fail("ClosePosition", err)
+	}
+	return id
+}
+
+// PlaceOrder place an order with the leverage set by SetLeverage, it is a market order if price <= 0 and the amount is in contracts
+func (e *HuobiFuture) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	return e.place(tradeType, stockType, price, amount, e.leverage, msgs...)
+}
+
+// place place an order with a leverage
+func (e *HuobiFuture) place(tradeType string, stockType string, price, amount float64, leverage string, msgs ...interface{}) (string, error) {
+	tradeType = strings.ToUpper(tradeType)
+	stockType = strings.ToUpper(stockType)
+	side, ok := e.tradeTypeMap[tradeType]
+	if !ok {
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
+	}
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	// 数量是合约张数, 不能有小数
+	volume := math.Round(amount)
+	if volume <= 0 || math.Abs(amount-volume) > 1e-9 {
+		return "", newError(ErrUnknown, "Trade", "the amount should be a whole number of contracts: ", amount)
+	}
+	coin, contractType := e.contractOf(stockType)
+	params := map[string]string{
+		"symbol":           coin,
+		"contract_type":    contractType,
+		"volume":           fmt.Sprint(int64(volume)),
+		"direction":        side[0],
+		"offset":           side[1],
+		"lever_rate":       leverage,
+		"order_price_type": huobiFutureOrderType(price, opts),
+	}
+	if price > 0.0 {
+		params["price"] = conver.StringMust(price)
+	} else {
+		price = 0.0
+	}
+	json, err := e.post("Trade", "/api/v1/contract_order", params)
+	if err != nil {
+		return "", err
+	}
+	e.logger.Log(e.tradeTypeLogMap[tradeType], stockType, price, amount, msgs...)
+	return fmt.Sprint(json.GetPath("data", "order_id").Interface()), nil
+}
+
+// huobiFutureOrderType get the order_price_type of an order, the market orders eat at most the best 20 levels
+// and the rest is canceled if it is IOC or FOK, otherwise the rest is left at the price of the 20th level
+func huobiFutureOrderType(price float64, opts OrderOptions) string {
+	orderType := "limit"
+	if price <= 0 {
+		orderType = "optimal_20"
+	}
+	switch {
+	case opts.PostOnly:
+		return "post_only"
+	case opts.TimeInForce == constant.TimeInForceIOC && price <= 0:
+		return orderType + "_ioc"
+	case opts.TimeInForce == constant.TimeInForceFOK && price <= 0:
+		return orderType + "_fok"
+	case opts.TimeInForce == constant.TimeInForceIOC:
+		return "ioc"
+	case opts.TimeInForce == constant.TimeInForceFOK:
+		return "fok"
+	}
+	return orderType
+}
+
+// Trade place an order
+func (e *HuobiFuture) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// tradeTypeOf get the trade type of an order or a fill by its direction and offset
+func (e *HuobiFuture) tradeTypeOf(orderJSON *simplejson.Json) string {
+	direction := orderJSON.Get("direction").MustString()
+	offset := orderJSON.Get("offset").MustString()
+	for tradeType, side := range e.tradeTypeMap {
+		if side[0] == direction && side[1] == offset {
+			return tradeType
+		}
+	}
+	return strings.ToUpper(direction)
+}
+
+// parseOrder convert an order of hbdm to Order
+func (e *HuobiFuture) parseOrder(stockType string, orderJSON *simplejson.Json) Order {
+	return Order{
+		ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
+		Price:      conver.Float64Must(orderJSON.Get("price").Interface()),
+		Amount:     conver.Float64Must(orderJSON.Get("volume").Interface()),
+		DealAmount: conver.Float64Must(orderJSON.Get("trade_volume").Interface()),
+		Fee:        math.Abs(conver.Float64Must(orderJSON.Get("fee").Interface())),
+		TradeType:  e.tradeTypeOf(orderJSON),
+		StockType:  stockType,
+	}
+}
+
+// Order get details of an order
+func (e *HuobiFuture) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
+	}
+	coin, _ := e.contractOf(stockType)
+	json, err := e.post("GetOrder", "/api/v1/contract_order_info", map[string]string{"symbol": coin, "order_id": id})
+	if err != nil {
+		return Order{}, err
+	}
+	data := json.Get("data")
+	if len(data.MustArray()) < 1 {
+		return Order{}, newError(ErrUnknown, "GetOrder", "order(id = ", id, ") not exist")
+	}
+	return e.parseOrder(stockType, data.GetIndex(0)), nil
+}
+
+// GetOrder get details of an order
+func (e *HuobiFuture) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
+}
+
+// Orders get all unfilled orders of the contract
+func (e *HuobiFuture) Orders(stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
+	}
+	coin, contractType := e.contractOf(stockType)
+	json, err := e.post("GetOrders", "/api/v1/contract_openorders", map[string]string{"symbol": coin, "page_index": "1", "page_size": "50"})
+	if err != nil {
+		return nil, err
+	}
+	orders := []Order{}
+	ordersJSON := json.GetPath("data", "orders")
+	for i := 0; i < len(ordersJSON.MustArray()); i++ {
+		orderJSON := ordersJSON.GetIndex(i)
+		// 同一个币种的所有合约的挂单在一起
+		if orderJSON.Get("contract_type").MustString() != contractType {
+			continue
+		}
+		orders = append(orders, e.parseOrder(stockType, orderJSON))
+	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *HuobiFuture) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the fills of the contract in the last 7 days, the fee is charged in the coin
+func (e *HuobiFuture) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	coin, contractType := e.contractOf(stockType)
+	params := map[string]string{
+		"symbol":      coin,
+		"trade_type":  "0",
+		"create_date": "7",
+		"page_size":   "50",
+	}
+	json, err := e.post("GetTrades", "/api/v1/contract_matchresults", params)
+	if err != nil {
+		return nil, err
+	}
+	trades := []Trade{}
+	tradesJSON := json.GetPath("data", "trades")
+	for i := 0; i < len(tradesJSON.MustArray()); i++ {
+		tradeJSON := tradesJSON.GetIndex(i)
+		if tradeJSON.Get("contract_type").MustString() != contractType {
+			continue
+		}
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(tradeJSON.Get("id").Interface()),
+			OrderID:     fmt.Sprint(tradeJSON.Get("order_id").Interface()),
+			Price:       conver.Float64Must(tradeJSON.Get("trade_price").Interface()),
+			Amount:      conver.Float64Must(tradeJSON.Get("trade_volume").Interface()),
+			Fee:         math.Abs(conver.Float64Must(tradeJSON.Get("trade_fee").Interface())),
+			FeeCurrency: coin,
+			Time:        conver.Int64Must(tradeJSON.Get("create_date").Interface()) / 1000,
+			TradeType:   e.tradeTypeOf(tradeJSON),
+			StockType:   stockType,
+		})
+	}
+	return trades, nil
+}
+
+// GetTrades get the recent fills
+func (e *HuobiFuture) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// Cancel cancel an order
+func (e *HuobiFuture) Cancel(order Order) error {
+	if !e.markets.has(order.StockType) {
+		return newError(ErrInvalidSymbol, "CancelOrder", "unrecognized stockType: ", order.StockType)
+	}
+	coin, _ := e.contractOf(order.StockType)
+	json, err := e.post("CancelOrder", "/api/v1/contract_cancel", map[string]string{"symbol": coin, "order_id": order.ID})
+	if err != nil {
+		return err
+	}
+	// 撤单失败时 status 仍然是 ok, 错误在 errors 中
+	if errorsJSON := json.GetPath("data", "errors"); len(errorsJSON.MustArray()) > 0 {
+		errorJSON := errorsJSON.GetIndex(0)
+		return newCodeError("CancelOrder", huobiFutureErrorKinds, errorJSON.Get("err_code").Interface(), errorJSON.Get("err_msg").MustString())
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *HuobiFuture) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
+	return true
+}
+
+// Ticker get market ticker & depth
+func (e *HuobiFuture) Ticker(stockType string, size int) (ticker Ticker, err error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
+		return
+	}
+	if size <= 0 {
+		size = 20
+	}
+	json, err := e.get("GetTicker", "/market/depth", map[string]string{"symbol": e.markets.symbol(stockType), "type": "step0"})
+	if err != nil {
+		return
+	}
+	for i, side := range []string{"bids", "asks"} {
+		levels := json.GetPath("tick", side)
+		for j := 0; j < len(levels.MustArray()) && j < size; j++ {
+			level := OrderBook{
+				Price:  conver.Float64Must(levels.GetIndex(j).GetIndex(0).Interface()),
+				Amount: conver.Float64Must(levels.GetIndex(j).GetIndex(1).Interface()),
+			}
+			if i == 0 {
+				ticker.Bids = append(ticker.Bids, level)
+			} else {
+				ticker.Asks = append(ticker.Asks, level)
+			}
+		}
+	}
+	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
+		return
+	}
+	ticker.Buy = ticker.Bids[0].Price
+	ticker.Sell = ticker.Asks[0].Price
+	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
+	return
+}
+
+// GetTicker get market ticker & depth
+func (e *HuobiFuture) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data, the volume is in contracts
+func (e *HuobiFuture) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	recordsNew, err := e.klines(stockType, period, 0, e.store.missing(stockType, period, size))
+	if err != nil {
+		return nil, err
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *HuobiFuture) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	return e.klines(stockType, period, since, size)
+}
+
+// klines get the latest size records, or the records since a unix timestamp if since > 0
+func (e *HuobiFuture) klines(stockType, period string, since int64, size int) ([]Record, error) {
+	if size <= 0 || size > 2000 {
+		size = 2000
+	}
+	params := map[string]string{
+		"symbol": e.markets.symbol(stockType),
+		"period": e.recordsPeriodMap[period],
+	}
+	if since > 0 {
+		// 按时间查询时不能同时使用 size
+		params["from"] = fmt.Sprint(since)
+		params["to"] = fmt.Sprint(since + int64(size-1)*periodLength(period))
+	} else {
+		params["size"] = fmt.Sprint(size)
+	}
+	json, err := e.get("GetRecords", "/market/history/kline", params)
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	data := json.Get("data")
+	for i := 0; i < len(data.MustArray()); i++ {
+		recordJSON := data.GetIndex(i)
+		records = append(records, Record{
+			Time:   conver.Int64Must(recordJSON.Get("id").Interface()),
+			Open:   conver.Float64Must(recordJSON.Get("open").Interface()),
+			High:   conver.Float64Must(recordJSON.Get("high").Interface()),
+			Low:    conver.Float64Must(recordJSON.Get("low").Interface()),
+			Close:  conver.Float64Must(recordJSON.Get("close").Interface()),
+			Volume: conver.Float64Must(recordJSON.Get("vol").Interface()),
+		})
+	}
+	return records, nil
+}
+
+// GetRecords get candlestick data
+func (e *HuobiFuture) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
@@ -1,46 +1,75 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io/ioutil"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/miaolz123/conver"
-	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/config"
 	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/models"
 	"github.com/HunterUPP/QuantBot/api/HuobiProAPI/services"
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
+	"golang.org/x/net/websocket"
 )
 
+// huobiErrorKinds the error codes of huobi.com
+var huobiErrorKinds = map[string]string{
+	"order-accountbalance-error":                ErrInsufficientBalance,
+	"account-frozen-balance-insufficient-error": ErrInsufficientBalance,
+	"base-symbol-error":                         ErrInvalidSymbol,
+	"invalid-parameter":                         ErrUnknown,
+	"api-signature-not-valid":                   ErrAuth,
+	"login-required":                            ErrAuth,
+}
+
 // Huobi the exchange struct of huobi.com
 type Huobi struct {
+	client           *services.Client
 	stockTypeMap     map[string]string
 	tradeTypeMap     map[string]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	streams          *stream
+	userStream       *userStream
+	store            *recordStore
 	logger           model.Logger
 	option           Option
+	lastError        *Error
+
+	limiter *rateLimiter
+}
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+// huobiPeriods the periods of the records the exchange supports
+var huobiPeriods = map[string]string{
+	"M":   "1min",
+	"M5":  "5min",
+	"M15": "15min",
+	"M30": "30min",
+	"H":   "60min",
+	"D":   "1day",
+	"W":   "1week",
 }
 
+// huobiOrderTypes the order types the exchange supports
+var huobiOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
 // NewHuobi create an exchange struct of huobi.com
 func NewHuobi(opt Option) Exchange {
-	config.ACCESS_KEY = opt.AccessKey
-	config.SECRET_KEY = opt.SecretKey
-	//...
-	return &Huobi{
+	e := &Huobi{
+		client: services.NewClient(newHTTPClient(opt), opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
-			"BTC/USDT":  "btc",
-			"ETH/USDT":  "eth",
-			"EOS/USDT":  "eos",
-			"ONT/USDT":  "ont",
-			"QTUM/USDT": "qtum",
+			"BTC/USDT":  "btcusdt",
+			"ETH/USDT":  "ethusdt",
+			"EOS/USDT":  "eosusdt",
+			"ONT/USDT":  "ontusdt",
+			"QTUM/USDT": "qtumusdt",
 		},
 		tradeTypeMap: map[string]string{
 			"buy-limit":   constant.TradeTypeBuy,
@@ -48,15 +77,7 @@ func NewHuobi(opt Option) Exchange {
 			"buy-market":  constant.TradeTypeBuy,
 			"sell-market": constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "001",
-			"M5":  "005",
-			"M15": "015",
-			"M30": "030",
-			"H":   "060",
-			"D":   "100",
-			"W":   "200",
-		},
+		recordsPeriodMap: huobiPeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT":  0.001,
 			"ETH/USDT":  0.001,
@@ -64,13 +85,19 @@ func NewHuobi(opt Option) Exchange {
 			"ONT/USDT":  0.001,
 			"QTUM/USDT": 0.001,
 		},
-		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: huobiOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
-	}
+		limiter: limiterOf(opt),
+	}
+	e.client.MarketURL = baseURLOf(opt, e.client.MarketURL)
+	e.client.TradeURL = baseURLOf(opt, e.client.TradeURL)
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	e.streams = newStream(&huobiStream{proxy: opt.ProxyURL}, e.logger)
+	e.userStream = newUserStream(&huobiUserStream{client: e.client, proxy: opt.ProxyURL, markets: e.markets, tradeTypeMap: e.tradeTypeMap}, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -88,63 +115,116 @@ func (e *Huobi) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *Huobi) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *Huobi) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *Huobi) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *Huobi) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *Huobi) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
 }
 
-// GetAccount get the account detail of this exchange
-func (e *Huobi) GetAccount() interface{} {
-	accounts, err := services.GetAccounts()
+// GetMarkets get all the markets of this exchange
+func (e *Huobi) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the symbols of huobi
+func (e *Huobi) loadMarkets() (markets []Market, err error) {
+	result, err := e.client.GetSymbols()
+	if err != nil {
+		return
+	}
+	if result.Status != "ok" {
+		err = newCodeError("GetMarkets", huobiErrorKinds, result.ErrCode, result.ErrMsg)
+		return
+	}
+	for _, s := range result.Data {
+		base := strings.ToUpper(s.BaseCurrency)
+		quote := strings.ToUpper(s.QuoteCurrency)
+		markets = append(markets, Market{
+			StockType:       base + "/" + quote,
+			Symbol:          s.BaseCurrency + s.QuoteCurrency,
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  s.PricePrecision,
+			AmountPrecision: s.AmountPrecision,
+			TickSize:        stepOf(s.PricePrecision),
+			LotSize:         stepOf(s.AmountPrecision),
+			MinAmount:       s.MinOrderAmt,
+			MinNotional:     s.MinOrderValue,
+		})
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *Huobi) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Huobi) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// accountID get the id of the working spot account
+func (e *Huobi) accountID() (string, error) {
+	if e.client.AccountID != "" {
+		return e.client.AccountID, nil
+	}
+	accounts, err := e.client.GetAccounts()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return "", wrapError("GetAccount", err)
 	}
 	if accounts.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", accounts.ErrMsg)
-		return false
+		return "", newCodeError("GetAccount", huobiErrorKinds, accounts.ErrCode, accounts.ErrMsg)
 	}
-	accountID := int64(-1)
-	count := len(accounts.Data)
-	for i := 0; i < count; i++ {
-		actData := accounts.Data[i]
+	for _, actData := range accounts.Data {
 		if actData.State == "working" && actData.Type == "spot" {
-			accountID = actData.ID
-			break
+			e.client.AccountID = strconv.FormatInt(actData.ID, 10)
+			return e.client.AccountID, nil
 		}
 	}
-	if accountID == -1 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", "all account locked")
-		return false
+	return "", newError(ErrAuth, "GetAccount", "all account locked")
+}
+
+// Account get the account detail of this exchange
+func (e *Huobi) Account() (Account, error) {
+	accountID, err := e.accountID()
+	if err != nil {
+		return nil, err
 	}
-	balance, err := services.GetAccountBalance(strconv.FormatInt(accountID, 10))
+	balance, err := e.client.GetAccountBalance(accountID)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, wrapError("GetAccount", err)
 	}
 	if balance.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", balance.ErrMsg)
-		return false
+		return nil, newCodeError("GetAccount", huobiErrorKinds, balance.ErrCode, balance.ErrMsg)
 	}
-	result := make(map[string]float64)
-	count = len(balance.Data.List)
+	result := Account{}
+	count := len(balance.Data.List)
 	for i := 0; i < count; i++ {
 		subAcc := balance.Data.List[i]
 		if subAcc.Type == "trade" {
@@ -153,92 +233,99 @@ func (e *Huobi) GetAccount() interface{} {
 			result["Frozen"+strings.ToUpper(subAcc.Currency)] = conver.Float64Must(subAcc.Balance)
 		}
 	}
-	//...
-	config.ACCOUNT_ID = strconv.FormatInt(accountID, 10)
-	//...
-	return result
+	return result, nil
 }
 
-// Trade place an order
-func (e *Huobi) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// GetAccount get the account detail of this exchange
+func (e *Huobi) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency
+func (e *Huobi) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	// 订单类型是 buy-limit、buy-market、buy-limit-maker、buy-ioc 和 buy-limit-fok 等
+	orderType := "limit"
+	switch {
+	case price <= 0:
+		orderType = "market"
+		price = 0.0
+	case opts.PostOnly:
+		orderType = "limit-maker"
+	case opts.TimeInForce == constant.TimeInForceIOC:
+		orderType = "ioc"
+	case opts.TimeInForce == constant.TimeInForceFOK:
+		orderType = "limit-fok"
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", "buy-"+orderType, constant.BUY, stockType, price, amount, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", "sell-"+orderType, constant.SELL, stockType, price, amount, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
 }
 
-func (e *Huobi) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	params := models.PlaceRequestParams{
-		AccountID: config.ACCOUNT_ID,                  // 账户ID
-		Amount:    conver.StringMust(amount),          // 限价表示下单数量, 市价买单时表示买多少钱, 市价卖单时表示卖多少币
-		Price:     conver.StringMust(price),           // 下单价格, 市价单不传该参数
-		Source:    "api",                              // 订单来源, api: API调用, margin-api: 借贷资产交易
-		Symbol:    e.stockTypeMap[stockType] + "usdt", // 交易对, btcusdt, bccbtc......
-		Type:      "buy-limit",                        // 订单类型, buy-market: 市价买, sell-market: 市价卖, buy-limit: 限价买, sell-limit: 限价卖
-	}
-	result, err := services.Place(params)
+// Trade place an order
+func (e *Huobi) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
+		return e.fail("Trade", err)
 	}
-	if result.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", result.ErrMsg)
-		return false
-	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return result.Data
+	return id
 }
 
-func (e *Huobi) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
+func (e *Huobi) place(method, orderType, logType, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	accountID, err := e.accountID()
+	if err != nil {
+		return "", err
+	}
 	params := models.PlaceRequestParams{
-		AccountID: config.ACCOUNT_ID,                  // 账户ID
-		Amount:    conver.StringMust(amount),          // 限价表示下单数量, 市价买单时表示买多少钱, 市价卖单时表示卖多少币
-		Price:     conver.StringMust(price),           // 下单价格, 市价单不传该参数
-		Source:    "api",                              // 订单来源, api: API调用, margin-api: 借贷资产交易
-		Symbol:    e.stockTypeMap[stockType] + "usdt", // 交易对, btcusdt, bccbtc......
-		Type:      "sell-limit",                       // 订单类型, buy-market: 市价买, sell-market: 市价卖, buy-limit: 限价买, sell-limit: 限价卖
-	}
-	result, err := services.Place(params)
+		AccountID: accountID,                   // 账户ID
+		Amount:    conver.StringMust(amount),   // 限价表示下单数量, 市价买单时表示买多少钱, 市价卖单时表示卖多少币
+		Source:    "api",                       // 订单来源, api: API调用, margin-api: 借贷资产交易
+		Symbol:    e.markets.symbol(stockType), // 交易对, btcusdt, bccbtc......
+		Type:      orderType,                   // 订单类型, buy-market: 市价买, sell-market: 市价卖, buy-limit: 限价买, sell-limit: 限价卖
+	}
+	if price > 0 {
+		params.Price = conver.StringMust(price) // 下单价格, 市价单不传该参数
+	}
+	result, err := e.client.Place(params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
+		return "", wrapError(method, err)
 	}
 	if result.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", result.ErrMsg)
-		return false
+		return "", newCodeError(method, huobiErrorKinds, result.ErrCode, result.ErrMsg)
 	}
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return result.Data
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return result.Data, nil
 }
 
-// GetOrder get details of an order
-func (e *Huobi) GetOrder(stockType, id string) interface{} {
+// Order get details of an order
+func (e *Huobi) Order(stockType, id string) (Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
 	}
-	result, err := services.GetOrderDetail(id)
+	result, err := e.client.GetOrderDetail(id)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
+		return Order{}, wrapError("GetOrder", err)
 	}
 	if result.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", result.ErrMsg)
-		return false
+		return Order{}, newCodeError("GetOrder", huobiErrorKinds, result.ErrCode, result.ErrMsg)
 	}
 	return Order{
 		ID:         fmt.Sprint(result.Data.ID),
@@ -247,24 +334,30 @@ func (e *Huobi) GetOrder(stockType, id string) interface{} {
 		DealAmount: conver.Float64Must(result.Data.DealAmount),
 		TradeType:  e.tradeTypeMap[result.Data.TradeType],
 		StockType:  stockType,
+	}, nil
+}
+
+// GetOrder get details of an order
+func (e *Huobi) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
+	return order
 }
 
-// GetOrders get all unfilled orders
-func (e *Huobi) GetOrders(stockType string) interface{} {
+// Orders get all unfilled orders
+func (e *Huobi) Orders(stockType string) ([]Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
-	result, err := services.GetOrders(e.stockTypeMap[stockType] + "usdt")
+	result, err := e.client.GetOrders(e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
+		return nil, wrapError("GetOrders", err)
 	}
 	if result.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", result.ErrMsg)
-		return false
+		return nil, newCodeError("GetOrders", huobiErrorKinds, result.ErrCode, result.ErrMsg)
 	}
 	orders := []Order{}
 	count := len(result.Data)
@@ -278,61 +371,118 @@ func (e *Huobi) GetOrders(stockType string) interface{} {
 			StockType:  stockType,
 		})
 	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Huobi) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
 	return orders
 }
 
-// GetTrades get all filled orders recently
+// Trades get the recent fills
+func (e *Huobi) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	result, err := e.client.GetMatchResults(e.markets.symbol(stockType))
+	if err != nil {
+		return nil, wrapError("GetTrades", err)
+	}
+	if result.Status != "ok" {
+		return nil, newCodeError("GetTrades", huobiErrorKinds, result.ErrCode, result.ErrMsg)
+	}
+	trades := []Trade{}
+	for _, match := range result.Data {
+		trade := Trade{
+			ID:          fmt.Sprint(match.ID),
+			OrderID:     fmt.Sprint(match.OrderID),
+			Price:       conver.Float64Must(match.Price),
+			Amount:      conver.Float64Must(match.FilledAmount),
+			Fee:         conver.Float64Must(match.FilledFees),
+			FeeCurrency: strings.ToUpper(match.FeeCurrency),
+			Time:        match.CreatedAt / 1000,
+			TradeType:   e.tradeTypeMap[match.Type],
+			StockType:   stockType,
+		}
+		// 旧版接口没有返回手续费币种, 买入扣除基础币种, 卖出扣除计价币种
+		if trade.FeeCurrency == "" {
+			trade.FeeCurrency = feeCurrencyOf(stockType, trade.TradeType)
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// GetTrades get the recent fills
 func (e *Huobi) GetTrades(stockType string) interface{} {
-	return nil
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
 }
 
-// CancelOrder cancel an order
-func (e *Huobi) CancelOrder(order Order) bool {
-	result, err := services.SubmitCancel(order.ID)
+// Cancel cancel an order
+func (e *Huobi) Cancel(order Order) error {
+	result, err := e.client.SubmitCancel(order.ID)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+		return wrapError("CancelOrder", err)
 	}
 	if result.Status != "ok" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", result.ErrMsg)
-		return false
+		return newCodeError("CancelOrder", huobiErrorKinds, result.ErrCode, result.ErrMsg)
 	}
 	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Huobi) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *Huobi) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *Huobi) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	result, err := services.GetMarketDepth(e.stockTypeMap[stockType]+"usdt", "step0")
+	if t, ok := e.streams.ticker(stockType, size); ok {
+		return t, nil
+	}
+	result, err := e.client.GetMarketDepth(e.markets.symbol(stockType), "step0")
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	if result.Status != "ok" {
-		err = fmt.Errorf("GetTicker() error, %+v", result.ErrMsg)
+		err = newCodeError("GetTicker", huobiErrorKinds, result.ErrCode, result.ErrMsg)
 		return
 	}
 	count := len(result.Tick.Bids)
-	for i := 0; i < count; i++ {
+	for i := 0; i < count && (size <= 0 || i < size); i++ {
 		ticker.Bids = append(ticker.Bids, OrderBook{
 			Price:  result.Tick.Bids[i][0],
 			Amount: result.Tick.Bids[i][1],
 		})
 	}
 	count = len(result.Tick.Asks)
-	for i := 0; i < count; i++ {
+	for i := 0; i < count && (size <= 0 || i < size); i++ {
 		ticker.Asks = append(ticker.Asks, OrderBook{
 			Price:  result.Tick.Asks[i][0],
 			Amount: result.Tick.Asks[i][1],
 		})
 	}
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
 	ticker.Buy = ticker.Bids[0].Price
@@ -343,15 +493,248 @@ func (e *Huobi) getTicker(stockType string, sizes ...interface{}) (ticker Ticker
 
 // GetTicker get market ticker & depth
 func (e *Huobi) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
+// Records get candlestick data
+func (e *Huobi) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	if size > 2000 {
+		size = 2000
+	}
+	fetch := e.store.missing(stockType, period, size)
+	result, err := e.client.GetKLine(e.markets.symbol(stockType), e.recordsPeriodMap[period], fetch)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	if result.Status != "ok" {
+		return nil, newCodeError("GetRecords", huobiErrorKinds, result.ErrCode, result.ErrMsg)
+	}
+	recordsNew := []Record{}
+	for i := len(result.Data); i > 0; i-- {
+		kline := result.Data[i-1]
+		recordsNew = append(recordsNew, Record{
+			Time:   kline.ID,
+			Open:   kline.Open,
+			High:   kline.High,
+			Low:    kline.Low,
+			Close:  kline.Close,
+			Volume: kline.Amount,
+		})
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
 // GetRecords get candlestick data
 func (e *Huobi) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
+
+// Stream subscribe the depth and trade channels of stockType by websocket, GetTicker reads the local order book after it
+func (e *Huobi) Stream(stockType string) error {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return newError(ErrInvalidSymbol, "Subscribe", "unrecognized stockType: ", stockType)
+	}
+	e.streams.subscribe(stockType, e.markets.symbol(stockType))
+	return nil
+}
+
+// Subscribe subscribe the market data of stockType by websocket
+func (e *Huobi) Subscribe(stockType string) bool {
+	if err := e.Stream(stockType); err != nil {
+		return e.fail("Subscribe", err)
+	}
+	return true
+}
+
+// CloseStreams close all the websocket streams
+func (e *Huobi) CloseStreams() {
+	e.streams.close()
+	e.userStream.close()
+}
+
+// Listen start the user data stream, the order and balance changes are kept in the event queue
+func (e *Huobi) Listen() error {
+	if _, err := e.accountID(); err != nil { //只推送现货账户的资金变化
+		return err
+	}
+	e.userStream.listen()
+	return nil
+}
+
+// Events take all the events out of the event queue, the user data stream is started at the first call
+func (e *Huobi) Events() ([]Event, error) {
+	if err := e.Listen(); err != nil {
+		return nil, err
+	}
+	return e.userStream.drain(), nil
+}
+
+// GetEvents get the order and balance changes pushed since the last call
+func (e *Huobi) GetEvents() interface{} {
+	if e.userStream.callback {
+		return e.fail("GetEvents", errCallbacks)
+	}
+	events, err := e.Events()
+	if err != nil {
+		return e.fail("GetEvents", err)
+	}
+	return events
+}
+
+// UseCallbacks pass the events to onOrder and onBalance of the script only, GetEvents fails after it
+func (e *Huobi) UseCallbacks() {
+	e.userStream.callback = true
+}
+
+// huobiStream the websocket stream of huobi.com, every depth message is a full snapshot of 150 levels
+type huobiStream struct {
+	proxy string //代理地址
+}
+
+func (p *huobiStream) dial(symbol string) (*websocket.Conn, error) {
+	return dialStream(p.proxy, "wss://api.huobi.pro/ws",
+		map[string]string{"sub": "market." + symbol + ".depth.step0", "id": "depth"},
+		map[string]string{"sub": "market." + symbol + ".trade.detail", "id": "trade"},
+	)
+}
+
+func (p *huobiStream) handle(conn *websocket.Conn, message []byte, book *orderBook) error {
+	reader, err := gzip.NewReader(bytes.NewReader(message)) //火币的消息都经过 gzip 压缩
+	if err != nil {
+		return err
+	}
+	message, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	json, err := simplejson.NewJson(message)
+	if err != nil {
+		return err
+	}
+	if ping, ok := json.CheckGet("ping"); ok {
+		return websocket.JSON.Send(conn, map[string]interface{}{"pong": ping.Interface()})
+	}
+	if json.Get("status").MustString() == "error" {
+		return fmt.Errorf("%v", json.Get("err-msg").MustString())
+	}
+	ch := json.Get("ch").MustString()
+	tick := json.Get("tick")
+	switch {
+	case strings.HasSuffix(ch, ".depth.step0"):
+		version := tick.Get("version").MustInt64()
+		if seq, ready := book.sequence(); ready && version <= seq { //过期的快照
+			return nil
+		}
+		book.snapshot(tick.Get("bids").MustArray(), tick.Get("asks").MustArray(), version)
+	case strings.HasSuffix(ch, ".trade.detail"):
+		trades := tick.Get("data")
+		if count := len(trades.MustArray()); count > 0 {
+			book.trade(conver.Float64Must(trades.GetIndex(count - 1).Get("price").Interface()))
+		}
+	}
 	return nil
 }
+
+// huobiOrderStatusMap the order state of huobi.com
+var huobiOrderStatusMap = map[string]string{
+	"submitted":        constant.OrderStatusNew,
+	"partial-filled":   constant.OrderStatusPartial,
+	"filled":           constant.OrderStatusFilled,
+	"partial-canceled": constant.OrderStatusCanceled,
+	"canceled":         constant.OrderStatusCanceled,
+}
+
+// huobiUserStream the user data stream of huobi.com, it is authorized by a signed auth message
+type huobiUserStream struct {
+	client       *services.Client
+	markets      *marketCache
+	tradeTypeMap map[string]string
+	proxy        string //代理地址
+}
+
+func (p *huobiUserStream) dial(done chan struct{}) (*websocket.Conn, error) {
+	return dialStream(p.proxy, "wss://api.huobi.pro/ws/v1",
+		p.client.WSAuth("/ws/v1"),
+		map[string]string{"op": "sub", "topic": "orders.*"},
+		map[string]string{"op": "sub", "topic": "accounts"},
+	)
+}
+
+func (p *huobiUserStream) handle(conn *websocket.Conn, message []byte) ([]Event, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	message, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	json, err := simplejson.NewJson(message)
+	if err != nil {
+		return nil, err
+	}
+	switch json.Get("op").MustString() {
+	case "ping":
+		return nil, websocket.JSON.Send(conn, map[string]interface{}{"op": "pong", "ts": json.Get("ts").Interface()})
+	case "auth", "sub":
+		if code := json.Get("err-code").MustInt(); code != 0 {
+			return nil, newCodeError("GetEvents", huobiErrorKinds, json.Get("err-code").Interface(), json.Get("err-msg").MustString())
+		}
+	case "notify":
+		data := json.Get("data")
+		if json.Get("topic").MustString() == "accounts" {
+			account := Account{}
+			balances := data.Get("list")
+			for i := 0; i < len(balances.MustArray()); i++ {
+				balance := balances.GetIndex(i)
+				if fmt.Sprint(balance.Get("account-id").Interface()) != p.client.AccountID {
+					continue
+				}
+				currency := strings.ToUpper(balance.Get("currency").MustString())
+				if balance.Get("type").MustString() == "frozen" {
+					currency = "Frozen" + currency
+				}
+				account[currency] = conver.Float64Must(balance.Get("balance").Interface())
+			}
+			return []Event{{
+				Type:    constant.EventBalance,
+				Time:    json.Get("ts").MustInt64() / 1000,
+				Account: account,
+			}}, nil
+		}
+		amount := conver.Float64Must(data.Get("order-amount").Interface())
+		return []Event{{
+			Type:   constant.EventOrder,
+			Time:   json.Get("ts").MustInt64() / 1000,
+			Status: huobiOrderStatusMap[data.Get("order-state").MustString()],
+			Order: Order{
+				ID:         fmt.Sprint(data.Get("order-id").Interface()),
+				Price:      conver.Float64Must(data.Get("order-price").Interface()),
+				Amount:     amount,
+				DealAmount: amount - conver.Float64Must(data.Get("unfilled-amount").Interface()),
+				TradeType:  p.tradeTypeMap[data.Get("order-type").MustString()],
+				StockType:  p.markets.stockTypeOf(data.Get("symbol").MustString()),
+			},
+		}}, nil
+	}
+	return nil, nil
+}
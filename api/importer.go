@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordFields the fields of a record read from a file, in the default column order
+var recordFields = []string{"time", "open", "high", "low", "close", "volume"}
+
+// ImportOptions struct, how to read the records of a csv or json file, e.g.
+// {"exchangeType": "okex", "stockType": "BTC/USDT", "period": "M", "format": "csv", "columns": {"time": "Date"}, "timeFormat": "2006-01-02 15:04", "timezone": "Asia/Shanghai"}
+type ImportOptions struct {
+	ExchangeType string            `json:"exchangeType"` //保存到哪个交易所
+	StockType    string            `json:"stockType"`    //货币类型
+	Period       string            `json:"period"`       //K线周期
+	Format       string            `json:"format"`       //csv 或者 json
+	Columns      map[string]string `json:"columns"`      //字段对应的列名或者从 0 开始的列号, 字段为 time、open、high、low、close、volume, 默认使用字段名
+	NoHeader     bool              `json:"noHeader"`     //csv 文件没有表头, 此时 Columns 只能使用列号, 默认按字段的顺序
+	Comma        string            `json:"comma"`        //csv 的分隔符, 默认为逗号
+	TimeFormat   string            `json:"timeFormat"`   //时间格式: unix(秒)、unixms(毫秒) 或者 Go 的时间格式, 为空时自动识别时间戳和 "2006-01-02 15:04:05"
+	Timezone     string            `json:"timezone"`     //时间的时区, 如 Asia/Shanghai, 默认为 UTC
+}
+
+// ImportResult struct, the summary of an import
+type ImportResult struct {
+	Count int   //导入的K线数量
+	First int64 //第一根K线的unix时间戳
+	Last  int64 //最后一根K线的unix时间戳
+}
+
+// ImportRecords read the records of a csv or json file and store them, the timestamps must be increasing without duplicates,
+// nothing is stored if any line is wrong
+func ImportRecords(r io.Reader, opt ImportOptions) (result ImportResult, err error) {
+	records, err := ParseRecords(r, opt)
+	if err != nil {
+		return
+	}
+	if len(records) == 0 {
+		err = newError(ErrUnknown, "ImportRecords", "no records in the file")
+		return
+	}
+	if err = saveRecords(opt.ExchangeType, strings.ToUpper(opt.StockType), opt.Period, records); err != nil {
+		return result, wrapError("ImportRecords", err)
+	}
+	return ImportResult{
+		Count: len(records),
+		First: records[0].Time,
+		Last:  records[len(records)-1].Time,
+	}, nil
+}
+
+// ParseRecords read and check the records of a csv or json file
+func ParseRecords(r io.Reader, opt ImportOptions) ([]Record, error) {
+	if opt.ExchangeType == "" || opt.StockType == "" {
+		return nil, newError(ErrUnknown, "ImportRecords", "the exchange type and the stockType are required")
+	}
+	if periodLength(opt.Period) <= 0 {
+		return nil, newError(ErrNotSupported, "ImportRecords", "unrecognized period: ", opt.Period)
+	}
+	location := time.UTC
+	if opt.Timezone != "" {
+		l, err := time.LoadLocation(opt.Timezone)
+		if err != nil {
+			return nil, wrapError("ImportRecords", err)
+		}
+		location = l
+	}
+	var rows []map[string]interface{}
+	var err error
+	switch strings.ToLower(opt.Format) {
+	case "csv", "":
+		rows, err = csvRows(r, opt)
+	case "json":
+		rows, err = jsonRows(r, opt)
+	default:
+		return nil, newError(ErrNotSupported, "ImportRecords", "unrecognized format: ", opt.Format)
+	}
+	if err != nil {
+		return nil, wrapError("ImportRecords", err)
+	}
+	records := []Record{}
+	for i, row := range rows {
+		record, err := parseRecord(row, opt.TimeFormat, location)
+		if err != nil {
+			return nil, newError(ErrUnknown, "ImportRecords", fmt.Sprintf("record %v: %v", i+1, err))
+		}
+		if n := len(records); n > 0 {
+			if record.Time == records[n-1].Time {
+				return nil, newError(ErrUnknown, "ImportRecords", fmt.Sprintf("record %v: duplicate time %v", i+1, record.Time))
+			}
+			if record.Time < records[n-1].Time {
+				return nil, newError(ErrUnknown, "ImportRecords", fmt.Sprintf("record %v: time %v is before the last one %v", i+1, record.Time, records[n-1].Time))
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// column get the column of a field in the options, the field name by default
+func (opt ImportOptions) column(field string) string {
+	if c, ok := opt.Columns[field]; ok && c != "" {
+		return c
+	}
+	return field
+}
+
+// csvRows read the rows of a csv file as maps from the fields to the cells
+func csvRows(r io.Reader, opt ImportOptions) (rows []map[string]interface{}, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	if opt.Comma != "" {
+		reader.Comma = []rune(opt.Comma)[0]
+	}
+	lines, err := reader.ReadAll()
+	if err != nil || len(lines) == 0 {
+		return
+	}
+	indexes := map[string]int{}
+	if opt.NoHeader {
+		for i, field := range recordFields {
+			indexes[field] = i
+			if c, ok := opt.Columns[field]; ok {
+				if indexes[field], err = strconv.Atoi(c); err != nil {
+					return nil, fmt.Errorf("the column of %v must be a number without the header: %v", field, c)
+				}
+			}
+		}
+	} else {
+		header := map[string]int{}
+		for i, name := range lines[0] {
+			header[strings.TrimSpace(name)] = i
+		}
+		for _, field := range recordFields {
+			c := opt.column(field)
+			if i, ok := header[c]; ok {
+				indexes[field] = i
+			} else if i, err := strconv.Atoi(c); err == nil {
+				indexes[field] = i
+			} else {
+				return nil, fmt.Errorf("no column %q of %v in the header", c, field)
+			}
+		}
+		lines = lines[1:]
+	}
+	for n, line := range lines {
+		row := map[string]interface{}{}
+		for field, i := range indexes {
+			if i < 0 || i >= len(line) {
+				return nil, fmt.Errorf("line %v: no column %v of %v", n+1, i, field)
+			}
+			row[field] = strings.TrimSpace(line[i])
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// jsonRows read the rows of a json array of objects or arrays as maps from the fields to the values
+func jsonRows(r io.Reader, opt ImportOptions) (rows []map[string]interface{}, err error) {
+	items := []interface{}{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err = decoder.Decode(&items); err != nil {
+		return
+	}
+	for n, item := range items {
+		row := map[string]interface{}{}
+		switch v := item.(type) {
+		case map[string]interface{}:
+			for _, field := range recordFields {
+				row[field] = v[opt.column(field)]
+			}
+		case []interface{}:
+			for i, field := range recordFields {
+				if c, ok := opt.Columns[field]; ok {
+					if i, err = strconv.Atoi(c); err != nil {
+						return nil, fmt.Errorf("the column of %v must be a number for the arrays: %v", field, c)
+					}
+				}
+				if i >= 0 && i < len(v) {
+					row[field] = v[i]
+				}
+			}
+		default:
+			return nil, fmt.Errorf("item %v is neither an object nor an array", n+1)
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// parseRecord convert a row into a record, the time is converted to a unix timestamp
+func parseRecord(row map[string]interface{}, timeFormat string, location *time.Location) (record Record, err error) {
+	if record.Time, err = parseTime(row["time"], timeFormat, location); err != nil {
+		return
+	}
+	values := []*float64{&record.Open, &record.High, &record.Low, &record.Close, &record.Volume}
+	for i, field := range recordFields[1:] {
+		s := strings.TrimSpace(fmt.Sprint(row[field]))
+		if row[field] == nil || s == "" {
+			return record, fmt.Errorf("no %v", field)
+		}
+		if *values[i], err = strconv.ParseFloat(s, 64); err != nil || math.IsNaN(*values[i]) {
+			return record, fmt.Errorf("invalid %v: %v", field, s)
+		}
+	}
+	return
+}
+
+// parseTime convert a time value into a unix timestamp
+func parseTime(value interface{}, timeFormat string, location *time.Location) (int64, error) {
+	s := strings.TrimSpace(fmt.Sprint(value))
+	if value == nil || s == "" {
+		return 0, fmt.Errorf("no time")
+	}
+	switch timeFormat {
+	case "unix", "unixms", "":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			// 自动识别时, 超过 1e11 的时间戳按毫秒处理
+			if timeFormat == "unixms" || (timeFormat == "" && n > 1e11) {
+				n /= 1000
+			}
+			return int64(n), nil
+		} else if timeFormat != "" {
+			return 0, fmt.Errorf("invalid time: %v", s)
+		}
+		timeFormat = "2006-01-02 15:04:05"
+	}
+	t, err := time.ParseInLocation(timeFormat, s, location)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time: %v", s)
+	}
+	return t.Unix(), nil
+}
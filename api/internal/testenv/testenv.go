@@ -0,0 +1,17 @@
+// Package testenv points the config of the tests at testdata/config.test.ini, whose database is in memory,
+// it is imported blank by the tests and initialized before the config package because of its import path
+package testenv
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+func init() {
+	if os.Getenv("QUANTBOT_CONFIG") != "" {
+		return
+	}
+	_, file, _, _ := runtime.Caller(0)
+	os.Setenv("QUANTBOT_CONFIG", filepath.Join(filepath.Dir(file), "testdata", "config.test.ini"))
+}
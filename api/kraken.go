@@ -0,0 +1,684 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
+)
+
+// krakenErrorKinds the error messages of kraken.com
+var krakenErrorKinds = map[string]string{
+	"EAPI:Invalid key":           ErrAuth,
+	"EAPI:Invalid signature":     ErrAuth,
+	"EAPI:Invalid nonce":         ErrAuth,
+	"EGeneral:Permission denied": ErrAuth,
+	"EAPI:Rate limit exceeded":   ErrRateLimited,
+	"EOrder:Rate limit exceeded": ErrRateLimited,
+	"EQuery:Unknown asset pair":  ErrInvalidSymbol,
+	"EOrder:Insufficient funds":  ErrInsufficientBalance,
+}
+
+// krakenCurrencyMap the currencies which kraken names differently
+var krakenCurrencyMap = map[string]string{
+	"XBT": "BTC",
+	"XDG": "DOGE",
+}
+
+// krakenLegacyAssets the old assets of kraken which have an X or Z prefix, the newer assets like ZETA have no prefix
+var krakenLegacyAssets = map[string]bool{
+	"XXBT": true, "XXDG": true, "XETH": true, "XETC": true, "XLTC": true, "XMLN": true, "XREP": true,
+	"XXLM": true, "XXMR": true, "XXRP": true, "XZEC": true, "XICN": true, "XNMC": true, "XXVN": true,
+	"ZUSD": true, "ZEUR": true, "ZCAD": true, "ZGBP": true, "ZJPY": true, "ZAUD": true, "ZKRW": true,
+}
+
+// Kraken the exchange struct of kraken.com, the symbols are the altnames of the pairs like XBTUSD
+type Kraken struct {
+	stockTypeMap     map[string]string
+	tradeTypeMap     map[string]string
+	recordsPeriodMap map[string]string
+	minAmountMap     map[string]float64
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
+	host             string
+	logger           model.Logger
+	option           Option
+	lastError        *Error
+
+	mutex    sync.Mutex
+	pairKeys map[string]string //交易对的 altname 对应的名称, 成交记录中使用的是这个名称
+
+	limiter    *rateLimiter
+	httpClient *http.Client
+}
+
+// krakenPeriods the periods of the records the exchange supports
+var krakenPeriods = map[string]string{
+	"M":   "1",
+	"M5":  "5",
+	"M15": "15",
+	"M30": "30",
+	"H":   "60",
+	"H4":  "240",
+	"D":   "1440",
+	"W":   "10080",
+}
+
+// krakenOrderTypes the order types the exchange supports
+var krakenOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC}
+
+// NewKraken create an exchange struct of kraken.com
+func NewKraken(opt Option) Exchange {
+	e := &Kraken{
+		stockTypeMap: map[string]string{
+			"BTC/USD":  "XBTUSD",
+			"BTC/EUR":  "XBTEUR",
+			"ETH/USD":  "ETHUSD",
+			"ETH/EUR":  "ETHEUR",
+			"ETH/BTC":  "ETHXBT",
+			"LTC/USD":  "LTCUSD",
+			"LTC/EUR":  "LTCEUR",
+			"USDT/USD": "USDTUSD",
+		},
+		tradeTypeMap: map[string]string{
+			"buy":  constant.TradeTypeBuy,
+			"sell": constant.TradeTypeSell,
+			"b":    constant.TradeTypeBuy,
+			"s":    constant.TradeTypeSell,
+		},
+		recordsPeriodMap: krakenPeriods,
+		minAmountMap: map[string]float64{
+			"BTC/USD":  0.0001,
+			"BTC/EUR":  0.0001,
+			"ETH/USD":  0.002,
+			"ETH/EUR":  0.002,
+			"ETH/BTC":  0.002,
+			"LTC/USD":  0.02,
+			"LTC/EUR":  0.02,
+			"USDT/USD": 5.0,
+		},
+		orderTypes: krakenOrderTypes,
+		host:       baseURLOf(opt, "https://api.kraken.com/"),
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
+
+		pairKeys: map[string]string{
+			"XBTUSD":  "XXBTZUSD",
+			"XBTEUR":  "XXBTZEUR",
+			"ETHUSD":  "XETHZUSD",
+			"ETHEUR":  "XETHZEUR",
+			"ETHXBT":  "XETHXXBT",
+			"LTCUSD":  "XLTCZUSD",
+			"LTCEUR":  "XLTCZEUR",
+			"USDTUSD": "USDTZUSD",
+		},
+
+		limiter:    limiterOf(opt),
+		httpClient: newHTTPClient(opt),
+	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
+}
+
+// krakenCurrency convert an asset of kraken to the currency of this project, like XXBT => BTC and ZUSD => USD
+func krakenCurrency(asset string) string {
+	asset = strings.ToUpper(asset)
+	// 老的币种有 X 或者 Z 前缀, 如 XXBT、XETH、ZUSD、ZEUR
+	if krakenLegacyAssets[asset] {
+		asset = asset[1:]
+	}
+	if currency, ok := krakenCurrencyMap[asset]; ok {
+		return currency
+	}
+	return asset
+}
+
+// Log print something to console
+func (e *Kraken) Log(msgs ...interface{}) {
+	e.logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
+}
+
+// GetType get the type of this exchange
+func (e *Kraken) GetType() string {
+	return e.option.Type
+}
+
+// GetName get the name of this exchange
+func (e *Kraken) GetName() string {
+	return e.option.Name
+}
+
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
+func (e *Kraken) SetLimit(times interface{}) float64 {
+	return e.limiter.setRate(conver.Float64Must(times))
+}
+
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
+func (e *Kraken) AutoSleep() {
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *Kraken) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *Kraken) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
+}
+
+// GetMinAmount get the min trade amonut of this exchange
+func (e *Kraken) GetMinAmount(stock string) float64 {
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the markets of this exchange
+func (e *Kraken) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the asset pairs of kraken, the dark pool pairs like XBTUSD.d are left out
+func (e *Kraken) loadMarkets() (markets []Market, err error) {
+	json, err := e.public("GetMarkets", "AssetPairs", nil)
+	if err != nil {
+		return
+	}
+	pairKeys := map[string]string{}
+	for key := range json.MustMap() {
+		pairJSON := json.Get(key)
+		altname := pairJSON.Get("altname").MustString()
+		currencies := strings.Split(pairJSON.Get("wsname").MustString(), "/")
+		if strings.HasSuffix(altname, ".d") || len(currencies) != 2 {
+			continue
+		}
+		base := krakenCurrency(currencies[0])
+		quote := krakenCurrency(currencies[1])
+		pricePrecision := conver.IntMust(pairJSON.Get("pair_decimals").Interface())
+		amountPrecision := conver.IntMust(pairJSON.Get("lot_decimals").Interface())
+		pairKeys[altname] = key
+		markets = append(markets, Market{
+			StockType:       base + "/" + quote,
+			Symbol:          altname,
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  pricePrecision,
+			AmountPrecision: amountPrecision,
+			TickSize:        stepOf(pricePrecision),
+			LotSize:         stepOf(amountPrecision),
+			MinAmount:       conver.Float64Must(pairJSON.Get("ordermin").Interface()),
+			MinNotional:     conver.Float64Must(pairJSON.Get("costmin").Interface()),
+		})
+	}
+	e.mutex.Lock()
+	e.pairKeys = pairKeys
+	e.mutex.Unlock()
+	return
+}
+
+// pairKey get the name of a pair in the trades of kraken
+func (e *Kraken) pairKey(altname string) string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if key, ok := e.pairKeys[altname]; ok {
+		return key
+	}
+	return altname
+}
+
+// parse check the errors in the response and get its result
+func (e *Kraken) parse(method string, data []byte) (*simplejson.Json, error) {
+	json, err := simplejson.NewJson(data)
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	if errors := json.Get("error").MustArray(); len(errors) > 0 {
+		msg := fmt.Sprint(errors[0])
+		return nil, newCodeError(method, krakenErrorKinds, msg, msg)
+	}
+	return json.Get("result"), nil
+}
+
+// public send a request to the public api
+func (e *Kraken) public(method, api string, params url.Values) (*simplejson.Json, error) {
+	url := e.host + "0/public/" + api
+	if len(params) > 0 {
+		url += "?" + params.Encode()
+	}
+	resp, err := get(e.httpClient, url)
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	return e.parse(method, resp)
+}
+
+// private post a request to the private api, it is signed by HMAC-SHA512 of the path and the SHA256 of the nonce and the body,
+// the key is the base64 decoded secret
+func (e *Kraken) private(method, api string, params url.Values) (*simplejson.Json, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	nonce := fmt.Sprint(time.Now().UnixNano())
+	params.Set("nonce", nonce)
+	body := params.Encode()
+	path := "/0/private/" + api
+	secret, err := base64.StdEncoding.DecodeString(e.option.SecretKey)
+	if err != nil {
+		return nil, newError(ErrAuth, method, "the secret key is not base64 encoded")
+	}
+	hash := sha256.Sum256([]byte(nonce + body))
+	req, err := http.NewRequest("POST", strings.TrimSuffix(e.host, "/")+path, strings.NewReader(body))
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("API-Key", e.option.AccessKey)
+	req.Header.Set("API-Sign", base64.StdEncoding.EncodeToString(hmacSha512(append([]byte(path), hash[:]...), secret)))
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, wrapError(method, fmt.Errorf("[POST %s] HTTP Error Info: %v", path, err))
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, wrapError(method, fmt.Errorf("[POST %s] HTTP Status: %d", path, resp.StatusCode))
+	}
+	return e.parse(method, data)
+}
+
+// pairResult get the result of the only pair in a public response, the pair is keyed by its name instead of the altname
+func pairResult(json *simplejson.Json) *simplejson.Json {
+	for key := range json.MustMap() {
+		if key != "last" {
+			return json.Get(key)
+		}
+	}
+	return simplejson.New()
+}
+
+// GetLastError get the last error of this exchange
+func (e *Kraken) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Kraken) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange, the frozen amount is held by the open orders
+func (e *Kraken) Account() (Account, error) {
+	json, err := e.private("GetAccount", "BalanceEx", nil)
+	if err != nil {
+		return nil, err
+	}
+	account := Account{}
+	for asset := range json.MustMap() {
+		currency := krakenCurrency(asset)
+		balance := conver.Float64Must(json.GetPath(asset, "balance").Interface())
+		hold := conver.Float64Must(json.GetPath(asset, "hold_trade").Interface())
+		account[currency] += balance - hold
+		account["Frozen"+currency] += hold
+	}
+	return account, nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *Kraken) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency,
+// kraken only takes the volume in the base currency so a market buy is converted by the asks of the order book
+func (e *Kraken) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	stockType = strings.ToUpper(stockType)
+	tradeType = strings.ToUpper(tradeType)
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	logType := constant.BUY
+	switch tradeType {
+	case constant.TradeTypeBuy:
+	case constant.TradeTypeSell:
+		logType = constant.SELL
+	default:
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	volume := amount
+	if price <= 0 && tradeType == constant.TradeTypeBuy {
+		if _, volume, err = marketOrderOf("Trade", e.Ticker, e.markets, stockType, tradeType, amount); err != nil {
+			return "", err
+		}
+	}
+	params := url.Values{}
+	params.Set("pair", e.markets.symbol(stockType))
+	params.Set("type", strings.ToLower(tradeType))
+	params.Set("ordertype", "market")
+	params.Set("volume", conver.StringMust(volume))
+	if price > 0 {
+		params.Set("ordertype", "limit")
+		params.Set("price", conver.StringMust(price))
+		if opts.PostOnly {
+			params.Set("oflags", "post")
+		} else if opts.TimeInForce != "" {
+			params.Set("timeinforce", opts.TimeInForce)
+		}
+	} else {
+		price = 0.0
+	}
+	json, err := e.private("Trade", "AddOrder", params)
+	if err != nil {
+		return "", err
+	}
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return json.Get("txid").GetIndex(0).MustString(), nil
+}
+
+// Trade place an order
+func (e *Kraken) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// parseOrder convert an order of kraken to Order
+func (e *Kraken) parseOrder(id, stockType string, orderJSON *simplejson.Json) Order {
+	return Order{
+		ID:         id,
+		Price:      conver.Float64Must(orderJSON.GetPath("descr", "price").Interface()),
+		Amount:     conver.Float64Must(orderJSON.Get("vol").Interface()),
+		DealAmount: conver.Float64Must(orderJSON.Get("vol_exec").Interface()),
+		Fee:        conver.Float64Must(orderJSON.Get("fee").Interface()),
+		TradeType:  e.tradeTypeMap[orderJSON.GetPath("descr", "type").MustString()],
+		StockType:  stockType,
+	}
+}
+
+// Order get details of an order
+func (e *Kraken) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("txid", id)
+	json, err := e.private("GetOrder", "QueryOrders", params)
+	if err != nil {
+		return Order{}, err
+	}
+	orderJSON, ok := json.CheckGet(id)
+	if !ok {
+		return Order{}, newError(ErrUnknown, "GetOrder", "order(id = ", id, ") not exist")
+	}
+	return e.parseOrder(id, stockType, orderJSON), nil
+}
+
+// GetOrder get details of an order
+func (e *Kraken) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *Kraken) Orders(stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
+	}
+	json, err := e.private("GetOrders", "OpenOrders", nil)
+	if err != nil {
+		return nil, err
+	}
+	altname := e.markets.symbol(stockType)
+	orders := []Order{}
+	openJSON := json.Get("open")
+	for id := range openJSON.MustMap() {
+		orderJSON := openJSON.Get(id)
+		// 所有交易对的挂单在一起, descr 中的交易对是 altname
+		if orderJSON.GetPath("descr", "pair").MustString() != altname {
+			continue
+		}
+		orders = append(orders, e.parseOrder(id, stockType, orderJSON))
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Kraken) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the latest 50 fills, the fee is charged in the quote currency
+func (e *Kraken) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	json, err := e.private("GetTrades", "TradesHistory", nil)
+	if err != nil {
+		return nil, err
+	}
+	altname := e.markets.symbol(stockType)
+	key := e.pairKey(altname)
+	_, quote, _ := splitStockType(stockType)
+	trades := []Trade{}
+	tradesJSON := json.Get("trades")
+	for id := range tradesJSON.MustMap() {
+		tradeJSON := tradesJSON.Get(id)
+		if pair := tradeJSON.Get("pair").MustString(); pair != key && pair != altname {
+			continue
+		}
+		trades = append(trades, Trade{
+			ID:          id,
+			OrderID:     tradeJSON.Get("ordertxid").MustString(),
+			Price:       conver.Float64Must(tradeJSON.Get("price").Interface()),
+			Amount:      conver.Float64Must(tradeJSON.Get("vol").Interface()),
+			Fee:         conver.Float64Must(tradeJSON.Get("fee").Interface()),
+			FeeCurrency: quote,
+			Time:        int64(conver.Float64Must(tradeJSON.Get("time").Interface())),
+			TradeType:   e.tradeTypeMap[tradeJSON.Get("type").MustString()],
+			StockType:   stockType,
+		})
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time < trades[j].Time
+	})
+	return trades, nil
+}
+
+// GetTrades get the recent fills
+func (e *Kraken) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// MarketTrades get the latest public trades of the market, kraken returns at most 1000 trades
+func (e *Kraken) MarketTrades(stockType string, size int) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetMarketTrades", "unrecognized stockType: ", stockType)
+	}
+	params := url.Values{}
+	params.Set("pair", e.markets.symbol(stockType))
+	json, err := e.public("GetMarketTrades", "Trades", params)
+	if err != nil {
+		return nil, err
+	}
+	trades := []Trade{}
+	tradesJSON := pairResult(json)
+	for i := 0; i < len(tradesJSON.MustArray()); i++ {
+		tradeJSON := tradesJSON.GetIndex(i)
+		trades = append(trades, Trade{
+			ID:        fmt.Sprint(tradeJSON.GetIndex(6).Interface()),
+			Price:     conver.Float64Must(tradeJSON.GetIndex(0).Interface()),
+			Amount:    conver.Float64Must(tradeJSON.GetIndex(1).Interface()),
+			Time:      int64(conver.Float64Must(tradeJSON.GetIndex(2).Interface())),
+			TradeType: e.tradeTypeMap[tradeJSON.GetIndex(3).MustString()],
+			StockType: stockType,
+		})
+	}
+	if size > 0 && len(trades) > size {
+		trades = trades[len(trades)-size:]
+	}
+	return trades, nil
+}
+
+// Cancel cancel an order
+func (e *Kraken) Cancel(order Order) error {
+	params := url.Values{}
+	params.Set("txid", order.ID)
+	if _, err := e.private("CancelOrder", "CancelOrder", params); err != nil {
+		return err
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Kraken) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
+	return true
+}
+
+// Ticker get market ticker & depth
+func (e *Kraken) Ticker(stockType string, size int) (ticker Ticker, err error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
+		return
+	}
+	if size <= 0 {
+		size = 20
+	}
+	params := url.Values{}
+	params.Set("pair", e.markets.symbol(stockType))
+	params.Set("count", fmt.Sprint(size))
+	json, err := e.public("GetTicker", "Depth", params)
+	if err != nil {
+		return
+	}
+	book := pairResult(json)
+	for i, side := range []string{"bids", "asks"} {
+		levels := book.Get(side)
+		for j := 0; j < len(levels.MustArray()); j++ {
+			level := OrderBook{
+				Price:  conver.Float64Must(levels.GetIndex(j).GetIndex(0).Interface()),
+				Amount: conver.Float64Must(levels.GetIndex(j).GetIndex(1).Interface()),
+			}
+			if i == 0 {
+				ticker.Bids = append(ticker.Bids, level)
+			} else {
+				ticker.Asks = append(ticker.Asks, level)
+			}
+		}
+	}
+	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
+		return
+	}
+	ticker.Buy = ticker.Bids[0].Price
+	ticker.Sell = ticker.Asks[0].Price
+	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
+	return
+}
+
+// GetTicker get market ticker & depth
+func (e *Kraken) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data, kraken only returns the latest 720 records of a period
+func (e *Kraken) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	fetch := e.store.missing(stockType, period, size)
+	params := url.Values{}
+	params.Set("pair", e.markets.symbol(stockType))
+	params.Set("interval", e.recordsPeriodMap[period])
+	params.Set("since", fmt.Sprint(time.Now().Unix()-int64(fetch+1)*periodLength(period)))
+	json, err := e.public("GetRecords", "OHLC", params)
+	if err != nil {
+		return nil, err
+	}
+	recordsNew := []Record{}
+	recordsJSON := pairResult(json)
+	for i := 0; i < len(recordsJSON.MustArray()); i++ {
+		recordJSON := recordsJSON.GetIndex(i)
+		recordsNew = append(recordsNew, Record{
+			Time:   conver.Int64Must(recordJSON.GetIndex(0).Interface()),
+			Open:   conver.Float64Must(recordJSON.GetIndex(1).Interface()),
+			High:   conver.Float64Must(recordJSON.GetIndex(2).Interface()),
+			Low:    conver.Float64Must(recordJSON.GetIndex(3).Interface()),
+			Close:  conver.Float64Must(recordJSON.GetIndex(4).Interface()),
+			Volume: conver.Float64Must(recordJSON.GetIndex(6).Interface()),
+		})
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// GetRecords get candlestick data
+func (e *Kraken) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+// defaultRate the request weight per second of the exchanges not in rateLimits
+var defaultRate = 10.0
+
+// rateLimits the request weight per second of the exchanges, it can be changed by E.SetLimit()
+var rateLimits = map[string]float64{
+	constant.Binance:       20.0, //1200 每分钟
+	constant.BinanceFuture: 20.0, //2400 每分钟
+	constant.Huobi:         10.0, //100 每10秒
+	constant.Okex:          10.0, //20 每2秒
+	constant.Poloniex:      6.0,
+	constant.Kraken:        1.0, //计数器每秒减少 0.33, 最多积累 15
+}
+
+// requestWeights the weights of the heavy endpoints, the other requests weigh 1
+var requestWeights = map[string]map[string]float64{
+	constant.Binance: {
+		"/api/v3/account":   5.0,
+		"/api/v3/allOrders": 5.0,
+		"/api/v3/myTrades":  5.0,
+	},
+	constant.BinanceFuture: {
+		"/fapi/v2/account":      5.0,
+		"/fapi/v2/positionRisk": 5.0,
+		"/fapi/v1/userTrades":   5.0,
+	},
+}
+
+// RateLimit struct, the request budget shared by all the traders of the same exchange and api key
+type RateLimit struct {
+	Rate      float64 //每秒恢复的请求权重, 也是最多可以积累的权重
+	Remaining float64 //当前剩余的请求权重
+}
+
+// rateLimiter is a token bucket, the requests over the budget wait until it is paid back
+type rateLimiter struct {
+	mutex   sync.Mutex
+	rate    float64            //每秒恢复的请求权重
+	tokens  float64            //剩余的请求权重, 为负数时表示预支了的权重
+	updated time.Time          //上次恢复权重的时间
+	weights map[string]float64 //按请求路径设置的权重
+}
+
+// limiters the rate limiters keyed by exchange type and api key
+var limiters = struct {
+	sync.Mutex
+	m map[string]*rateLimiter
+}{m: make(map[string]*rateLimiter)}
+
+// limiterOf get the rate limiter shared by the exchanges of the same type and api key
+func limiterOf(opt Option) *rateLimiter {
+	limiters.Lock()
+	defer limiters.Unlock()
+	key := opt.Type + "/" + opt.AccessKey
+	if l, ok := limiters.m[key]; ok {
+		return l
+	}
+	rate, ok := rateLimits[opt.Type]
+	if !ok {
+		rate = defaultRate
+	}
+	l := &rateLimiter{
+		rate:    rate,
+		tokens:  rate,
+		updated: time.Now(),
+		weights: requestWeights[opt.Type],
+	}
+	limiters.m[key] = l
+	return l
+}
+
+// refill recover the tokens since the last update, the caller must hold the mutex
+func (l *rateLimiter) refill() {
+	now := time.Now()
+	l.tokens += now.Sub(l.updated).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.updated = now
+}
+
+// wait take weight from the bucket and sleep until it is paid back if the bucket is overdrawn
+func (l *rateLimiter) wait(weight float64) {
+	l.mutex.Lock()
+	l.refill()
+	l.tokens -= weight
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mutex.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// waitFor wait on the weight of a request path
+func (l *rateLimiter) waitFor(path string) {
+	weight := 1.0
+	for prefix, w := range l.weights {
+		if strings.HasPrefix(path, prefix) {
+			weight = w
+			break
+		}
+	}
+	l.wait(weight)
+}
+
+// setRate change the request weight per second
+func (l *rateLimiter) setRate(rate float64) float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if rate > 0 {
+		l.refill()
+		l.rate = rate
+	}
+	return l.rate
+}
+
+// status get the rate and the remaining weight
+func (l *rateLimiter) status() RateLimit {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.refill()
+	remaining := l.tokens
+	if remaining < 0 {
+		remaining = 0
+	}
+	return RateLimit{Rate: l.rate, Remaining: remaining}
+}
+
+// limitTransport wait on the rate limiter before sending every request
+type limitTransport struct {
+	limiter *rateLimiter
+	next    http.RoundTripper
+}
+
+// RoundTrip implement http.RoundTripper
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.limiter.waitFor(req.URL.Path)
+	return t.next.RoundTrip(req)
+}
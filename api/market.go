@@ -0,0 +1,167 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// marketsTTL the seconds before the markets of an exchange are reloaded from its exchange-info endpoint
+var marketsTTL int64 = 3600
+
+// marketsRetry the seconds before a failed loading of the markets is retried
+var marketsRetry int64 = 30
+
+// Market struct, the trading rules of a pair
+type Market struct {
+	StockType       string  //货币类型, 如 BTC/USDT
+	Symbol          string  //交易所中的交易对名称, 如 BTCUSDT
+	BaseCurrency    string  //基础币种, 如 BTC
+	QuoteCurrency   string  //计价币种, 如 USDT
+	PricePrecision  int     //价格精度位数
+	AmountPrecision int     //数量精度位数
+	TickSize        float64 //价格的最小变动单位
+	LotSize         float64 //数量的最小变动单位
+	MinAmount       float64 //最小交易数量
+	MinNotional     float64 //最小交易金额
+}
+
+// marketCache keeps the markets of an exchange, the hard-coded pairs are used until the markets are loaded
+type marketCache struct {
+	mutex   sync.Mutex
+	markets map[string]Market
+	load    func() ([]Market, error)
+	loaded  int64         //上次加载成功的时间, unix时间戳
+	failed  int64         //上次加载失败的时间, unix时间戳
+	loading chan struct{} //正在加载时不为 nil, 加载结束时关闭
+}
+
+// newMarketCache create a market cache with the hard-coded pairs, load get the markets from the exchange
+func newMarketCache(stockTypeMap map[string]string, minAmountMap map[string]float64, load func() ([]Market, error)) *marketCache {
+	c := &marketCache{
+		markets: make(map[string]Market),
+		load:    load,
+	}
+	for stockType, symbol := range stockTypeMap {
+		base, quote, _ := splitStockType(stockType)
+		c.markets[stockType] = Market{
+			StockType:     stockType,
+			Symbol:        symbol,
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			MinAmount:     minAmountMap[stockType],
+		}
+	}
+	return c
+}
+
+// refresh reload the markets if they are expired, the loading runs without the mutex and only one at a time,
+// the other callers wait for it only before the markets are loaded for the first time,
+// the old markets are kept if the loading fails and it is retried after marketsRetry seconds
+func (c *marketCache) refresh() {
+	c.mutex.Lock()
+	now := time.Now().Unix()
+	if c.load == nil || now-c.loaded < marketsTTL || now-c.failed < marketsRetry {
+		c.mutex.Unlock()
+		return
+	}
+	if loading := c.loading; loading != nil {
+		first := c.loaded == 0
+		c.mutex.Unlock()
+		if first {
+			<-loading
+		}
+		return
+	}
+	loading := make(chan struct{})
+	c.loading = loading
+	c.mutex.Unlock()
+	markets, err := c.load()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.loading = nil
+	close(loading)
+	if err != nil || len(markets) == 0 {
+		c.failed = time.Now().Unix()
+		return
+	}
+	c.loaded, c.failed = time.Now().Unix(), 0
+	c.markets = make(map[string]Market)
+	for _, m := range markets {
+		c.markets[m.StockType] = m
+	}
+}
+
+// get get the market of stockType
+func (c *marketCache) get(stockType string) (Market, bool) {
+	c.refresh()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	m, ok := c.markets[strings.ToUpper(stockType)]
+	return m, ok
+}
+
+// has check if the exchange supports stockType
+func (c *marketCache) has(stockType string) bool {
+	_, ok := c.get(stockType)
+	return ok
+}
+
+// symbol get the symbol of stockType in the exchange
+func (c *marketCache) symbol(stockType string) string {
+	m, _ := c.get(stockType)
+	return m.Symbol
+}
+
+// stockTypeOf get the stockType of a symbol in the exchange, it is empty if the symbol is unknown
+func (c *marketCache) stockTypeOf(symbol string) string {
+	c.refresh()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for stockType, m := range c.markets {
+		if strings.EqualFold(m.Symbol, symbol) {
+			return stockType
+		}
+	}
+	return ""
+}
+
+// minAmount get the min trade amount of stockType
+func (c *marketCache) minAmount(stockType string) float64 {
+	m, _ := c.get(stockType)
+	return m.MinAmount
+}
+
+// list get all the markets sorted by stockType
+func (c *marketCache) list() []Market {
+	c.refresh()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	markets := []Market{}
+	for _, m := range c.markets {
+		markets = append(markets, m)
+	}
+	sort.Slice(markets, func(i, j int) bool {
+		return markets[i].StockType < markets[j].StockType
+	})
+	return markets
+}
+
+// precisionOf get the decimal places of a step size, like 0.001 => 3
+func precisionOf(step float64) int {
+	if step <= 0 {
+		return 0
+	}
+	precision := int(math.Round(-math.Log10(step)))
+	if precision < 0 {
+		return 0
+	}
+	return precision
+}
+
+// stepOf get the step size of the decimal places, like 3 => 0.001
+func stepOf(precision int) float64 {
+	return math.Pow10(-precision)
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
+)
+
+// okexErrorKinds the error codes of the okex v5 api
+var okexErrorKinds = map[string]string{
+	"50011": ErrRateLimited,
+	"50061": ErrRateLimited,
+	"50100": ErrAuth,
+	"50101": ErrAuth,
+	"50102": ErrAuth,
+	"50103": ErrAuth,
+	"50104": ErrAuth,
+	"50105": ErrAuth,
+	"50111": ErrAuth,
+	"50113": ErrAuth,
+	"51001": ErrInvalidSymbol,
+	"51008": ErrInsufficientBalance,
+	"51119": ErrInsufficientBalance,
+	"51131": ErrInsufficientBalance,
+}
+
+// okexClient the client of the okex v5 rest api shared by okex and okex.future,
+// the private requests are signed by HMAC-SHA256 with the passphrase of the api key
+type okexClient struct {
+	host       string //API请求地址, 要带最后的/
+	accessKey  string
+	secretKey  string
+	passphrase string
+	httpClient *http.Client
+}
+
+// newOkexClient create an okex v5 api client
+func newOkexClient(opt Option) *okexClient {
+	return &okexClient{
+		host:       baseURLOf(opt, "https://www.okx.com/"),
+		accessKey:  opt.AccessKey,
+		secretKey:  opt.SecretKey,
+		passphrase: opt.Passphrase,
+		httpClient: newHTTPClient(opt),
+	}
+}
+
+// request send a request and get the data of the response, the params of GET are sent in the query and the others in the json body,
+// the error codes of okex are converted to the error kinds
+func (c *okexClient) request(method, httpMethod, path string, params map[string]string, signed bool) (*simplejson.Json, error) {
+	query, body := "", ""
+	if httpMethod == "GET" {
+		values := url.Values{}
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		if len(values) > 0 {
+			query = "?" + values.Encode()
+		}
+	} else {
+		if params == nil {
+			params = map[string]string{}
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, wrapError(method, err)
+		}
+		body = string(data)
+	}
+	req, err := http.NewRequest(httpMethod, c.host+path+query, strings.NewReader(body))
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signed {
+		// 签名的内容是 timestamp + method + requestPath + body
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		req.Header.Set("OK-ACCESS-KEY", c.accessKey)
+		req.Header.Set("OK-ACCESS-SIGN", signSha256Base64(timestamp+httpMethod+req.URL.RequestURI()+body, c.secretKey))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", c.passphrase)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, wrapError(method, fmt.Errorf("[%s %s] HTTP Error Info: %v", httpMethod, path, err))
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, wrapError(method, err)
+	}
+	res, err := simplejson.NewJson(data)
+	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, wrapError(method, fmt.Errorf("[%s %s] HTTP Status: %d", httpMethod, path, resp.StatusCode))
+		}
+		return nil, wrapError(method, err)
+	}
+	if code := res.Get("code").MustString(); code != "0" {
+		// 下单和撤单的错误码在 data 的 sCode 中
+		item := res.Get("data").GetIndex(0)
+		if sCode := item.Get("sCode").MustString(); sCode != "" && sCode != "0" {
+			return nil, newCodeError(method, okexErrorKinds, sCode, item.Get("sMsg").MustString())
+		}
+		return nil, newCodeError(method, okexErrorKinds, code, res.Get("msg").MustString())
+	}
+	return res.Get("data"), nil
+}
+
+// okexOrderType get the ordType of an order, like market, limit, post_only, ioc and fok
+func okexOrderType(price float64, opts OrderOptions) string {
+	switch {
+	case price <= 0:
+		return "market"
+	case opts.PostOnly:
+		return "post_only"
+	case opts.TimeInForce == constant.TimeInForceIOC:
+		return "ioc"
+	case opts.TimeInForce == constant.TimeInForceFOK:
+		return "fok"
+	}
+	return "limit"
+}
+
+// instruments get the live instruments of an instType, like SPOT, SWAP and FUTURES
+func (c *okexClient) instruments(instType string) (*simplejson.Json, error) {
+	return c.request("GetMarkets", "GET", "api/v5/public/instruments", map[string]string{"instType": instType}, false)
+}
+
+// balance get the available and the frozen balances of all the currencies in the trading account
+func (c *okexClient) balance() (Account, error) {
+	data, err := c.request("GetAccount", "GET", "api/v5/account/balance", nil, true)
+	if err != nil {
+		return nil, err
+	}
+	account := Account{}
+	details := data.GetIndex(0).Get("details")
+	for i := 0; i < len(details.MustArray()); i++ {
+		detail := details.GetIndex(i)
+		currency := strings.ToUpper(detail.Get("ccy").MustString())
+		account[currency] = conver.Float64Must(detail.Get("availBal").Interface())
+		account["Frozen"+currency] = conver.Float64Must(detail.Get("frozenBal").Interface())
+	}
+	return account, nil
+}
+
+// books get the order book of an instrument, okex returns at most 400 levels
+func (c *okexClient) books(instID string, size int) (ticker Ticker, err error) {
+	if size > 400 {
+		size = 400
+	}
+	data, err := c.request("GetTicker", "GET", "api/v5/market/books", map[string]string{"instId": instID, "sz": fmt.Sprint(size)}, false)
+	if err != nil {
+		return
+	}
+	book := data.GetIndex(0)
+	for i, side := range []string{"bids", "asks"} {
+		levels := book.Get(side)
+		for j := 0; j < len(levels.MustArray()); j++ {
+			level := OrderBook{
+				Price:  conver.Float64Must(levels.GetIndex(j).GetIndex(0).Interface()),
+				Amount: conver.Float64Must(levels.GetIndex(j).GetIndex(1).Interface()),
+			}
+			if i == 0 {
+				ticker.Bids = append(ticker.Bids, level)
+			} else {
+				ticker.Asks = append(ticker.Asks, level)
+			}
+		}
+	}
+	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
+		return
+	}
+	ticker.Buy = ticker.Bids[0].Price
+	ticker.Sell = ticker.Asks[0].Price
+	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
+	return
+}
+
+// candles get the latest size records of a bar, or the records since a unix timestamp if since > 0,
+// okex returns the newest records first and pages the history backwards, so the records after since are asked by the end time
+func (c *okexClient) candles(instID, bar, period string, since int64, size int) ([]Record, error) {
+	path := "api/v5/market/candles"
+	limit := 300
+	params := map[string]string{"instId": instID, "bar": bar}
+	if since > 0 {
+		path = "api/v5/market/history-candles"
+		limit = 100
+	}
+	if size <= 0 || size > limit {
+		size = limit
+	}
+	params["limit"] = fmt.Sprint(size)
+	if since > 0 {
+		params["after"] = fmt.Sprint((since + int64(size)*periodLength(period)) * 1000)
+	}
+	data, err := c.request("GetRecords", "GET", path, params, false)
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	for i := len(data.MustArray()) - 1; i >= 0; i-- {
+		candle := data.GetIndex(i)
+		record := Record{
+			Time:   conver.Int64Must(candle.GetIndex(0).Interface()) / 1000,
+			Open:   conver.Float64Must(candle.GetIndex(1).Interface()),
+			High:   conver.Float64Must(candle.GetIndex(2).Interface()),
+			Low:    conver.Float64Must(candle.GetIndex(3).Interface()),
+			Close:  conver.Float64Must(candle.GetIndex(4).Interface()),
+			Volume: conver.Float64Must(candle.GetIndex(5).Interface()),
+		}
+		if record.Time >= since {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// marketTrades get the latest public trades of an instrument in time order, okex returns at most 500 trades
+func (c *okexClient) marketTrades(instID, stockType string, size int) ([]Trade, error) {
+	if size <= 0 || size > 500 {
+		size = 500
+	}
+	data, err := c.request("GetMarketTrades", "GET", "api/v5/market/trades", map[string]string{"instId": instID, "limit": fmt.Sprint(size)}, false)
+	if err != nil {
+		return nil, err
+	}
+	trades := []Trade{}
+	for i := 0; i < len(data.MustArray()); i++ {
+		tradeJSON := data.GetIndex(i)
+		trades = append(trades, Trade{
+			ID:        tradeJSON.Get("tradeId").MustString(),
+			Price:     conver.Float64Must(tradeJSON.Get("px").Interface()),
+			Amount:    conver.Float64Must(tradeJSON.Get("sz").Interface()),
+			Time:      conver.Int64Must(tradeJSON.Get("ts").Interface()) / 1000,
+			TradeType: strings.ToUpper(tradeJSON.Get("side").MustString()),
+			StockType: stockType,
+		})
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time < trades[j].Time
+	})
+	return trades, nil
+}
+
+// order get an order of an instrument
+func (c *okexClient) order(instID, id string) (*simplejson.Json, error) {
+	data, err := c.request("GetOrder", "GET", "api/v5/trade/order", map[string]string{"instId": instID, "ordId": id}, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.MustArray()) < 1 {
+		return nil, newError(ErrUnknown, "GetOrder", "order(id = ", id, ") not exist")
+	}
+	return data.GetIndex(0), nil
+}
+
+// pendingOrders get the unfilled orders of an instrument
+func (c *okexClient) pendingOrders(instType, instID string) (*simplejson.Json, error) {
+	return c.request("GetOrders", "GET", "api/v5/trade/orders-pending", map[string]string{"instType": instType, "instId": instID}, true)
+}
+
+// fills get the recent fills of an instrument in the last 3 days
+func (c *okexClient) fills(instType, instID string) (*simplejson.Json, error) {
+	return c.request("GetTrades", "GET", "api/v5/trade/fills", map[string]string{"instType": instType, "instId": instID, "limit": "100"}, true)
+}
+
+// ordersHistory get the finished orders of an instrument in the last 7 days
+func (c *okexClient) ordersHistory(instType, instID string) (*simplejson.Json, error) {
+	return c.request("GetTrades", "GET", "api/v5/trade/orders-history", map[string]string{"instType": instType, "instId": instID, "limit": "100"}, true)
+}
+
+// placeOrder place an order and get its id
+func (c *okexClient) placeOrder(params map[string]string) (string, error) {
+	data, err := c.request("Trade", "POST", "api/v5/trade/order", params, true)
+	if err != nil {
+		return "", err
+	}
+	return data.GetIndex(0).Get("ordId").MustString(), nil
+}
+
+// cancelOrder cancel an order of an instrument
+func (c *okexClient) cancelOrder(instID, id string) error {
+	_, err := c.request("CancelOrder", "POST", "api/v5/trade/cancel-order", map[string]string{"instId": instID, "ordId": id}, true)
+	return err
+}
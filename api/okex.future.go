@@ -2,57 +2,81 @@ package api
 
 import (
 	"fmt"
-	"sort"
+	"math"
 	"strings"
-	"time"
+	"sync"
 
-	"github.com/bitly/go-simplejson"
-	"github.com/miaolz123/conver"
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
+	"github.com/bitly/go-simplejson"
+	"github.com/miaolz123/conver"
 )
 
-// OkexFuture the exchange struct of okex.com future
+// OkexFuture the exchange struct of okex.com future, the stockTypes are like BTC.WEEK/USD for the dated futures
+// and BTC.SWAP/USDT for the perpetual swaps, the amount is in contracts
 type OkexFuture struct {
-	stockTypeMap        map[string][2]string
-	tradeTypeMap        map[string]string
-	tradeTypeAntiMap    map[int]string
-	tradeTypeLogMap     map[string]string
-	contractTypeAntiMap map[string]string
-	leverageMap         map[string]string
-	recordsPeriodMap    map[string]string
-	records             map[string][]Record
-	host                string
-	logger              model.Logger
-	option              Option
-
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+	client           *okexClient
+	stockTypeMap     map[string]string
+	tradeTypeMap     map[string][2]string //交易类型对应的 side 和 posSide
+	tradeTypeLogMap  map[string]string
+	contractTypeMap  map[string]string //交割合约的 alias 对应的合约类型
+	leverageMap      map[string]string //兼容旧策略时, 可以作为杠杆倍数的第一个 Message
+	recordsPeriodMap map[string]string
+	minAmountMap     map[string]float64
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
+	logger           model.Logger
+	option           Option
+	lastError        *Error
+
+	mutex      sync.Mutex
+	leverage   string            //下单使用的杠杆倍数
+	marginMode string            //保证金模式
+	applied    map[string]string //合约已经设置的杠杆倍数和保证金模式
+	hedgeMode  *bool             //是否是开平仓模式, 第一次下单时获取
+
+	limiter *rateLimiter
 }
 
+// okexFuturePeriods the periods of the records the exchange supports
+var okexFuturePeriods = map[string]string{
+	"M":   "1m",
+	"M3":  "3m",
+	"M5":  "5m",
+	"M15": "15m",
+	"M30": "30m",
+	"H":   "1H",
+	"H2":  "2H",
+	"H4":  "4H",
+	"H6":  "6Hutc",
+	"H12": "12Hutc",
+	"D":   "1Dutc",
+	"D3":  "3Dutc",
+	"W":   "1Wutc",
+}
+
+// okexFutureOrderTypes the order types the exchange supports
+var okexFutureOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
 // NewOkexFuture create an exchange struct of okex.com
 func NewOkexFuture(opt Option) Exchange {
-	return &OkexFuture{
-		stockTypeMap: map[string][2]string{
-			"BTC.WEEK/USD":   {"btc_usd", "this_week"},
-			"BTC.WEEK2/USD":  {"btc_usd", "next_week"},
-			"BTC.MONTH3/USD": {"btc_usd", "quarter"},
-			"LTC.WEEK/USD":   {"ltc_usd", "this_week"},
-			"LTC.WEEK2/USD":  {"ltc_usd", "next_week"},
-			"LTC.MONTH3/USD": {"ltc_usd", "quarter"},
+	e := &OkexFuture{
+		client: newOkexClient(opt),
+		// 交割合约的 instId 每周都会变化, 只能从交易所加载
+		stockTypeMap: map[string]string{
+			"BTC.SWAP/USD":  "BTC-USD-SWAP",
+			"BTC.SWAP/USDT": "BTC-USDT-SWAP",
+			"ETH.SWAP/USD":  "ETH-USD-SWAP",
+			"ETH.SWAP/USDT": "ETH-USDT-SWAP",
+			"LTC.SWAP/USD":  "LTC-USD-SWAP",
+			"LTC.SWAP/USDT": "LTC-USDT-SWAP",
 		},
-		tradeTypeMap: map[string]string{
-			constant.TradeTypeLong:       "1",
-			constant.TradeTypeShort:      "2",
-			constant.TradeTypeLongClose:  "3",
-			constant.TradeTypeShortClose: "4",
-		},
-		tradeTypeAntiMap: map[int]string{
-			1: constant.TradeTypeLong,
-			2: constant.TradeTypeShort,
-			3: constant.TradeTypeLongClose,
-			4: constant.TradeTypeShortClose,
+		tradeTypeMap: map[string][2]string{
+			constant.TradeTypeLong:       {"buy", "long"},
+			constant.TradeTypeShort:      {"sell", "short"},
+			constant.TradeTypeLongClose:  {"sell", "long"},
+			constant.TradeTypeShortClose: {"buy", "short"},
 		},
 		tradeTypeLogMap: map[string]string{
 			constant.TradeTypeLong:       constant.LONG,
@@ -60,38 +84,39 @@ func NewOkexFuture(opt Option) Exchange {
 			constant.TradeTypeLongClose:  constant.LONGCLOSE,
 			constant.TradeTypeShortClose: constant.SHORTCLOSE,
 		},
-		contractTypeAntiMap: map[string]string{
-			"this_week": "WEEK",
-			"next_week": "WEEK2",
-			"quarter":   "MONTH3",
+		contractTypeMap: map[string]string{
+			"this_week":    "WEEK",
+			"next_week":    "WEEK2",
+			"quarter":      "MONTH3",
+			"next_quarter": "MONTH6",
 		},
 		leverageMap: map[string]string{
 			"10": "10",
 			"20": "20",
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "1min",
-			"M3":  "3min",
-			"M5":  "5min",
-			"M15": "15min",
-			"M30": "30min",
-			"H":   "1hour",
-			"H2":  "2hour",
-			"H4":  "4hour",
-			"H6":  "6hour",
-			"H12": "12hour",
-			"D":   "1day",
-			"D3":  "3day",
-			"W":   "1week",
+		// 6小时以上的K线使用 UTC 时间对齐
+		recordsPeriodMap: okexFuturePeriods,
+		minAmountMap: map[string]float64{
+			"BTC.SWAP/USD":  1.0,
+			"BTC.SWAP/USDT": 1.0,
+			"ETH.SWAP/USD":  1.0,
+			"ETH.SWAP/USDT": 1.0,
+			"LTC.SWAP/USD":  1.0,
+			"LTC.SWAP/USDT": 1.0,
 		},
-		records: make(map[string][]Record),
-		host:    "https://www.okex.com/api/v1/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: okexFutureOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
+
+		leverage:   "10",
+		marginMode: constant.MarginModeCrossed,
+		applied:    make(map[string]string),
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter: limiterOf(opt),
 	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -109,419 +134,582 @@ func (e *OkexFuture) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *OkexFuture) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *OkexFuture) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *OkexFuture) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *OkexFuture) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *OkexFuture) GetMinAmount(stock string) float64 {
-	return 1.0
+	return e.markets.minAmount(stock)
 }
 
-func (e *OkexFuture) getAuthJSON(url string, params []string) (json *simplejson.Json, err error) {
-	e.lastTimes++
-	params = append(params, "api_key="+e.option.AccessKey)
-	sort.Strings(params)
-	params = append(params, "secret_key="+e.option.SecretKey)
-	params = append(params, "sign="+strings.ToUpper(signMd5(params)))
-	resp, err := post(url, params)
-	if err != nil {
-		return
+// GetMarkets get all the contracts of this exchange, the amount is in contracts
+func (e *OkexFuture) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the dated futures and the perpetual swaps of okex.com
+func (e *OkexFuture) loadMarkets() (markets []Market, err error) {
+	for _, instType := range []string{"FUTURES", "SWAP"} {
+		data, err := e.client.instruments(instType)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(data.MustArray()); i++ {
+			instrumentJSON := data.GetIndex(i)
+			if instrumentJSON.Get("state").MustString() != "live" {
+				continue
+			}
+			currencies := strings.Split(instrumentJSON.Get("uly").MustString(), "-")
+			if len(currencies) != 2 {
+				continue
+			}
+			contractType := "SWAP"
+			if instType == "FUTURES" {
+				if contractType = e.contractTypeMap[instrumentJSON.Get("alias").MustString()]; contractType == "" {
+					continue
+				}
+			}
+			tickSize := conver.Float64Must(instrumentJSON.Get("tickSz").Interface())
+			lotSize := conver.Float64Must(instrumentJSON.Get("lotSz").Interface())
+			markets = append(markets, Market{
+				StockType:       currencies[0] + "." + contractType + "/" + currencies[1],
+				Symbol:          instrumentJSON.Get("instId").MustString(),
+				BaseCurrency:    currencies[0],
+				QuoteCurrency:   currencies[1],
+				PricePrecision:  precisionOf(tickSize),
+				AmountPrecision: precisionOf(lotSize),
+				TickSize:        tickSize,
+				LotSize:         lotSize,
+				MinAmount:       conver.Float64Must(instrumentJSON.Get("minSz").Interface()),
+			})
+		}
 	}
-	return simplejson.NewJson(resp)
+	return
+}
+
+// contractTypeOf get the contract type of stockType, like WEEK and SWAP
+func contractTypeOf(stockType string) string {
+	contract := strings.Split(stockType, "/")[0]
+	if i := strings.Index(contract, "."); i >= 0 {
+		return contract[i+1:]
+	}
+	return ""
+}
+
+// instTypeOf get the instType of stockType in the okex api
+func instTypeOf(stockType string) string {
+	if contractTypeOf(stockType) == "SWAP" {
+		return "SWAP"
+	}
+	return "FUTURES"
+}
+
+// GetLastError get the last error of this exchange
+func (e *OkexFuture) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *OkexFuture) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *OkexFuture) Account() (Account, error) {
+	return e.client.balance()
 }
 
 // GetAccount get the account detail of this exchange
 func (e *OkexFuture) GetAccount() interface{} {
-	json, err := e.getAuthJSON(e.host+"future_userinfo.do", []string{})
+	account, err := e.Account()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return e.fail("GetAccount", err)
 	}
-	if result := json.Get("result").MustBool(); !result {
-		err = fmt.Errorf("GetAccount() error, the error number is %v", json.Get("error_code").MustInt())
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+	return account
+}
+
+// SetLeverage set the leverage of the later orders, it is sent to okex before the next order of every contract
+func (e *OkexFuture) SetLeverage(leverage interface{}) bool {
+	l := conver.IntMust(leverage)
+	if l < 1 || l > 125 {
+		return e.fail("SetLeverage", newError(ErrNotSupported, "SetLeverage", "unrecognized leverage: ", leverage))
+	}
+	e.mutex.Lock()
+	e.leverage = fmt.Sprint(l)
+	e.mutex.Unlock()
+	return true
+}
+
+// SetMarginMode set the margin mode of the later orders
+func (e *OkexFuture) SetMarginMode(mode string) bool {
+	switch mode {
+	case constant.MarginModeCrossed, constant.MarginModeFixed:
+	default:
+		return e.fail("SetMarginMode", newError(ErrNotSupported, "SetMarginMode", "unrecognized margin mode: ", mode))
+	}
+	e.mutex.Lock()
+	e.marginMode = mode
+	e.mutex.Unlock()
+	return true
+}
+
+// tdMode get the trade mode of the current margin mode
+func (e *OkexFuture) tdMode() string {
+	if e.marginMode == constant.MarginModeFixed {
+		return "isolated"
 	}
-	return map[string]float64{
-		"BTC":       conver.Float64Must(json.GetPath("info", "btc", "account_rights").Interface()),
-		"FrozenBTC": 0.0,
-		"LTC":       conver.Float64Must(json.GetPath("info", "ltc", "account_rights").Interface()),
-		"FrozenLTC": 0.0,
+	return "cross"
+}
+
+// apply send the leverage of a contract to okex if it is changed
+func (e *OkexFuture) apply(instID, leverage string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	setting := leverage + "/" + e.tdMode()
+	if e.applied[instID] == setting {
+		return nil
+	}
+	params := map[string]string{
+		"instId":  instID,
+		"lever":   leverage,
+		"mgnMode": e.tdMode(),
+	}
+	if _, err := e.client.request("SetLeverage", "POST", "api/v5/account/set-leverage", params, true); err != nil {
+		return err
+	}
+	e.applied[instID] = setting
+	return nil
+}
+
+// isHedgeMode check if the account holds the long and short positions separately
+func (e *OkexFuture) isHedgeMode() (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.hedgeMode == nil {
+		data, err := e.client.request("Trade", "GET", "api/v5/account/config", nil, true)
+		if err != nil {
+			return false, err
+		}
+		hedge := data.GetIndex(0).Get("posMode").MustString() == "long_short_mode"
+		e.hedgeMode = &hedge
 	}
+	return *e.hedgeMode, nil
 }
 
-// GetPositions get the positions detail of this exchange
-func (e *OkexFuture) GetPositions(stockType string) interface{} {
+// Positions get the positions detail of this exchange
+func (e *OkexFuture) Positions(stockType string) ([]Position, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetPositions() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetPositions", "unrecognized stockType: ", stockType)
 	}
-	positions := []Position{}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType][0],
-		"contract_type=" + e.stockTypeMap[stockType][1],
+	params := map[string]string{
+		"instType": instTypeOf(stockType),
+		"instId":   e.markets.symbol(stockType),
 	}
-	json, err := e.getAuthJSON(e.host+"future_position.do", params)
+	data, err := e.client.request("GetPositions", "GET", "api/v5/account/positions", params, true)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetPositions() error, ", err)
-		return false
-	}
-	if result := json.Get("result").MustBool(); !result {
-		err = fmt.Errorf("GetPositions() error, the error number is %v", json.Get("error_code").MustInt())
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetPositions() error, ", err)
-		return false
-	}
-	positionsJSON := json.Get("holding")
-	count := len(positionsJSON.MustArray())
-	for i := 0; i < count; i++ {
-		positionJSON := positionsJSON.GetIndex(i)
-		side := "sell"
-		tradeType := constant.TradeTypeShort
-		amount := conver.Float64Must(positionJSON.Get("buy_amount").Interface())
-		if amount > 0 {
-			side = "buy"
-			tradeType = constant.TradeTypeLong
-		} else if amount = conver.Float64Must(positionJSON.Get("sell_amount").Interface()); amount == 0.0 {
+		return nil, err
+	}
+	positions := []Position{}
+	for i := 0; i < len(data.MustArray()); i++ {
+		positionJSON := data.GetIndex(i)
+		amount := conver.Float64Must(positionJSON.Get("pos").Interface())
+		if amount == 0.0 {
 			continue
 		}
-		positions = append(positions, Position{
-			Price:         conver.Float64Must(positionJSON.Get(side + "_price_avg").Interface()),
-			Leverage:      conver.IntMust(positionJSON.Get("lever_rate").Interface()),
-			Amount:        conver.Float64Must(positionJSON.Get(side + "_amount").Interface()),
-			ConfirmAmount: conver.Float64Must(positionJSON.Get(side + "_available").Interface()),
-			FrozenAmount:  0.0,
-			Profit:        conver.Float64Must(positionJSON.Get(side + "_profit_real").Interface()),
-			ContractType:  e.contractTypeAntiMap[positionJSON.Get("contract_type").MustString()],
-			TradeType:     tradeType,
-			StockType:     stockType,
-		})
+		// 买卖模式下 posSide 为 net, 用数量的正负区分多仓和空仓
+		tradeType := constant.TradeTypeLong
+		if positionJSON.Get("posSide").MustString() == "short" || amount < 0 {
+			tradeType = constant.TradeTypeShort
+		}
+		position := Position{
+			Price:            conver.Float64Must(positionJSON.Get("avgPx").Interface()),
+			Leverage:         conver.IntMust(positionJSON.Get("lever").Interface()),
+			Amount:           math.Abs(amount),
+			ConfirmAmount:    math.Abs(conver.Float64Must(positionJSON.Get("availPos").Interface())),
+			Profit:           conver.Float64Must(positionJSON.Get("upl").Interface()),
+			Margin:           conver.Float64Must(positionJSON.Get("imr").Interface()),
+			MarginMode:       constant.MarginModeCrossed,
+			LiquidationPrice: conver.Float64Must(positionJSON.Get("liqPx").Interface()),
+			ContractType:     contractTypeOf(stockType),
+			TradeType:        tradeType,
+			StockType:        stockType,
+		}
+		if positionJSON.Get("mgnMode").MustString() == "isolated" {
+			position.MarginMode = constant.MarginModeFixed
+			position.Margin = conver.Float64Must(positionJSON.Get("margin").Interface())
+		}
+		// 全仓的平仓单不冻结持仓, availPos 为空
+		if position.ConfirmAmount == 0.0 && positionJSON.Get("availPos").MustString() == "" {
+			position.ConfirmAmount = position.Amount
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+// GetPositions get the positions detail of this exchange
+func (e *OkexFuture) GetPositions(stockType string) interface{} {
+	positions, err := e.Positions(stockType)
+	if err != nil {
+		return e.fail("GetPositions", err)
 	}
 	return positions
 }
 
-// Trade place an order
-func (e *OkexFuture) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// ClosePosition close all the available amount of a position at the market price
+func (e *OkexFuture) ClosePosition(position Position, msgs ...interface{}) interface{} {
+	tradeType := constant.TradeTypeLongClose
+	if position.TradeType == constant.TradeTypeShort {
+		tradeType = constant.TradeTypeShortClose
+	}
+	amount := position.ConfirmAmount
+	if amount <= 0 {
+		return e.fail("ClosePosition", newError(ErrUnknown, "ClosePosition", "no available amount of the position"))
+	}
+	e.mutex.Lock()
+	leverage := e.leverage
+	e.mutex.Unlock()
+	if position.Leverage > 0 {
+		leverage = fmt.Sprint(position.Leverage)
+	}
+	id, err := e.place(tradeType, position.StockType, 0.0, amount, leverage, msgs...)
+	if err != nil {
+		return e.fail("ClosePosition", err)
+	}
+	return id
+}
+
+// PlaceOrder place an order with the leverage set by SetLeverage,
+// the first msg is taken as the leverage if it is 10 or 20 to keep the old scripts working
+func (e *OkexFuture) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	e.mutex.Lock()
+	leverage := e.leverage
+	e.mutex.Unlock()
+	if len(msgs) > 0 {
+		if _, ok := e.leverageMap[fmt.Sprint(msgs[0])]; ok {
+			leverage = fmt.Sprint(msgs[0])
+			msgs = msgs[1:]
+		}
+	}
+	return e.place(tradeType, stockType, price, amount, leverage, msgs...)
+}
+
+// place place an order with a leverage, it is a market order if price <= 0
+func (e *OkexFuture) place(tradeType string, stockType string, price, amount float64, leverage string, msgs ...interface{}) (string, error) {
 	tradeType = strings.ToUpper(tradeType)
 	stockType = strings.ToUpper(stockType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.tradeTypeMap[tradeType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
-	}
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	if len(msgs) < 1 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized leverage")
-		return false
-	}
-	leverage := fmt.Sprint(msgs[0])
-	if _, ok := e.leverageMap[leverage]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized leverage: ", leverage)
-		return false
-	}
-	matchPrice := "match_price=1"
+	side, ok := e.tradeTypeMap[tradeType]
+	if !ok {
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
+	}
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	instID := e.markets.symbol(stockType)
+	if err := e.apply(instID, leverage); err != nil {
+		return "", err
+	}
+	hedge, err := e.isHedgeMode()
+	if err != nil {
+		return "", err
+	}
+	e.mutex.Lock()
+	params := map[string]string{
+		"instId":  instID,
+		"tdMode":  e.tdMode(),
+		"side":    side[0],
+		"ordType": okexOrderType(price, opts),
+		"sz":      conver.StringMust(amount),
+	}
+	e.mutex.Unlock()
+	if hedge {
+		params["posSide"] = side[1]
+	} else if tradeType == constant.TradeTypeLongClose || tradeType == constant.TradeTypeShortClose {
+		// 买卖模式下平仓单只能减少持仓
+		params["reduceOnly"] = "true"
+	}
 	if price > 0.0 {
-		matchPrice = "match_price=0"
+		params["px"] = conver.StringMust(price)
 	} else {
 		price = 0.0
 	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType][0],
-		"contract_type=" + e.stockTypeMap[stockType][1],
-		fmt.Sprintf("price=%f", price),
-		fmt.Sprintf("amount=%f", amount),
-		"type=" + e.tradeTypeMap[tradeType],
-		matchPrice,
-		"lever_rate=" + leverage,
+	id, err := e.client.placeOrder(params)
+	if err != nil {
+		return "", err
 	}
-	json, err := e.getAuthJSON(e.host+"future_trade.do", params)
+	e.logger.Log(e.tradeTypeLogMap[tradeType], stockType, price, amount, msgs...)
+	return id, nil
+}
+
+// Trade place an order
+func (e *OkexFuture) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, ", err)
-		return false
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// tradeTypeOf get the trade type of an order or a fill by its side and position side
+func (e *OkexFuture) tradeTypeOf(orderJSON *simplejson.Json) string {
+	side := orderJSON.Get("side").MustString()
+	posSide := orderJSON.Get("posSide").MustString()
+	if posSide == "net" || posSide == "" {
+		// 买卖模式下 reduceOnly 的订单是平仓单, 卖出平多或者买入平空
+		posSide = "long"
+		if (side == "sell") != okexReduceOnly(orderJSON) {
+			posSide = "short"
+		}
+	}
+	for tradeType, s := range e.tradeTypeMap {
+		if s[0] == side && s[1] == posSide {
+			return tradeType
+		}
 	}
-	if result := json.Get("result").MustBool(); !result {
-		err = fmt.Errorf("Trade() error, the error number is %v", json.Get("error_code").MustInt())
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, ", err)
-		return false
+	return strings.ToUpper(side)
+}
+
+// okexReduceOnly check if an order is reduce-only, okex returns it as a string and Trades marks the fills with a bool
+func okexReduceOnly(orderJSON *simplejson.Json) bool {
+	if reduceOnly, err := orderJSON.Get("reduceOnly").Bool(); err == nil {
+		return reduceOnly
+	}
+	return orderJSON.Get("reduceOnly").MustString() == "true"
+}
+
+// parseOrder convert an order of okex to Order
+func (e *OkexFuture) parseOrder(stockType string, orderJSON *simplejson.Json) Order {
+	return Order{
+		ID:         orderJSON.Get("ordId").MustString(),
+		Price:      conver.Float64Must(orderJSON.Get("px").Interface()),
+		Amount:     conver.Float64Must(orderJSON.Get("sz").Interface()),
+		DealAmount: conver.Float64Must(orderJSON.Get("accFillSz").Interface()),
+		Fee:        math.Abs(conver.Float64Must(orderJSON.Get("fee").Interface())),
+		TradeType:  e.tradeTypeOf(orderJSON),
+		StockType:  stockType,
+	}
+}
+
+// Order get details of an order
+func (e *OkexFuture) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
+	}
+	orderJSON, err := e.client.order(e.markets.symbol(stockType), id)
+	if err != nil {
+		return Order{}, err
 	}
-	e.logger.Log(e.tradeTypeLogMap[tradeType], stockType, price, amount, msgs[2:]...)
-	return fmt.Sprint(json.Get("order_id").Interface())
+	return e.parseOrder(stockType, orderJSON), nil
 }
 
 // GetOrder get details of an order
 func (e *OkexFuture) GetOrder(stockType, id string) interface{} {
-	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType][0],
-		"contract_type=" + e.stockTypeMap[stockType][1],
-		"order_id=" + id,
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *OkexFuture) Orders(stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
-	json, err := e.getAuthJSON(e.host+"future_orders_info.do", params)
+	ordersJSON, err := e.client.pendingOrders(instTypeOf(stockType), e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
-	}
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, the error number is ", json.Get("error_code").MustInt())
-		return false
-	}
-	ordersJSON := json.Get("orders")
-	if len(ordersJSON.MustArray()) > 0 {
-		orderJSON := ordersJSON.GetIndex(0)
-		return Order{
-			ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
-			Price:      orderJSON.Get("price").MustFloat64(),
-			Amount:     orderJSON.Get("amount").MustFloat64(),
-			DealAmount: orderJSON.Get("deal_amount").MustFloat64(),
-			Fee:        orderJSON.Get("fee").MustFloat64(),
-			TradeType:  e.tradeTypeAntiMap[orderJSON.Get("type").MustInt()],
-			StockType:  stockType,
-		}
+		return nil, err
 	}
-	return false
+	orders := []Order{}
+	for i := 0; i < len(ordersJSON.MustArray()); i++ {
+		orders = append(orders, e.parseOrder(stockType, ordersJSON.GetIndex(i)))
+	}
+	return orders, nil
 }
 
 // GetOrders get all unfilled orders
 func (e *OkexFuture) GetOrders(stockType string) interface{} {
-	stockType = strings.ToUpper(stockType)
-	orders := []Order{}
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType][0],
-		"contract_type=" + e.stockTypeMap[stockType][1],
-		"status=1",
-		"order_id=-1",
-		"current_page=1",
-		"page_length=50",
-	}
-	json, err := e.getAuthJSON(e.host+"future_order_info.do", params)
+	orders, err := e.Orders(stockType)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
-	}
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, the error number is ", json.Get("error_code").MustInt())
-		return false
-	}
-	ordersJSON := json.Get("orders")
-	count := len(ordersJSON.MustArray())
-	for i := 0; i < count; i++ {
-		orderJSON := ordersJSON.GetIndex(i)
-		orders = append(orders, Order{
-			ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
-			Price:      orderJSON.Get("price").MustFloat64(),
-			Amount:     orderJSON.Get("amount").MustFloat64(),
-			DealAmount: orderJSON.Get("deal_amount").MustFloat64(),
-			Fee:        orderJSON.Get("fee").MustFloat64(),
-			TradeType:  e.tradeTypeAntiMap[orderJSON.Get("type").MustInt()],
-			StockType:  stockType,
-		})
+		return e.fail("GetOrders", err)
 	}
 	return orders
 }
 
-// GetTrades get all filled orders recently
-func (e *OkexFuture) GetTrades(stockType string) interface{} {
+// Trades get the recent fills of the last 3 days
+func (e *OkexFuture) Trades(stockType string) ([]Trade, error) {
 	stockType = strings.ToUpper(stockType)
-	orders := []Order{}
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType][0],
-		"contract_type=" + e.stockTypeMap[stockType][1],
-		"status=2",
-		"order_id=-1",
-		"current_page=1",
-		"page_length=50",
-	}
-	json, err := e.getAuthJSON(e.host+"future_order_info.do", params)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	fillsJSON, err := e.client.fills(instTypeOf(stockType), e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, ", err)
-		return false
-	}
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, the error number is ", json.Get("error_code").MustInt())
-		return false
-	}
-	ordersJSON := json.Get("orders")
-	count := len(ordersJSON.MustArray())
-	for i := 0; i < count; i++ {
-		orderJSON := ordersJSON.GetIndex(i)
-		orders = append(orders, Order{
-			ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
-			Price:      orderJSON.Get("price").MustFloat64(),
-			Amount:     orderJSON.Get("amount").MustFloat64(),
-			DealAmount: orderJSON.Get("deal_amount").MustFloat64(),
-			Fee:        orderJSON.Get("fee").MustFloat64(),
-			TradeType:  e.tradeTypeAntiMap[orderJSON.Get("type").MustInt()],
-			StockType:  stockType,
+		return nil, err
+	}
+	// 买卖模式下成交记录中没有 reduceOnly, 从订单中获取才能区分开仓和平仓
+	var reduceOnly map[string]bool
+	for i := 0; i < len(fillsJSON.MustArray()); i++ {
+		fillJSON := fillsJSON.GetIndex(i)
+		if posSide := fillJSON.Get("posSide").MustString(); posSide != "net" && posSide != "" {
+			continue
+		}
+		if reduceOnly == nil {
+			if reduceOnly, err = e.reduceOnlyOrders(stockType); err != nil {
+				return nil, err
+			}
+		}
+		fillJSON.Set("reduceOnly", reduceOnly[fillJSON.Get("ordId").MustString()])
+	}
+	trades := []Trade{}
+	for i := 0; i < len(fillsJSON.MustArray()); i++ {
+		fillJSON := fillsJSON.GetIndex(i)
+		trades = append(trades, Trade{
+			ID:          fillJSON.Get("tradeId").MustString(),
+			OrderID:     fillJSON.Get("ordId").MustString(),
+			Price:       conver.Float64Must(fillJSON.Get("fillPx").Interface()),
+			Amount:      conver.Float64Must(fillJSON.Get("fillSz").Interface()),
+			Fee:         math.Abs(conver.Float64Must(fillJSON.Get("fee").Interface())),
+			FeeCurrency: strings.ToUpper(fillJSON.Get("feeCcy").MustString()),
+			Time:        conver.Int64Must(fillJSON.Get("ts").Interface()) / 1000,
+			TradeType:   e.tradeTypeOf(fillJSON),
+			StockType:   stockType,
 		})
 	}
-	return orders
+	return trades, nil
 }
 
-// CancelOrder cancel an order
-func (e *OkexFuture) CancelOrder(order Order) bool {
-	params := []string{
-		"symbol=" + e.stockTypeMap[order.StockType][0],
-		"order_id=" + order.ID,
-		"contract_type=" + e.stockTypeMap[order.StockType][1],
+// reduceOnlyOrders get whether the recent orders of stockType are reduce-only keyed by their ids, including the unfilled ones
+func (e *OkexFuture) reduceOnlyOrders(stockType string) (map[string]bool, error) {
+	reduceOnly := map[string]bool{}
+	for _, list := range []func(instType, instID string) (*simplejson.Json, error){e.client.ordersHistory, e.client.pendingOrders} {
+		ordersJSON, err := list(instTypeOf(stockType), e.markets.symbol(stockType))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(ordersJSON.MustArray()); i++ {
+			orderJSON := ordersJSON.GetIndex(i)
+			reduceOnly[orderJSON.Get("ordId").MustString()] = okexReduceOnly(orderJSON)
+		}
 	}
-	json, err := e.getAuthJSON(e.host+"future_cancel.do", params)
+	return reduceOnly, nil
+}
+
+// GetTrades get the recent fills
+func (e *OkexFuture) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+		return e.fail("GetTrades", err)
 	}
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, the error number is ", json.Get("error_code").MustInt())
-		return false
+	return trades
+}
+
+// Cancel cancel an order
+func (e *OkexFuture) Cancel(order Order) error {
+	if !e.markets.has(order.StockType) {
+		return newError(ErrInvalidSymbol, "CancelOrder", "unrecognized stockType: ", order.StockType)
+	}
+	if err := e.client.cancelOrder(e.markets.symbol(order.StockType), order.ID); err != nil {
+		return err
 	}
 	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *OkexFuture) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *OkexFuture) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *OkexFuture) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	size := 20
-	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
-		size = conver.IntMust(sizes[0])
+	if size <= 0 {
+		size = 20
 	}
-	resp, err := get(fmt.Sprintf("%vfuture_depth.do?symbol=%v&contract_type=%v&size=%v", e.host, e.stockTypeMap[stockType][0], e.stockTypeMap[stockType][1], size))
-	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
-		return
-	}
-	json, err := simplejson.NewJson(resp)
-	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
-		return
-	}
-	depthsJSON := json.Get("bids")
-	for i := 0; i < len(depthsJSON.MustArray()); i++ {
-		depthJSON := depthsJSON.GetIndex(i)
-		ticker.Bids = append(ticker.Bids, OrderBook{
-			Price:  depthJSON.GetIndex(0).MustFloat64(),
-			Amount: depthJSON.GetIndex(1).MustFloat64(),
-		})
-	}
-	depthsJSON = json.Get("asks")
-	for i := len(depthsJSON.MustArray()); i > 0; i-- {
-		depthJSON := depthsJSON.GetIndex(i - 1)
-		ticker.Asks = append(ticker.Asks, OrderBook{
-			Price:  depthJSON.GetIndex(0).MustFloat64(),
-			Amount: depthJSON.GetIndex(1).MustFloat64(),
-		})
-	}
-	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
-		return
-	}
-	ticker.Buy = ticker.Bids[0].Price
-	ticker.Sell = ticker.Asks[0].Price
-	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
-	return
+	return e.client.books(e.markets.symbol(stockType), size)
 }
 
 // GetTicker get market ticker & depth
 func (e *OkexFuture) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
-// GetRecords get candlestick data
-func (e *OkexFuture) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+// Records get candlestick data
+func (e *OkexFuture) Records(stockType, period string, size int) ([]Record, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
 	}
 	if _, ok := e.recordsPeriodMap[period]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, unrecognized period: ", period)
-		return false
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
 	}
-	size := 200
-	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
-		size = conver.IntMust(sizes[0])
+	if size <= 0 {
+		size = 200
 	}
-	resp, err := get(fmt.Sprintf("%vfuture_kline.do?symbol=%v&contract_type=%v&type=%v&size=%v", e.host, e.stockTypeMap[stockType][0], e.stockTypeMap[stockType][1], e.recordsPeriodMap[period], size))
+	recordsNew, err := e.client.candles(e.markets.symbol(stockType), e.recordsPeriodMap[period], period, 0, e.store.missing(stockType, period, size))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, ", err)
-		return false
+		return nil, err
 	}
-	json, err := simplejson.NewJson(resp)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, ", err)
-		return false
-	}
-	timeLast := int64(0)
-	if len(e.records[period]) > 0 {
-		timeLast = e.records[period][len(e.records[period])-1].Time
-	}
-	recordsNew := []Record{}
-	for i := len(json.MustArray()); i > 0; i-- {
-		recordJSON := json.GetIndex(i - 1)
-		recordTime := recordJSON.GetIndex(0).MustInt64() / 1000
-		if recordTime > timeLast {
-			recordsNew = append([]Record{{
-				Time:   recordTime,
-				Open:   recordJSON.GetIndex(1).MustFloat64(),
-				High:   recordJSON.GetIndex(2).MustFloat64(),
-				Low:    recordJSON.GetIndex(3).MustFloat64(),
-				Close:  recordJSON.GetIndex(4).MustFloat64(),
-				Volume: recordJSON.GetIndex(5).MustFloat64(),
-			}}, recordsNew...)
-		} else if timeLast > 0 && recordTime == timeLast {
-			e.records[period][len(e.records[period])-1] = Record{
-				Time:   recordTime,
-				Open:   recordJSON.GetIndex(1).MustFloat64(),
-				High:   recordJSON.GetIndex(2).MustFloat64(),
-				Low:    recordJSON.GetIndex(3).MustFloat64(),
-				Close:  recordJSON.GetIndex(4).MustFloat64(),
-				Volume: recordJSON.GetIndex(5).MustFloat64(),
-			}
-		} else {
-			break
-		}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *OkexFuture) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
 	}
-	e.records[period] = append(e.records[period], recordsNew...)
-	if len(e.records[period]) > size {
-		e.records[period] = e.records[period][len(e.records[period])-size : len(e.records[period])]
+	return e.client.candles(e.markets.symbol(stockType), e.recordsPeriodMap[period], period, since, size)
+}
+
+// GetRecords get candlestick data
+func (e *OkexFuture) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
 	}
-	return e.records[period]
+	return records
 }
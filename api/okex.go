@@ -2,7 +2,8 @@ package api
 
 import (
 	"fmt"
-	"sort"
+	"hash/crc32"
+	"math"
 	"strings"
 	"time"
 
@@ -10,50 +11,60 @@ import (
 	"github.com/HunterUPP/QuantBot/model"
 	"github.com/bitly/go-simplejson"
 	"github.com/miaolz123/conver"
+	"golang.org/x/net/websocket"
 )
 
-// OKEX the exchange struct of okex.com
+// OKEX the exchange struct of okex.com, it uses the v5 api which needs the passphrase of the api key
 type OKEX struct {
+	client           *okexClient
 	stockTypeMap     map[string]string
-	tradeTypeMap     map[string]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
-	host             string
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	streams          *stream
+	store            *recordStore
 	logger           model.Logger
 	option           Option
+	lastError        *Error
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+	limiter *rateLimiter
 }
 
+// okexPeriods the periods of the records the exchange supports
+var okexPeriods = map[string]string{
+	"M":   "1m",
+	"M3":  "3m",
+	"M5":  "5m",
+	"M15": "15m",
+	"M30": "30m",
+	"H":   "1H",
+	"H2":  "2H",
+	"H4":  "4H",
+	"H6":  "6Hutc",
+	"H12": "12Hutc",
+	"D":   "1Dutc",
+	"D3":  "3Dutc",
+	"W":   "1Wutc",
+}
+
+// okexOrderTypes the order types the exchange supports
+var okexOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
 // NewOKEX create an exchange struct of okex.com
 func NewOKEX(opt Option) Exchange {
-	return &OKEX{
+	e := &OKEX{
+		client: newOkexClient(opt),
 		stockTypeMap: map[string]string{
-			"BTC/USDT":  "btc_usdt",
-			"ETH/USDT":  "eth_usdt",
-			"EOS/USDT":  "eos_usdt",
-			"ONT/USDT":  "ont_usdt",
-			"QTUM/USDT": "qtum_usdt",
-			"ONT/ETH":   "ont_eth",
-		},
-		tradeTypeMap: map[string]string{
-			"buy":         constant.TradeTypeBuy,
-			"sell":        constant.TradeTypeSell,
-			"buy_market":  constant.TradeTypeBuy,
-			"sell_market": constant.TradeTypeSell,
-		},
-		recordsPeriodMap: map[string]string{
-			"M":   "1min",
-			"M5":  "5min",
-			"M15": "15min",
-			"M30": "30min",
-			"H":   "1hour",
-			"D":   "1day",
-			"W":   "1week",
+			"BTC/USDT":  "BTC-USDT",
+			"ETH/USDT":  "ETH-USDT",
+			"EOS/USDT":  "EOS-USDT",
+			"ONT/USDT":  "ONT-USDT",
+			"QTUM/USDT": "QTUM-USDT",
+			"ONT/ETH":   "ONT-ETH",
 		},
+		// 6小时以上的K线使用 UTC 时间对齐
+		recordsPeriodMap: okexPeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT":  0.001,
 			"ETH/USDT":  0.001,
@@ -62,14 +73,16 @@ func NewOKEX(opt Option) Exchange {
 			"QTUM/USDT": 0.001,
 			"ONT/ETH":   0.001,
 		},
-		records: make(map[string][]Record),
-		host:    "https://www.okex.com/api/v1/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: okexOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter: limiterOf(opt),
 	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	e.streams = newStream(&okexStream{proxy: opt.ProxyURL}, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -87,402 +100,433 @@ func (e *OKEX) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *OKEX) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *OKEX) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *OKEX) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *OKEX) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *OKEX) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
 }
 
-func (e *OKEX) getAuthJSON(url string, params []string) (json *simplejson.Json, err error) {
-	e.lastTimes++
-	params = append(params, "api_key="+e.option.AccessKey)
-	sort.Strings(params)
-	strToSign := append(params, "secret_key="+e.option.SecretKey)
-	// params = append(params, "secret_key="+e.option.SecretKey)
-	params = append(params, "sign="+strings.ToUpper(signMd5(strToSign)))
+// GetMarkets get all the markets of this exchange
+func (e *OKEX) GetMarkets() interface{} {
+	return e.markets.list()
+}
 
-	resp, err := post(url, params)
+// loadMarkets load the markets from the spot instruments of okex.com
+func (e *OKEX) loadMarkets() (markets []Market, err error) {
+	data, err := e.client.instruments("SPOT")
 	if err != nil {
 		return
 	}
-	return simplejson.NewJson(resp)
+	for i := 0; i < len(data.MustArray()); i++ {
+		instrumentJSON := data.GetIndex(i)
+		base := strings.ToUpper(instrumentJSON.Get("baseCcy").MustString())
+		quote := strings.ToUpper(instrumentJSON.Get("quoteCcy").MustString())
+		if base == "" || quote == "" || instrumentJSON.Get("state").MustString() != "live" {
+			continue
+		}
+		tickSize := conver.Float64Must(instrumentJSON.Get("tickSz").Interface())
+		lotSize := conver.Float64Must(instrumentJSON.Get("lotSz").Interface())
+		markets = append(markets, Market{
+			StockType:       base + "/" + quote,
+			Symbol:          instrumentJSON.Get("instId").MustString(),
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  precisionOf(tickSize),
+			AmountPrecision: precisionOf(lotSize),
+			TickSize:        tickSize,
+			LotSize:         lotSize,
+			MinAmount:       conver.Float64Must(instrumentJSON.Get("minSz").Interface()),
+		})
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *OKEX) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *OKEX) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *OKEX) Account() (Account, error) {
+	return e.client.balance()
 }
 
 // GetAccount get the account detail of this exchange
 func (e *OKEX) GetAccount() interface{} {
-	json, err := e.getAuthJSON(e.host+"userinfo.do", []string{})
+	account, err := e.Account()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
-	}
-
-	fmt.Println("response: ", json)
-	if result := json.Get("result").MustBool(); !result {
-		err = fmt.Errorf("GetAccount() error, the error number is %v", json.Get("error_code").MustInt())
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
-	}
-	return map[string]float64{
-		"USDT":       conver.Float64Must(json.GetPath("info", "funds", "free", "usdt").Interface()),
-		"FrozenUSDT": conver.Float64Must(json.GetPath("info", "funds", "freezed", "usdt").Interface()),
-		"BTC":        conver.Float64Must(json.GetPath("info", "funds", "free", "btc").Interface()),
-		"FrozenBTC":  conver.Float64Must(json.GetPath("info", "funds", "freezed", "btc").Interface()),
-		"ETH":        conver.Float64Must(json.GetPath("info", "funds", "free", "eth").Interface()),
-		"FrozenETH":  conver.Float64Must(json.GetPath("info", "funds", "freezed", "eth").Interface()),
-		"EOS":        conver.Float64Must(json.GetPath("info", "funds", "free", "eos").Interface()),
-		"FrozenEOS":  conver.Float64Must(json.GetPath("info", "funds", "freezed", "eos").Interface()),
-		"ONT":        conver.Float64Must(json.GetPath("info", "funds", "free", "ont").Interface()),
-		"FrozenONT":  conver.Float64Must(json.GetPath("info", "funds", "freezed", "ont").Interface()),
-		"QTUM":       conver.Float64Must(json.GetPath("info", "funds", "free", "qtum").Interface()),
-		"FrozenQTUM": conver.Float64Must(json.GetPath("info", "funds", "freezed", "qtum").Interface()),
+		return e.fail("GetAccount", err)
 	}
+	return account
 }
 
-// Trade place an order
-func (e *OKEX) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency
+func (e *OKEX) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
 	}
+	logType := constant.BUY
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		logType = constant.SELL
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
-}
-
-func (e *OKEX) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType],
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	params := map[string]string{
+		"instId":  e.markets.symbol(stockType),
+		"tdMode":  "cash",
+		"side":    strings.ToLower(tradeType),
+		"ordType": okexOrderType(price, opts),
+		"sz":      conver.StringMust(amount),
 	}
-	typeParam := "type=buy_market"
-	amountParam := fmt.Sprintf("price=%f", amount)
 	if price > 0 {
-		typeParam = "type=buy"
-		amountParam = fmt.Sprintf("amount=%f", amount)
-		params = append(params, fmt.Sprintf("price=%f", price))
+		params["px"] = conver.StringMust(price)
+	} else if tradeType == constant.TradeTypeBuy {
+		// 市价买单的数量是计价货币的金额
+		params["tgtCcy"] = "quote_ccy"
+		price = 0.0
+	} else {
+		price = 0.0
 	}
-	params = append(params, typeParam, amountParam)
-	json, err := e.getAuthJSON(e.host+"trade.do", params)
+	id, err := e.client.placeOrder(params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
+		return "", err
 	}
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, the error number is ", json.Get("error_code").MustInt())
-		return false
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return id, nil
+}
+
+// Trade place an order
+func (e *OKEX) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
 	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return fmt.Sprint(json.Get("order_id").Interface())
+	return id
 }
 
-func (e *OKEX) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType],
-		fmt.Sprintf("amount=%f", amount),
+// parseOrder convert an order of okex to Order
+func (e *OKEX) parseOrder(stockType string, orderJSON *simplejson.Json) Order {
+	return Order{
+		ID:         orderJSON.Get("ordId").MustString(),
+		Price:      conver.Float64Must(orderJSON.Get("px").Interface()),
+		Amount:     conver.Float64Must(orderJSON.Get("sz").Interface()),
+		DealAmount: conver.Float64Must(orderJSON.Get("accFillSz").Interface()),
+		Fee:        math.Abs(conver.Float64Must(orderJSON.Get("fee").Interface())),
+		TradeType:  strings.ToUpper(orderJSON.Get("side").MustString()),
+		StockType:  stockType,
 	}
-	typeParam := "type=sell_market"
-	if price > 0 {
-		typeParam = "type=sell"
-		params = append(params, fmt.Sprintf("price=%f", price))
+}
+
+// Order get details of an order
+func (e *OKEX) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
 	}
-	params = append(params, typeParam)
-	json, err := e.getAuthJSON(e.host+"trade.do", params)
+	orderJSON, err := e.client.order(e.markets.symbol(stockType), id)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
-	}
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, the error number is ", json.Get("error_code").MustInt())
-		return false
+		return Order{}, err
 	}
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return fmt.Sprint(json.Get("order_id").Interface())
+	return e.parseOrder(stockType, orderJSON), nil
 }
 
 // GetOrder get details of an order
 func (e *OKEX) GetOrder(stockType, id string) interface{} {
-	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType],
-		"order_id=" + id,
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *OKEX) Orders(stockType string) ([]Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
-	json, err := e.getAuthJSON(e.host+"order_info.do", params)
+	ordersJSON, err := e.client.pendingOrders("SPOT", e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
-	}
-
-	fmt.Println(json)
-	//// To get result:
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, the error number is ", json.Get("error_code").MustInt())
-		return false
-	}
-	ordersJSON := json.Get("orders")
-	if len(ordersJSON.MustArray()) > 0 {
-		orderJSON := ordersJSON.GetIndex(0)
-		return Order{
-			ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
-			Price:      orderJSON.Get("price").MustFloat64(),
-			Amount:     orderJSON.Get("amount").MustFloat64(),
-			DealAmount: orderJSON.Get("deal_amount").MustFloat64(),
-			TradeType:  e.tradeTypeMap[orderJSON.Get("type").MustString()],
-			StockType:  stockType,
-		}
-	} else {
-		e.logger.Log(constant.INFO, "", 0.0, 0.0, "order(id = "+id+") not exist.", json.Get("error_code").MustInt())
-		return true
+		return nil, err
+	}
+	orders := []Order{}
+	for i := 0; i < len(ordersJSON.MustArray()); i++ {
+		orders = append(orders, e.parseOrder(stockType, ordersJSON.GetIndex(i)))
 	}
+	return orders, nil
 }
 
 // GetOrders get all unfilled orders
 func (e *OKEX) GetOrders(stockType string) interface{} {
-	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType],
-		"order_id=-1",
-	}
-	json, err := e.getAuthJSON(e.host+"order_info.do", params)
+	orders, err := e.Orders(stockType)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
+		return e.fail("GetOrders", err)
 	}
+	return orders
+}
 
-	fmt.Println(json)
-	//// To get result:
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, the error number is ", json.Get("error_code").MustInt())
-		return false
+// Trades get the recent fills of the last 3 days
+func (e *OKEX) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
 	}
-	orders := []Order{}
-	ordersJSON := json.Get("orders")
-	count := len(ordersJSON.MustArray())
-	for i := 0; i < count; i++ {
-		orderJSON := ordersJSON.GetIndex(i)
-		orders = append(orders, Order{
-			ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
-			Price:      orderJSON.Get("price").MustFloat64(),
-			Amount:     orderJSON.Get("amount").MustFloat64(),
-			DealAmount: orderJSON.Get("deal_amount").MustFloat64(),
-			TradeType:  e.tradeTypeMap[orderJSON.Get("type").MustString()],
-			StockType:  stockType,
+	fillsJSON, err := e.client.fills("SPOT", e.markets.symbol(stockType))
+	if err != nil {
+		return nil, err
+	}
+	trades := []Trade{}
+	for i := 0; i < len(fillsJSON.MustArray()); i++ {
+		fillJSON := fillsJSON.GetIndex(i)
+		trades = append(trades, Trade{
+			ID:          fillJSON.Get("tradeId").MustString(),
+			OrderID:     fillJSON.Get("ordId").MustString(),
+			Price:       conver.Float64Must(fillJSON.Get("fillPx").Interface()),
+			Amount:      conver.Float64Must(fillJSON.Get("fillSz").Interface()),
+			Fee:         math.Abs(conver.Float64Must(fillJSON.Get("fee").Interface())),
+			FeeCurrency: strings.ToUpper(fillJSON.Get("feeCcy").MustString()),
+			Time:        conver.Int64Must(fillJSON.Get("ts").Interface()) / 1000,
+			TradeType:   strings.ToUpper(fillJSON.Get("side").MustString()),
+			StockType:   stockType,
 		})
 	}
-	return orders
+	return trades, nil
 }
 
-// GetTrades get all filled orders recently
+// GetTrades get the recent fills
 func (e *OKEX) GetTrades(stockType string) interface{} {
-	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, unrecognized stockType: ", stockType)
-		return false
-	}
-	params := []string{
-		"symbol=" + e.stockTypeMap[stockType],
-		"status=1",
-		"current_page=1",
-		"page_length=200",
-	}
-	json, err := e.getAuthJSON(e.host+"order_history.do", params)
+	trades, err := e.Trades(stockType)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, ", err)
-		return false
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// MarketTrades get the latest public trades of the market, okex returns at most 500 trades
+func (e *OKEX) MarketTrades(stockType string, size int) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetMarketTrades", "unrecognized stockType: ", stockType)
 	}
+	return e.client.marketTrades(e.markets.symbol(stockType), stockType, size)
+}
 
-	fmt.Println(json)
-	//// To get result:
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, the error number is ", json.Get("error_code").MustInt())
-		return false
+// Cancel cancel an order
+func (e *OKEX) Cancel(order Order) error {
+	if !e.markets.has(order.StockType) {
+		return newError(ErrInvalidSymbol, "CancelOrder", "unrecognized stockType: ", order.StockType)
 	}
-	orders := []Order{}
-	ordersJSON := json.Get("orders")
-	count := len(ordersJSON.MustArray())
-	for i := 0; i < count; i++ {
-		orderJSON := ordersJSON.GetIndex(i)
-		orders = append(orders, Order{
-			ID:         fmt.Sprint(orderJSON.Get("order_id").Interface()),
-			Price:      orderJSON.Get("price").MustFloat64(),
-			Amount:     orderJSON.Get("amount").MustFloat64(),
-			DealAmount: orderJSON.Get("deal_amount").MustFloat64(),
-			TradeType:  e.tradeTypeMap[orderJSON.Get("type").MustString()],
-			StockType:  stockType,
-		})
+	if err := e.client.cancelOrder(e.markets.symbol(order.StockType), order.ID); err != nil {
+		return err
 	}
-	return orders
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
 }
 
 // CancelOrder cancel an order
 func (e *OKEX) CancelOrder(order Order) bool {
-	params := []string{
-		"symbol=" + e.stockTypeMap[order.StockType],
-		"order_id=" + order.ID,
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
 	}
-	json, err := e.getAuthJSON(e.host+"cancel_order.do", params)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
-	}
-
-	fmt.Println("json: ", json)
-	//// To get result:
-	if result := json.Get("result").MustBool(); !result {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, the error number is ", json.Get("error_code").MustInt())
-		return false
-	}
-	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *OKEX) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *OKEX) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	size := 20
-	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
-		size = conver.IntMust(sizes[0])
+	if size <= 0 {
+		size = 20
 	}
-	resp, err := get(fmt.Sprintf("%vdepth.do?symbol=%v&size=%v", e.host, e.stockTypeMap[stockType], size))
-	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
-		return
+	if t, ok := e.streams.ticker(stockType, size); ok {
+		return t, nil
 	}
-	json, err := simplejson.NewJson(resp)
+	return e.client.books(e.markets.symbol(stockType), size)
+}
+
+// GetTicker get market ticker & depth
+func (e *OKEX) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
-		return
+		return e.fail("GetTicker", err)
 	}
-	depthsJSON := json.Get("bids")
-	for i := 0; i < len(depthsJSON.MustArray()); i++ {
-		depthJSON := depthsJSON.GetIndex(i)
-		ticker.Bids = append(ticker.Bids, OrderBook{
-			Price:  depthJSON.GetIndex(0).MustFloat64(),
-			Amount: depthJSON.GetIndex(1).MustFloat64(),
-		})
+	return ticker
+}
+
+// Records get candlestick data
+func (e *OKEX) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
 	}
-	depthsJSON = json.Get("asks")
-	for i := len(depthsJSON.MustArray()); i > 0; i-- {
-		depthJSON := depthsJSON.GetIndex(i - 1)
-		ticker.Asks = append(ticker.Asks, OrderBook{
-			Price:  depthJSON.GetIndex(0).MustFloat64(),
-			Amount: depthJSON.GetIndex(1).MustFloat64(),
-		})
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
 	}
-	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
-		return
+	if size <= 0 {
+		size = 200
 	}
-	ticker.Buy = ticker.Bids[0].Price
-	ticker.Sell = ticker.Asks[0].Price
-	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
-	return
+	recordsNew, err := e.client.candles(e.markets.symbol(stockType), e.recordsPeriodMap[period], period, 0, e.store.missing(stockType, period, size))
+	if err != nil {
+		return nil, err
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
 }
 
-// GetTicker get market ticker & depth
-func (e *OKEX) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
-	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *OKEX) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
 	}
-	return ticker
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	return e.client.candles(e.markets.symbol(stockType), e.recordsPeriodMap[period], period, since, size)
 }
 
 // GetRecords get candlestick data
 func (e *OKEX) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
-	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, unrecognized stockType: ", stockType)
-		return false
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
 	}
-	if _, ok := e.recordsPeriodMap[period]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, unrecognized period: ", period)
-		return false
+	return records
+}
+
+// Stream subscribe the depth and trade channels of stockType by websocket, GetTicker reads the local order book after it
+func (e *OKEX) Stream(stockType string) error {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return newError(ErrInvalidSymbol, "Subscribe", "unrecognized stockType: ", stockType)
 	}
-	size := 200
-	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
-		size = conver.IntMust(sizes[0])
+	e.streams.subscribe(stockType, e.markets.symbol(stockType))
+	return nil
+}
+
+// Subscribe subscribe the market data of stockType by websocket
+func (e *OKEX) Subscribe(stockType string) bool {
+	if err := e.Stream(stockType); err != nil {
+		return e.fail("Subscribe", err)
 	}
-	resp, err := get(fmt.Sprintf("%vkline.do?symbol=%v&type=%v&size=%v", e.host, e.stockTypeMap[stockType], e.recordsPeriodMap[period], size))
+	return true
+}
+
+// CloseStreams close all the websocket streams
+func (e *OKEX) CloseStreams() {
+	e.streams.close()
+}
+
+// okexStream the websocket stream of the okex v5 api, the depth updates are verified by the checksum of the top 25 levels
+type okexStream struct {
+	proxy string //代理地址
+}
+
+func (p *okexStream) dial(symbol string) (*websocket.Conn, error) {
+	conn, err := dialStream(p.proxy, "wss://ws.okx.com:8443/ws/v5/public", map[string]interface{}{
+		"op": "subscribe",
+		"args": []map[string]string{
+			{"channel": "books", "instId": symbol},
+			{"channel": "trades", "instId": symbol},
+		},
+	})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, ", err)
-		return false
+		return nil, err
+	}
+	go func() { //30秒内没有消息服务器会断开连接
+		for {
+			time.Sleep(20 * time.Second)
+			if err := websocket.Message.Send(conn, "ping"); err != nil {
+				return
+			}
+		}
+	}()
+	return conn, nil
+}
+
+func (p *okexStream) handle(conn *websocket.Conn, message []byte, book *orderBook) error {
+	if string(message) == "pong" {
+		return nil
 	}
-	json, err := simplejson.NewJson(resp)
+	json, err := simplejson.NewJson(message)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, ", err)
-		return false
-	}
-	timeLast := int64(0)
-	if len(e.records[period]) > 0 {
-		timeLast = e.records[period][len(e.records[period])-1].Time
-	}
-	recordsNew := []Record{}
-	for i := len(json.MustArray()); i > 0; i-- {
-		recordJSON := json.GetIndex(i - 1)
-		recordTime := recordJSON.GetIndex(0).MustInt64() / 1000
-		if recordTime > timeLast {
-			recordsNew = append([]Record{{
-				Time:   recordTime,
-				Open:   recordJSON.GetIndex(1).MustFloat64(),
-				High:   recordJSON.GetIndex(2).MustFloat64(),
-				Low:    recordJSON.GetIndex(3).MustFloat64(),
-				Close:  recordJSON.GetIndex(4).MustFloat64(),
-				Volume: recordJSON.GetIndex(5).MustFloat64(),
-			}}, recordsNew...)
-		} else if timeLast > 0 && recordTime == timeLast {
-			e.records[period][len(e.records[period])-1] = Record{
-				Time:   recordTime,
-				Open:   recordJSON.GetIndex(1).MustFloat64(),
-				High:   recordJSON.GetIndex(2).MustFloat64(),
-				Low:    recordJSON.GetIndex(3).MustFloat64(),
-				Close:  recordJSON.GetIndex(4).MustFloat64(),
-				Volume: recordJSON.GetIndex(5).MustFloat64(),
+		return err
+	}
+	if json.Get("event").MustString() == "error" {
+		return fmt.Errorf("%v", json.Get("msg").MustString())
+	}
+	data := json.Get("data")
+	for i := 0; i < len(data.MustArray()); i++ {
+		item := data.GetIndex(i)
+		switch json.GetPath("arg", "channel").MustString() {
+		case "trades":
+			book.trade(conver.Float64Must(item.Get("px").Interface()))
+		case "books":
+			if json.Get("action").MustString() == "snapshot" {
+				book.snapshot(item.Get("bids").MustArray(), item.Get("asks").MustArray(), 0)
+			} else if _, ready := book.sequence(); !ready {
+				return errResync
+			} else {
+				book.update(item.Get("bids").MustArray(), item.Get("asks").MustArray(), 0)
+			}
+			if okexChecksum(book) != int32(item.Get("checksum").MustInt64()) {
+				return errResync
 			}
-		} else {
-			break
 		}
 	}
-	e.records[period] = append(e.records[period], recordsNew...)
-	if len(e.records[period]) > size {
-		e.records[period] = e.records[period][len(e.records[period])-size : len(e.records[period])]
+	return nil
+}
+
+// okexChecksum get the crc32 of the top 25 levels like "bid1price:bid1size:ask1price:ask1size:..."
+func okexChecksum(book *orderBook) int32 {
+	bids, asks := book.levels(25)
+	fields := []string{}
+	for i := 0; i < 25; i++ {
+		if i < len(bids) {
+			fields = append(fields, bids[i].RawPrice, bids[i].RawAmount)
+		}
+		if i < len(asks) {
+			fields = append(fields, asks[i].RawPrice, asks[i].RawAmount)
+		}
 	}
-	return e.records[period]
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(fields, ":"))))
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+// OrderOptions the optional flags of an order, a script passes them as an object right after the amount,
+// like E.Trade("BUY", "BTC/USDT", 6000, 0.1, {postOnly: true}) or E.Trade("SELL", "BTC/USDT", 6000, 0.1, {timeInForce: "IOC"})
+type OrderOptions struct {
+	PostOnly    bool   //只做 maker, 会立即成交时交易所拒绝或者撤销这个订单
+	TimeInForce string //GTC: 一直有效(默认), IOC: 立即成交, 剩余的撤销, FOK: 全部立即成交, 否则撤销
+}
+
+// orderOptionKeys the keys of the order options in a script object, they are case insensitive
+var orderOptionKeys = map[string]bool{
+	"postonly":    true,
+	"timeinforce": true,
+}
+
+// orderOptionsOf take the order options out of the messages of Trade and check them against the order types of the exchange,
+// the first message is the options if it is an OrderOptions or an object with any of the option keys, otherwise it is logged as usual
+func orderOptionsOf(method string, price float64, orderTypes []string, msgs []interface{}) (OrderOptions, []interface{}, error) {
+	opts := OrderOptions{}
+	if len(msgs) > 0 {
+		switch v := msgs[0].(type) {
+		case OrderOptions:
+			opts = v
+			msgs = msgs[1:]
+		case *OrderOptions:
+			if v != nil {
+				opts = *v
+			}
+			msgs = msgs[1:]
+		case map[string]interface{}:
+			isOptions := false
+			for key := range v {
+				if orderOptionKeys[strings.ToLower(key)] {
+					isOptions = true
+				}
+			}
+			if isOptions {
+				for key, value := range v {
+					switch strings.ToLower(key) {
+					case "postonly":
+						postOnly, ok := value.(bool)
+						if !ok {
+							return opts, msgs, newError(ErrUnknown, method, "postOnly should be a boolean: ", value)
+						}
+						opts.PostOnly = postOnly
+					case "timeinforce":
+						opts.TimeInForce = fmt.Sprint(value)
+					default:
+						return opts, msgs, newError(ErrUnknown, method, "unrecognized order option: ", key)
+					}
+				}
+				msgs = msgs[1:]
+			}
+		}
+	}
+	opts.TimeInForce = strings.ToUpper(opts.TimeInForce)
+	supported := map[string]bool{}
+	for _, orderType := range orderTypes {
+		supported[orderType] = true
+	}
+	switch {
+	case price <= 0 && !supported[constant.OrderTypeMarket]:
+		return opts, msgs, newError(ErrNotSupported, method, "market orders are not supported")
+	case opts.TimeInForce != "" && opts.TimeInForce != constant.TimeInForceGTC &&
+		opts.TimeInForce != constant.TimeInForceIOC && opts.TimeInForce != constant.TimeInForceFOK:
+		return opts, msgs, newError(ErrUnknown, method, "unrecognized timeInForce: ", opts.TimeInForce)
+	case opts.PostOnly && price <= 0:
+		return opts, msgs, newError(ErrUnknown, method, "a market order can not be post-only")
+	case opts.PostOnly && opts.TimeInForce != "" && opts.TimeInForce != constant.TimeInForceGTC:
+		return opts, msgs, newError(ErrUnknown, method, "a post-only order can not be ", opts.TimeInForce)
+	case opts.PostOnly && !supported[constant.OrderTypePostOnly]:
+		return opts, msgs, newError(ErrNotSupported, method, "post-only orders are not supported")
+	case opts.TimeInForce == constant.TimeInForceIOC && !supported[constant.OrderTypeIOC],
+		opts.TimeInForce == constant.TimeInForceFOK && !supported[constant.OrderTypeFOK]:
+		return opts, msgs, newError(ErrNotSupported, method, opts.TimeInForce, " orders are not supported")
+	}
+	return opts, msgs, nil
+}
+
+// marketLimit get the limit price and the base amount which emulate a market order on the exchanges without market orders,
+// the price is the worst level of the order book the order eats and a market buy spends amount in the quote currency,
+// so its base amount is amount / price and the frozen funds are not more than amount, the base amount is rounded down to the lot size
+func marketLimit(method string, ticker Ticker, tradeType string, amount, lotSize float64) (price, baseAmount float64, err error) {
+	remaining := amount
+	switch tradeType {
+	case constant.TradeTypeBuy:
+		for _, level := range ticker.Asks {
+			if remaining <= 0 {
+				break
+			}
+			price = level.Price
+			remaining -= level.Price * level.Amount
+		}
+		if price > 0 {
+			baseAmount = amount / price
+		}
+	case constant.TradeTypeSell:
+		for _, level := range ticker.Bids {
+			if remaining <= 0 {
+				break
+			}
+			price = level.Price
+			remaining -= level.Amount
+		}
+		baseAmount = amount
+	}
+	if lotSize > 0 {
+		baseAmount = math.Floor(baseAmount/lotSize+1e-9) * lotSize
+	}
+	if price <= 0 || baseAmount <= 0 {
+		err = newError(ErrUnknown, method, "can not get the market price of ", tradeType, " ", amount)
+	}
+	return
+}
+
+// marketOrderOf get the limit price and the base amount of the IOC limit order which takes the place of a market order,
+// the order book is got from the ticker of the exchange
+func marketOrderOf(method string, ticker func(string, int) (Ticker, error), markets *marketCache, stockType, tradeType string, amount float64) (price, baseAmount float64, err error) {
+	t, err := ticker(stockType, 100)
+	if err != nil {
+		return 0.0, 0.0, wrapError(method, err)
+	}
+	market, _ := markets.get(stockType)
+	return marketLimit(method, t, tradeType, amount, market.LotSize)
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+func TestMarketLimit(t *testing.T) {
+	ticker := Ticker{
+		Bids: []OrderBook{{Price: 99, Amount: 1}, {Price: 98, Amount: 1}, {Price: 97, Amount: 5}},
+		Asks: []OrderBook{{Price: 100, Amount: 1}, {Price: 101, Amount: 1}, {Price: 102, Amount: 5}},
+	}
+	// 市价买单以最差的价格下单, 冻结的金额不超过计价币种的数量
+	price, amount, err := marketLimit("Buy", ticker, constant.TradeTypeBuy, 250, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 102 || amount > 250/102.0 || amount < 250/102.0-0.001 {
+		t.Errorf("unexpected market buy of price %v and amount %v", price, amount)
+	}
+	price, amount, err = marketLimit("Sell", ticker, constant.TradeTypeSell, 1.5, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 98 || amount != 1.5 {
+		t.Errorf("unexpected market sell of price %v and amount %v", price, amount)
+	}
+	if _, _, err = marketLimit("Buy", Ticker{}, constant.TradeTypeBuy, 250, 0.001); err == nil {
+		t.Error("expect an error without the order book")
+	}
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/miaolz123/conver"
+)
+
+// paperSettings is the paper trading part of the exchange settings, e.g.
+// {"paper": true, "balance": {"USDT": 10000}, "fee": 0.002}
+type paperSettings struct {
+	Paper   bool               `json:"paper"`   //是否模拟交易
+	Balance map[string]float64 `json:"balance"` //初始资金
+	Fee     float64            `json:"fee"`     //手续费率
+}
+
+func parsePaperSettings(opt Option) paperSettings {
+	settings := paperSettings{
+		Balance: map[string]float64{"USDT": 10000.0},
+		Fee:     0.002,
+	}
+	if opt.Settings != "" {
+		json.Unmarshal([]byte(opt.Settings), &settings)
+	}
+	return settings
+}
+
+// IsPaper check if paper trading is turned on in the settings of an exchange
+func IsPaper(opt Option) bool {
+	return parsePaperSettings(opt).Paper
+}
+
+// Paper wraps a live exchange for paper trading,
+// the market data comes from the live exchange but the orders are filled by a local simulator
+type Paper struct {
+	Exchange
+	sim       *simulator
+	logger    model.Logger
+	lastError *Error
+}
+
+// NewPaper create a paper trading wrapper of a live exchange
+func NewPaper(e Exchange, opt Option) Exchange {
+	settings := parsePaperSettings(opt)
+	logger := model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type}
+	return &Paper{
+		Exchange: e,
+		sim:      newSimulator(settings.Balance, settings.Fee, logger),
+		logger:   logger,
+	}
+}
+
+// ticker get the live ticker and fill the orders crossed by it
+func (e *Paper) ticker(stockType string, size int) (ticker Ticker, err error) {
+	stockType = strings.ToUpper(stockType)
+	if typed, ok := e.Exchange.(TypedExchange); ok {
+		ticker, err = typed.Ticker(stockType, size)
+	} else if t, ok := e.Exchange.GetTicker(stockType, size).(Ticker); ok {
+		ticker = t
+	} else {
+		err = newError(ErrUnknown, "GetTicker", "can not get the ticker of ", stockType)
+	}
+	if err != nil {
+		err = wrapError("GetTicker", err)
+		return
+	}
+	e.sim.match(stockType, ticker.Sell, ticker.Buy)
+	return
+}
+
+// refresh fill all the unfilled orders crossed by the live market
+func (e *Paper) refresh() {
+	for _, stockType := range e.sim.stockTypes() {
+		e.ticker(stockType, 0)
+	}
+}
+
+// GetLastError get the last error of this exchange
+func (e *Paper) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Paper) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of the simulator
+func (e *Paper) Account() (Account, error) {
+	e.refresh()
+	return e.sim.account(), nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *Paper) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order into the simulator
+func (e *Paper) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
+	stockType = strings.ToUpper(stockType)
+	tradeType = strings.ToUpper(tradeType)
+	ticker, err := e.ticker(stockType, 0)
+	if err != nil {
+		return "", wrapError("Trade", err)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, simOrderTypes, msgs)
+	if err != nil {
+		return "", err
+	}
+	id, err := e.sim.place(tradeType, stockType, price, amount, ticker.Buy, ticker.Sell, opts, msgs...)
+	if err != nil {
+		return "", wrapError("Trade", err)
+	}
+	return id, nil
+}
+
+// Trade place an order
+func (e *Paper) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
+	if err != nil {
+		return e.fail("Trade", err)
+	}
+	return id
+}
+
+// Order get details of an order
+func (e *Paper) Order(stockType, id string) (Order, error) {
+	e.refresh()
+	order, err := e.sim.order(id)
+	if err != nil {
+		return order, wrapError("GetOrder", err)
+	}
+	return order, nil
+}
+
+// GetOrder get details of an order
+func (e *Paper) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
+	}
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *Paper) Orders(stockType string) ([]Order, error) {
+	e.refresh()
+	return e.sim.openOrders(strings.ToUpper(stockType)), nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Paper) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the recent fills
+func (e *Paper) Trades(stockType string) ([]Trade, error) {
+	e.refresh()
+	return e.sim.recentFills(strings.ToUpper(stockType)), nil
+}
+
+// GetTrades get the recent fills
+func (e *Paper) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// Cancel cancel an order
+func (e *Paper) Cancel(order Order) error {
+	order, err := e.sim.cancel(order.ID)
+	if err != nil {
+		return wrapError("CancelOrder", err)
+	}
+	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Paper) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
+	return true
+}
+
+// Ticker get market ticker & depth from the live exchange
+func (e *Paper) Ticker(stockType string, size int) (Ticker, error) {
+	return e.ticker(stockType, size)
+}
+
+// GetTicker get market ticker & depth
+func (e *Paper) GetTicker(stockType string, sizes ...interface{}) interface{} {
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetTicker", err)
+	}
+	return ticker
+}
+
+// Records get candlestick data from the live exchange
+func (e *Paper) Records(stockType, period string, size int) ([]Record, error) {
+	if typed, ok := e.Exchange.(TypedExchange); ok {
+		return typed.Records(stockType, period, size)
+	}
+	if records, ok := e.Exchange.GetRecords(stockType, period, size).([]Record); ok {
+		return records, nil
+	}
+	return nil, newError(ErrUnknown, "GetRecords", "can not get the records of ", stockType)
+}
+
+// GetRecords get candlestick data
+func (e *Paper) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
+}
+
+// Capabilities the market data comes from the live exchange, and the orders are filled by the simulator,
+// so every order type and the events are supported
+func (e *Paper) Capabilities() Capabilities {
+	live := e.Exchange.Capabilities()
+	periods := map[string]string{}
+	for _, period := range live.Periods {
+		periods[period] = ""
+	}
+	c := newCapabilities(e.GetType(), e, periods, simOrderTypes)
+	if !live.Streaming {
+		c.Streaming = false
+		methods := []string{}
+		for _, method := range c.Methods {
+			if method != "Subscribe" {
+				methods = append(methods, method)
+			}
+		}
+		c.Methods = methods
+	}
+	c.MarketTrades = live.MarketTrades
+	c.Backfill = live.Backfill
+	return c
+}
+
+// Stream subscribe the market data of stockType from the live exchange
+func (e *Paper) Stream(stockType string) error {
+	if s, ok := e.Exchange.(Streamer); ok {
+		return s.Stream(stockType)
+	}
+	return newError(ErrNotSupported, "Subscribe", "the exchange can not stream the market data")
+}
+
+// Subscribe subscribe the market data of stockType by websocket
+func (e *Paper) Subscribe(stockType string) bool {
+	if err := e.Stream(stockType); err != nil {
+		return e.fail("Subscribe", err)
+	}
+	return true
+}
+
+// CloseStreams close all the websocket streams of the live exchange
+func (e *Paper) CloseStreams() {
+	if s, ok := e.Exchange.(Streamer); ok {
+		s.CloseStreams()
+	}
+}
+
+// Listen the events are pushed by the simulator, so there is nothing to start
+func (e *Paper) Listen() error {
+	return nil
+}
+
+// Events fill the orders crossed by the live market and take all the order and balance events of the simulator
+func (e *Paper) Events() ([]Event, error) {
+	e.refresh()
+	return e.sim.drainEvents(), nil
+}
+
+// GetEvents get the order and balance changes since the last call
+func (e *Paper) GetEvents() interface{} {
+	if e.sim.callback {
+		return e.fail("GetEvents", errCallbacks)
+	}
+	events, err := e.Events()
+	if err != nil {
+		return e.fail("GetEvents", err)
+	}
+	return events
+}
+
+// UseCallbacks pass the events to onOrder and onBalance of the script only, GetEvents fails after it
+func (e *Paper) UseCallbacks() {
+	e.sim.callback = true
+}
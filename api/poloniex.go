@@ -20,19 +20,34 @@ type Poloniex struct {
 	tradeTypeMap     map[string]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
 	host             string
 	logger           model.Logger
 	option           Option
+	lastError        *Error
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+	limiter    *rateLimiter
+	httpClient *http.Client
 }
 
+// poloniexPeriods the periods of the records the exchange supports
+var poloniexPeriods = map[string]string{
+	"M5":  "300",
+	"M15": "900",
+	"M30": "1800",
+	"H2":  "7200",
+	"H4":  "14400",
+	"D":   "86400",
+}
+
+// poloniexOrderTypes the order types the exchange supports
+var poloniexOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
 // NewPoloniex create an exchange struct of poloniex
 func NewPoloniex(opt Option) Exchange {
-	return &Poloniex{
+	e := &Poloniex{
 		stockTypeMap: map[string]string{
 			"BTC/1CR":    "BTC_1CR",
 			"BTC/BBR":    "BTC_BBR",
@@ -152,25 +167,21 @@ func NewPoloniex(opt Option) Exchange {
 			"buy":  constant.TradeTypeBuy,
 			"sell": constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M5":  "300",
-			"M15": "900",
-			"M30": "1800",
-			"H2":  "7200",
-			"H4":  "14400",
-			"D":   "86400",
-		},
+		recordsPeriodMap: poloniexPeriods,
 		minAmountMap: map[string]float64{
 			"BTC/XMR": 0.0,
 		},
-		records: make(map[string][]Record),
-		host:    "https://poloniex.com/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: poloniexOrderTypes,
+		host:       baseURLOf(opt, "https://poloniex.com/"),
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter:    limiterOf(opt),
+		httpClient: newHTTPClient(opt),
 	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -188,30 +199,67 @@ func (e *Poloniex) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *Poloniex) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *Poloniex) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *Poloniex) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *Poloniex) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *Poloniex) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
+}
+
+// GetMarkets get all the markets of this exchange
+func (e *Poloniex) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the ticker of poloniex, the amounts and prices have 8 decimal places,
+// a pair like BTC_ETH means ETH priced in BTC
+func (e *Poloniex) loadMarkets() (markets []Market, err error) {
+	resp, err := get(e.httpClient, e.host+"public?command=returnTicker")
+	if err != nil {
+		return
+	}
+	json, err := simplejson.NewJson(resp)
+	if err != nil {
+		return
+	}
+	for symbol := range json.MustMap() {
+		quote, base, err := splitStockType(strings.Replace(symbol, "_", "/", -1))
+		if err != nil || conver.IntMust(json.Get(symbol).Get("isFrozen").Interface()) != 0 {
+			continue
+		}
+		markets = append(markets, Market{
+			StockType:       quote + "/" + base,
+			Symbol:          symbol,
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  8,
+			AmountPrecision: 8,
+			TickSize:        stepOf(8),
+			LotSize:         stepOf(8),
+		})
+	}
+	return
 }
 
 func (e *Poloniex) getAuthJSON(url string, params []string) (data []byte, json *simplejson.Json, err error) {
-	e.lastTimes++
 	params = append(params, fmt.Sprint("nonce=", time.Now().UnixNano()))
 	req, err := http.NewRequest("POST", url, strings.NewReader(strings.Join(params, "&")))
 	if err != nil {
@@ -220,8 +268,7 @@ func (e *Poloniex) getAuthJSON(url string, params []string) (data []byte, json *
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Key", e.option.AccessKey)
 	req.Header.Set("Sign", signSha512(params, e.option.SecretKey))
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(req)
+	resp, err := e.httpClient.Do(req)
 	if err != nil {
 		return
 	}
@@ -240,19 +287,42 @@ func (e *Poloniex) getAuthJSON(url string, params []string) (data []byte, json *
 	return
 }
 
-// GetAccount get the account detail of this exchange
-func (e *Poloniex) GetAccount() interface{} {
-	data, jsoner, err := e.getAuthJSON(e.host+"tradingApi", []string{
+// tradingAPI post a command to the trading api and check the error message of the response
+func (e *Poloniex) tradingAPI(method string, params []string) (data []byte, json *simplejson.Json, err error) {
+	data, json, err = e.getAuthJSON(e.host+"tradingApi", params)
+	if err != nil {
+		err = wrapError(method, err)
+		return
+	}
+	if errMsg := json.Get("error").MustString(); errMsg != "" {
+		err = newError(guessKind(errMsg), method, errMsg)
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *Poloniex) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Poloniex) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *Poloniex) Account() (Account, error) {
+	data, _, err := e.tradingAPI("GetAccount", []string{
 		"command=returnCompleteBalances",
 		"account=all",
 	})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
-	}
-	if errMsg := jsoner.Get("error").MustString(); errMsg != "" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", errMsg)
-		return false
+		return nil, err
 	}
 	resp := map[string]struct {
 		Available string
@@ -260,140 +330,182 @@ func (e *Poloniex) GetAccount() interface{} {
 		BtcValue  string
 	}{}
 	if err = json.Unmarshal(data, &resp); err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return nil, wrapError("GetAccount", err)
 	}
-	account := map[string]float64{}
+	account := Account{}
 	for k, v := range resp {
 		account[k] = conver.Float64Must(v.Available)
 		account["Frozen"+k] = conver.Float64Must(v.OnOrders)
 	}
+	return account, nil
+}
+
+// GetAccount get the account detail of this exchange
+func (e *Poloniex) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
 	return account
 }
 
-// Trade place an order
-func (e *Poloniex) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency,
+// poloniex has no market orders so it is an IOC limit order at the price of the worst level of the order book it eats
+func (e *Poloniex) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", "buy", constant.BUY, stockType, price, amount, opts, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", "sell", constant.SELL, stockType, price, amount, opts, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
 }
 
-func (e *Poloniex) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	_, json, err := e.getAuthJSON(e.host+"tradingApi", []string{
-		"command=buy",
-		"stockType=" + stockType,
-		fmt.Sprintf("rate=%f", price),
-		fmt.Sprintf("amount=%f", amount),
-	})
+// Trade place an order
+func (e *Poloniex) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
+		return e.fail("Trade", err)
 	}
-	if errMsg := json.Get("error").MustString(); errMsg != "" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", errMsg)
-		return false
-	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return fmt.Sprint(json.Get("orderNumber").Interface())
+	return id
 }
 
-func (e *Poloniex) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	_, json, err := e.getAuthJSON(e.host+"tradingApi", []string{
-		"command=sell",
-		"stockType=" + stockType,
-		fmt.Sprintf("rate=%f", price),
-		fmt.Sprintf("amount=%f", amount),
-	})
+func (e *Poloniex) place(method, command, logType, stockType string, price, amount float64, opts OrderOptions, msgs ...interface{}) (string, error) {
+	orderPrice, orderAmount := price, amount
+	if price <= 0 {
+		var err error
+		orderPrice, orderAmount, err = marketOrderOf(method, e.Ticker, e.markets, stockType, logType, amount)
+		if err != nil {
+			return "", err
+		}
+		opts.TimeInForce = constant.TimeInForceIOC
+		price = 0.0
+	}
+	params := []string{
+		"command=" + command,
+		"currencyPair=" + e.markets.symbol(stockType),
+		fmt.Sprintf("rate=%f", orderPrice),
+		fmt.Sprintf("amount=%f", orderAmount),
+	}
+	switch {
+	case opts.PostOnly:
+		params = append(params, "postOnly=1")
+	case opts.TimeInForce == constant.TimeInForceIOC:
+		params = append(params, "immediateOrCancel=1")
+	case opts.TimeInForce == constant.TimeInForceFOK:
+		params = append(params, "fillOrKill=1")
+	}
+	_, json, err := e.tradingAPI(method, params)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
+		return "", err
 	}
-	if errMsg := json.Get("error").MustString(); errMsg != "" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", errMsg)
-		return false
+	e.logger.Log(logType, stockType, price, amount, msgs...)
+	return fmt.Sprint(json.Get("orderNumber").Interface()), nil
+}
+
+// Order get details of an order
+func (e *Poloniex) Order(stockType, id string) (Order, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
 	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return fmt.Sprint(json.Get("orderNumber").Interface())
+	return Order{ID: id, StockType: stockType}, nil
 }
 
 // GetOrder get details of an order
 func (e *Poloniex) GetOrder(stockType, id string) interface{} {
-	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
-	return Order{ID: id, StockType: stockType}
+	return order
+}
+
+// Orders get all unfilled orders
+func (e *Poloniex) Orders(stockType string) ([]Order, error) {
+	return e.list("GetOrders", "returnOpenOrders", stockType)
 }
 
 // GetOrders get all unfilled orders
 func (e *Poloniex) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
+	return orders
+}
+
+// Trades get the recent fills
+func (e *Poloniex) Trades(stockType string) ([]Trade, error) {
 	stockType = strings.ToUpper(stockType)
-	orders := []Order{}
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
 	}
-	_, json, err := e.getAuthJSON(e.host+"tradingApi", []string{
-		"command=returnOpenOrders",
-		"stockType=" + stockType,
+	_, json, err := e.tradingAPI("GetTrades", []string{
+		"command=returnTradeHistory",
+		"currencyPair=" + e.markets.symbol(stockType),
 	})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
-	}
-	if errMsg := json.Get("error").MustString(); errMsg != "" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", errMsg)
-		return false
+		return nil, err
 	}
+	trades := []Trade{}
 	count := len(json.MustArray())
 	for i := 0; i < count; i++ {
-		orderJSON := json.GetIndex(i)
-		orders = append(orders, Order{
-			ID:         fmt.Sprint(orderJSON.Get("orderNumber").Interface()),
-			Price:      conver.Float64Must(orderJSON.Get("rate").Interface()),
-			Amount:     conver.Float64Must(orderJSON.Get("amount").Interface()),
-			DealAmount: 0.0,
-			TradeType:  e.tradeTypeMap[orderJSON.Get("type").MustString()],
-			StockType:  stockType,
+		tradeJSON := json.GetIndex(i)
+		tradeType := e.tradeTypeMap[tradeJSON.Get("type").MustString()]
+		// fee 是手续费率, 买入从基础币种扣除, 卖出从计价币种扣除
+		fee := conver.Float64Must(tradeJSON.Get("fee").Interface()) * conver.Float64Must(tradeJSON.Get("amount").Interface())
+		if tradeType == constant.TradeTypeSell {
+			fee = conver.Float64Must(tradeJSON.Get("fee").Interface()) * conver.Float64Must(tradeJSON.Get("total").Interface())
+		}
+		t, _ := time.Parse("2006-01-02 15:04:05", tradeJSON.Get("date").MustString())
+		trades = append(trades, Trade{
+			ID:          fmt.Sprint(tradeJSON.Get("tradeID").Interface()),
+			OrderID:     fmt.Sprint(tradeJSON.Get("orderNumber").Interface()),
+			Price:       conver.Float64Must(tradeJSON.Get("rate").Interface()),
+			Amount:      conver.Float64Must(tradeJSON.Get("amount").Interface()),
+			Fee:         fee,
+			FeeCurrency: feeCurrencyOf(stockType, tradeType),
+			Time:        t.Unix(),
+			TradeType:   tradeType,
+			StockType:   stockType,
 		})
 	}
-	return orders
+	return trades, nil
 }
 
-// GetTrades get all filled orders recently
+// GetTrades get the recent fills
 func (e *Poloniex) GetTrades(stockType string) interface{} {
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
+}
+
+// list get the orders returned by the command
+func (e *Poloniex) list(method, command, stockType string) ([]Order, error) {
 	stockType = strings.ToUpper(stockType)
 	orders := []Order{}
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, method, "unrecognized stockType: ", stockType)
 	}
-	_, json, err := e.getAuthJSON(e.host+"tradingApi", []string{
-		"command=returnTradeHistory",
-		"stockType=" + stockType,
+	_, json, err := e.tradingAPI(method, []string{
+		"command=" + command,
+		"currencyPair=" + e.markets.symbol(stockType),
 	})
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, ", err)
-		return false
-	}
-	if errMsg := json.Get("error").MustString(); errMsg != "" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetTrades() error, ", errMsg)
-		return false
+		return nil, err
 	}
 	count := len(json.MustArray())
 	for i := 0; i < count; i++ {
@@ -407,47 +519,81 @@ func (e *Poloniex) GetTrades(stockType string) interface{} {
 			StockType:  stockType,
 		})
 	}
-	return orders
+	return orders, nil
 }
 
-// CancelOrder cancel an order
-func (e *Poloniex) CancelOrder(order Order) bool {
-	_, json, err := e.getAuthJSON(e.host+"tradingApi", []string{
-		"command=cancelOrder",
-		"orderNumber=" + order.ID,
-	})
+// MarketTrades get the latest public trades of the market
+func (e *Poloniex) MarketTrades(stockType string, size int) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetMarketTrades", "unrecognized stockType: ", stockType)
+	}
+	resp, err := get(e.httpClient, fmt.Sprintf("%vpublic?command=returnTradeHistory&currencyPair=%v", e.host, e.markets.symbol(stockType)))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+		return nil, wrapError("GetMarketTrades", err)
 	}
-	if errMsg := json.Get("error").MustString(); errMsg != "" {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", errMsg)
-		return false
+	json, err := simplejson.NewJson(resp)
+	if err != nil {
+		return nil, wrapError("GetMarketTrades", err)
+	}
+	trades := []Trade{}
+	// poloniex 返回的成交记录是从新到旧的
+	for i := len(json.MustArray()) - 1; i >= 0; i-- {
+		tradeJSON := json.GetIndex(i)
+		t, _ := time.Parse("2006-01-02 15:04:05", tradeJSON.Get("date").MustString())
+		trades = append(trades, Trade{
+			ID:        fmt.Sprint(tradeJSON.Get("tradeID").Interface()),
+			Price:     conver.Float64Must(tradeJSON.Get("rate").Interface()),
+			Amount:    conver.Float64Must(tradeJSON.Get("amount").Interface()),
+			Time:      t.Unix(),
+			TradeType: e.tradeTypeMap[tradeJSON.Get("type").MustString()],
+			StockType: stockType,
+		})
+	}
+	if size > 0 && len(trades) > size {
+		trades = trades[len(trades)-size:]
+	}
+	return trades, nil
+}
+
+// Cancel cancel an order
+func (e *Poloniex) Cancel(order Order) error {
+	if _, _, err := e.tradingAPI("CancelOrder", []string{
+		"command=cancelOrder",
+		"orderNumber=" + order.ID,
+	}); err != nil {
+		return err
 	}
 	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Poloniex) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *Poloniex) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
-	e.lastTimes++
+// Ticker get market ticker & depth
+func (e *Poloniex) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	size := 20
-	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
-		size = conver.IntMust(sizes[0])
+	if size <= 0 {
+		size = 20
 	}
-	resp, err := get(fmt.Sprintf("%vpublic?command=returnOrderBook&stockType=%v&depth=%v", e.host, e.stockTypeMap[stockType], size))
+	resp, err := get(e.httpClient, fmt.Sprintf("%vpublic?command=returnOrderBook&currencyPair=%v&depth=%v", e.host, e.markets.symbol(stockType), size))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	json, err := simplejson.NewJson(resp)
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	depthsJSON := json.Get("bids")
@@ -467,7 +613,7 @@ func (e *Poloniex) getTicker(stockType string, sizes ...interface{}) (ticker Tic
 		})
 	}
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
 	ticker.Buy = ticker.Bids[0].Price
@@ -478,78 +624,87 @@ func (e *Poloniex) getTicker(stockType string, sizes ...interface{}) (ticker Tic
 
 // GetTicker get market ticker & depth
 func (e *Poloniex) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
-// GetRecords get candlestick data
-func (e *Poloniex) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
-	e.lastTimes++
+// Records get candlestick data
+func (e *Poloniex) Records(stockType, period string, size int) ([]Record, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
 	}
 	if _, ok := e.recordsPeriodMap[period]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, unrecognized period: ", period)
-		return false
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
 	}
-	size := 200
-	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
-		size = conver.IntMust(sizes[0])
+	if size <= 0 {
+		size = 200
 	}
 	interval := conver.Int64Must(e.recordsPeriodMap[period])
-	start := time.Now().Unix() - interval*int64(size)
+	start := time.Now().Unix() - interval*int64(e.store.missing(stockType, period, size))
 	if start < 0 {
 		start = 0
 	}
-	resp, err := get(fmt.Sprintf("%vpublic?command=returnChartData&stockType=%v&start=%v&end=9999999999&period=%v", e.host, e.stockTypeMap[stockType], start, e.recordsPeriodMap[period]))
+	recordsNew, err := e.chartData(stockType, period, start, 9999999999)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *Poloniex) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	interval := conver.Int64Must(e.recordsPeriodMap[period])
+	records, err := e.chartData(stockType, period, since, since+interval*int64(size-1))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, ", err)
-		return false
+		return nil, wrapError("GetRecords", err)
+	}
+	return records, nil
+}
+
+// chartData get the records between start and end
+func (e *Poloniex) chartData(stockType, period string, start, end int64) (records []Record, err error) {
+	resp, err := get(e.httpClient, fmt.Sprintf("%vpublic?command=returnChartData&currencyPair=%v&start=%v&end=%v&period=%v", e.host, e.markets.symbol(stockType), start, end, e.recordsPeriodMap[period]))
+	if err != nil {
+		return
 	}
 	json, err := simplejson.NewJson(resp)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetRecords() error, ", err)
-		return false
-	}
-	timeLast := int64(0)
-	if len(e.records[period]) > 0 {
-		timeLast = e.records[period][len(e.records[period])-1].Time
-	}
-	recordsNew := []Record{}
-	for i := len(json.MustArray()); i > 0; i-- {
-		recordJSON := json.GetIndex(i - 1)
-		recordTime := recordJSON.Get("date").MustInt64()
-		if recordTime > timeLast {
-			recordsNew = append([]Record{{
-				Time:   recordTime,
-				Open:   recordJSON.Get("open").MustFloat64(),
-				High:   recordJSON.Get("high").MustFloat64(),
-				Low:    recordJSON.Get("low").MustFloat64(),
-				Close:  recordJSON.Get("close").MustFloat64(),
-				Volume: recordJSON.Get("volume").MustFloat64(),
-			}}, recordsNew...)
-		} else if timeLast > 0 && recordTime == timeLast {
-			e.records[period][len(e.records[period])-1] = Record{
-				Time:   recordTime,
-				Open:   recordJSON.Get("open").MustFloat64(),
-				High:   recordJSON.Get("high").MustFloat64(),
-				Low:    recordJSON.Get("low").MustFloat64(),
-				Close:  recordJSON.Get("close").MustFloat64(),
-				Volume: recordJSON.Get("volume").MustFloat64(),
-			}
-		} else {
-			break
+		return
+	}
+	records = []Record{}
+	for i := 0; i < len(json.MustArray()); i++ {
+		recordJSON := json.GetIndex(i)
+		if recordJSON.Get("date").MustInt64() <= 0 {
+			continue
 		}
+		records = append(records, Record{
+			Time:   recordJSON.Get("date").MustInt64(),
+			Open:   recordJSON.Get("open").MustFloat64(),
+			High:   recordJSON.Get("high").MustFloat64(),
+			Low:    recordJSON.Get("low").MustFloat64(),
+			Close:  recordJSON.Get("close").MustFloat64(),
+			Volume: recordJSON.Get("volume").MustFloat64(),
+		})
 	}
-	e.records[period] = append(e.records[period], recordsNew...)
-	if len(e.records[period]) > size {
-		e.records[period] = e.records[period][len(e.records[period])-size : len(e.records[period])]
+	return
+}
+
+// GetRecords get candlestick data
+func (e *Poloniex) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
 	}
-	return e.records[period]
+	return records
 }
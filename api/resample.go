@@ -0,0 +1,126 @@
+package api
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// periodPattern matches the periods like M, M3, H4, D3 and W2
+var periodPattern = regexp.MustCompile(`^([MHDW])(\d*)$`)
+
+// periodUnits the length of the units of the periods in seconds
+var periodUnits = map[string]int64{
+	"M": 60,
+	"H": 3600,
+	"D": 86400,
+	"W": 604800,
+}
+
+// weekOffset the weeks start from Monday 00:00 UTC, the unix epoch is a Thursday
+var weekOffset = int64(4 * 86400)
+
+// periodLength get the length of a period in seconds, the custom periods like M3 and H4 are supported, 0 if it is not a period
+func periodLength(period string) int64 {
+	if length, ok := periodSeconds[period]; ok {
+		return length
+	}
+	m := periodPattern.FindStringSubmatch(period)
+	if m == nil {
+		return 0
+	}
+	n := int64(1)
+	if m[2] != "" {
+		n, _ = strconv.ParseInt(m[2], 10, 64)
+	}
+	return n * periodUnits[m[1]]
+}
+
+// periodStart get the start time of the period which t is in
+func periodStart(t, length int64) int64 {
+	offset := int64(0)
+	if length%periodUnits["W"] == 0 {
+		offset = weekOffset
+	}
+	return t - ((t-offset)%length+length)%length
+}
+
+// Resample merge the records sorted by time into a longer period, the period must be a multiple of the period of the records,
+// the last record may be unfinished as the source
+func Resample(records []Record, period string) ([]Record, error) {
+	length := periodLength(period)
+	if length <= 0 {
+		return nil, newError(ErrNotSupported, "Resample", "unrecognized period: ", period)
+	}
+	if source := sourceLength(records); source > 0 && length%source != 0 {
+		return nil, newError(ErrNotSupported, "Resample", "the period ", period, " is not a multiple of the records' period of ", source, " seconds")
+	}
+	resampled := []Record{}
+	for _, r := range records {
+		start := periodStart(r.Time, length)
+		if n := len(resampled); n > 0 && resampled[n-1].Time == start {
+			last := &resampled[n-1]
+			if r.High > last.High {
+				last.High = r.High
+			}
+			if r.Low < last.Low {
+				last.Low = r.Low
+			}
+			last.Close = r.Close
+			last.Volume += r.Volume
+			continue
+		}
+		resampled = append(resampled, Record{
+			Time:   start,
+			Open:   r.Open,
+			High:   r.High,
+			Low:    r.Low,
+			Close:  r.Close,
+			Volume: r.Volume,
+		})
+	}
+	return resampled, nil
+}
+
+// sourceLength infer the period of the records in seconds from the shortest interval between them, 0 if it is unknown
+func sourceLength(records []Record) (length int64) {
+	for i := 1; i < len(records); i++ {
+		if d := records[i].Time - records[i-1].Time; d > 0 && (length == 0 || d < length) {
+			length = d
+		}
+	}
+	return
+}
+
+// resampleRecords build the records of a period the exchange does not support from the longest supported period which divides it
+func resampleRecords(records func(stockType, period string, size int) ([]Record, error), periods map[string]string, stockType, period string, size int) ([]Record, error) {
+	length := periodLength(period)
+	base, baseLength := "", int64(0)
+	for p := range periods {
+		if l := periodLength(p); l > baseLength && l < length && length%l == 0 {
+			base, baseLength = p, l
+		}
+	}
+	if base == "" {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	ratio := int(length / baseLength)
+	// 多取一个周期, 第一根K线可能是不完整的
+	list, err := records(stockType, base, (size+1)*ratio)
+	if err != nil || len(list) == 0 {
+		return list, err
+	}
+	resampled, err := Resample(list, period)
+	if err != nil {
+		return nil, err
+	}
+	if resampled[0].Time != list[0].Time {
+		resampled = resampled[1:]
+	}
+	if len(resampled) > size {
+		resampled = resampled[len(resampled)-size:]
+	}
+	return resampled, nil
+}
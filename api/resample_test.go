@@ -0,0 +1,21 @@
+package api
+
+import "testing"
+
+func TestResample(t *testing.T) {
+	records := []Record{}
+	for i := int64(0); i < 6; i++ {
+		records = append(records, Record{Time: 1687255200 + i*300, Open: float64(i), High: float64(i + 1), Low: float64(i), Close: float64(i), Volume: 1})
+	}
+	resampled, err := Resample(records, "M15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resampled) != 2 || resampled[0].Time != 1687255200 || resampled[0].High != 3 || resampled[1].Open != 3 || resampled[1].Volume != 3 {
+		t.Errorf("unexpected records %+v", resampled)
+	}
+	// M5 的K线不能合并为 M3
+	if _, err := Resample(records, "M3"); ErrorKind(err) != ErrNotSupported {
+		t.Errorf("expect a not supported error for M3 of M5 records, got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+)
+
+// simulator is an in-memory matching engine, it keeps a virtual account and fills orders against market prices
+type simulator struct {
+	mutex    sync.Mutex
+	balance  map[string]float64 //可用资金
+	frozen   map[string]float64 //冻结资金
+	orders   []*simOrder        //未完成订单
+	trades   []Order            //已完成订单
+	fills    []Trade            //成交记录
+	events   []Event            //订单和资金变化的事件队列
+	callback bool               //事件交给策略的回调函数
+	fee      float64            //手续费率
+	lastID   int64
+	now      func() int64 //当前的unix时间戳, 回测时是虚拟时钟
+	logger   model.Logger
+}
+
+// simOrderTypes the order types the simulator supports
+var simOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC, constant.OrderTypeFOK}
+
+type simOrder struct {
+	Order
+	frozen float64       //这个订单冻结的资金
+	msgs   []interface{} //成交时写入日志的信息
+}
+
+func newSimulator(balance map[string]float64, fee float64, logger model.Logger) *simulator {
+	s := &simulator{
+		balance: make(map[string]float64),
+		frozen:  make(map[string]float64),
+		fee:     fee,
+		logger:  logger,
+		now: func() int64 {
+			return time.Now().Unix()
+		},
+	}
+	for currency, amount := range balance {
+		s.balance[strings.ToUpper(currency)] = amount
+	}
+	return s
+}
+
+// splitStockType split a stockType like "BTC/USDT" into the base and the quote currency
+func splitStockType(stockType string) (base, quote string, err error) {
+	currencies := strings.Split(strings.ToUpper(stockType), "/")
+	if len(currencies) != 2 || currencies[0] == "" || currencies[1] == "" {
+		err = fmt.Errorf("unrecognized stockType: %v", stockType)
+		return
+	}
+	return currencies[0], currencies[1], nil
+}
+
+// account get the account detail, like {"USDT": 100, "FrozenUSDT": 0}
+func (s *simulator) account() Account {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	account := Account{}
+	for currency, amount := range s.balance {
+		account[currency] = amount
+		account["Frozen"+currency] = s.frozen[currency]
+	}
+	return account
+}
+
+// place place an order, an order which crosses the market (bid/ask) is filled at once,
+// if price <= 0 it is a market order and the amount of a market buy order is in quote currency,
+// a post-only order which crosses the market is rejected and an IOC or FOK order which does not is canceled
+func (s *simulator) place(tradeType, stockType string, price, amount, bid, ask float64, opts OrderOptions, msgs ...interface{}) (id string, err error) {
+	base, quote, err := splitStockType(stockType)
+	if err != nil {
+		return
+	}
+	if amount <= 0 {
+		err = fmt.Errorf("invalid amount: %v", amount)
+		return
+	}
+	if price <= 0 && (bid <= 0 || ask <= 0) {
+		err = fmt.Errorf("can not get the market price of %v", stockType)
+		return
+	}
+	crosses := false
+	switch tradeType {
+	case constant.TradeTypeBuy:
+		crosses = ask > 0 && (price <= 0 || price >= ask)
+	case constant.TradeTypeSell:
+		crosses = bid > 0 && (price <= 0 || price <= bid)
+	}
+	if opts.PostOnly && crosses {
+		err = fmt.Errorf("the post-only order would be filled at once: %v %v", tradeType, price)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	order := &simOrder{
+		Order: Order{
+			Price:     price,
+			Amount:    amount,
+			TradeType: tradeType,
+			StockType: stockType,
+		},
+		msgs: msgs,
+	}
+	switch tradeType {
+	case constant.TradeTypeBuy:
+		order.frozen = amount
+		if price > 0 {
+			order.frozen = price * amount
+		}
+		if s.balance[quote] < order.frozen {
+			err = fmt.Errorf("insufficient %v balance: %v < %v", quote, s.balance[quote], order.frozen)
+			return
+		}
+		s.balance[quote] -= order.frozen
+		s.frozen[quote] += order.frozen
+	case constant.TradeTypeSell:
+		order.frozen = amount
+		if s.balance[base] < order.frozen {
+			err = fmt.Errorf("insufficient %v balance: %v < %v", base, s.balance[base], order.frozen)
+			return
+		}
+		s.balance[base] -= order.frozen
+		s.frozen[base] += order.frozen
+	default:
+		err = fmt.Errorf("unrecognized tradeType: %v", tradeType)
+		return
+	}
+	s.lastID++
+	order.ID = fmt.Sprint(s.lastID)
+	s.orders = append(s.orders, order)
+	s.notify(order.Order, constant.OrderStatusNew)
+	switch {
+	case crosses && tradeType == constant.TradeTypeBuy:
+		s.fill(order, ask)
+	case crosses && tradeType == constant.TradeTypeSell:
+		s.fill(order, bid)
+	case opts.TimeInForce == constant.TimeInForceIOC || opts.TimeInForce == constant.TimeInForceFOK:
+		// 订单总是全部成交, 所以 IOC 和 FOK 没有成交时都是全部撤销
+		s.cancelAt(len(s.orders) - 1)
+	}
+	return order.ID, nil
+}
+
+// match fill the resting limit orders of stockType crossed by the market,
+// low is the lowest price sellers accepted and high is the highest price buyers paid
+func (s *simulator) match(stockType string, low, high float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, order := range s.orders {
+		if order.StockType != stockType || order.Price <= 0 {
+			continue
+		}
+		if order.TradeType == constant.TradeTypeBuy && low > 0 && low <= order.Price {
+			s.fill(order, order.Price)
+		} else if order.TradeType == constant.TradeTypeSell && high > 0 && high >= order.Price {
+			s.fill(order, order.Price)
+		}
+	}
+	orders := []*simOrder{}
+	for _, order := range s.orders {
+		if order.DealAmount <= 0 {
+			orders = append(orders, order)
+		}
+	}
+	s.orders = orders
+}
+
+// fill fill an order entirely at the price, the caller must hold the mutex
+func (s *simulator) fill(order *simOrder, price float64) {
+	base, quote, _ := splitStockType(order.StockType)
+	switch order.TradeType {
+	case constant.TradeTypeBuy:
+		amount := order.Amount
+		cost := price * amount
+		if order.Price <= 0 {
+			amount = order.Amount / price
+			cost = order.Amount
+		}
+		order.Fee = amount * s.fee
+		s.frozen[quote] -= order.frozen
+		s.balance[quote] += order.frozen - cost
+		s.balance[base] += amount - order.Fee
+		order.DealAmount = amount
+		s.logger.Log(constant.BUY, order.StockType, price, amount, order.msgs...)
+		s.addFill(order.Order, price, amount, base)
+	case constant.TradeTypeSell:
+		order.Fee = price * order.Amount * s.fee
+		s.frozen[base] -= order.frozen
+		s.balance[quote] += price*order.Amount - order.Fee
+		order.DealAmount = order.Amount
+		s.logger.Log(constant.SELL, order.StockType, price, order.Amount, order.msgs...)
+		s.addFill(order.Order, price, order.Amount, quote)
+	}
+	order.Price = price
+	s.trades = append(s.trades, order.Order)
+	s.notify(order.Order, constant.OrderStatusFilled)
+}
+
+// notify queue the order event and the balance event of its currencies, the caller must hold the mutex
+func (s *simulator) notify(order Order, status string) {
+	base, quote, _ := splitStockType(order.StockType)
+	s.events = append(s.events, Event{
+		Type:   constant.EventOrder,
+		Time:   s.now(),
+		Status: status,
+		Order:  order,
+	}, Event{
+		Type: constant.EventBalance,
+		Time: s.now(),
+		Account: Account{
+			base:             s.balance[base],
+			"Frozen" + base:  s.frozen[base],
+			quote:            s.balance[quote],
+			"Frozen" + quote: s.frozen[quote],
+		},
+	})
+	if len(s.events) > eventsLimit {
+		s.events = s.events[len(s.events)-eventsLimit:]
+	}
+}
+
+// drainEvents take all the events out of the queue
+func (s *simulator) drainEvents() []Event {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	events := s.events
+	s.events = []Event{}
+	if events == nil {
+		events = []Event{}
+	}
+	return events
+}
+
+// addFill keep the fill of an order, the caller must hold the mutex
+func (s *simulator) addFill(order Order, price, amount float64, feeCurrency string) {
+	s.fills = append(s.fills, Trade{
+		ID:          fmt.Sprint(len(s.fills) + 1),
+		OrderID:     order.ID,
+		Price:       price,
+		Amount:      amount,
+		Fee:         order.Fee,
+		FeeCurrency: feeCurrency,
+		Time:        s.now(),
+		TradeType:   order.TradeType,
+		StockType:   order.StockType,
+	})
+}
+
+// order get details of an order
+func (s *simulator) order(id string) (order Order, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, o := range s.orders {
+		if o.ID == id && o.DealAmount <= 0 {
+			return o.Order, nil
+		}
+	}
+	for _, o := range s.trades {
+		if o.ID == id {
+			return o, nil
+		}
+	}
+	err = fmt.Errorf("order(id = %v) not exist", id)
+	return
+}
+
+// openOrders get all unfilled orders of stockType
+func (s *simulator) openOrders(stockType string) []Order {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	orders := []Order{}
+	for _, o := range s.orders {
+		if o.StockType == stockType && o.DealAmount <= 0 {
+			orders = append(orders, o.Order)
+		}
+	}
+	return orders
+}
+
+// recentFills get the recent fills of stockType
+func (s *simulator) recentFills(stockType string) []Trade {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	trades := []Trade{}
+	for i := len(s.fills); i > 0 && len(trades) < 200; i-- {
+		if s.fills[i-1].StockType == stockType {
+			trades = append(trades, s.fills[i-1])
+		}
+	}
+	return trades
+}
+
+// stockTypes get all stockTypes which have unfilled orders
+func (s *simulator) stockTypes() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stockTypes := []string{}
+	exist := make(map[string]bool)
+	for _, o := range s.orders {
+		if !exist[o.StockType] {
+			exist[o.StockType] = true
+			stockTypes = append(stockTypes, o.StockType)
+		}
+	}
+	return stockTypes
+}
+
+// cancel cancel an unfilled order and unfreeze its balance
+func (s *simulator) cancel(id string) (order Order, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, o := range s.orders {
+		if o.ID != id || o.DealAmount > 0 {
+			continue
+		}
+		return s.cancelAt(i), nil
+	}
+	err = fmt.Errorf("order(id = %v) not exist or has been filled", id)
+	return
+}
+
+// cancelAt cancel the i-th unfilled order and unfreeze its balance, the caller must hold the mutex
+func (s *simulator) cancelAt(i int) Order {
+	o := s.orders[i]
+	base, quote, _ := splitStockType(o.StockType)
+	currency := base
+	if o.TradeType == constant.TradeTypeBuy {
+		currency = quote
+	}
+	s.frozen[currency] -= o.frozen
+	s.balance[currency] += o.frozen
+	s.orders = append(s.orders[:i], s.orders[i+1:]...)
+	s.notify(o.Order, constant.OrderStatusCanceled)
+	return o.Order
+}
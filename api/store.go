@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+)
+
+// backfillPage the records got by every request of a backfill
+var backfillPage = 500
+
+// RecordsPager is implemented by the exchanges which can get the records since a time, it is used to backfill the history
+type RecordsPager interface {
+	RecordsSince(stockType, period string, since int64, size int) ([]Record, error) //返回从 since 开始的最多 size 根K线
+}
+
+// recordStore keeps the records of an exchange in the database, so GetRecords only fetches the missing tail,
+// the records are kept in memory if the database fails and they are not stored if the exchange uses another host like a testnet
+type recordStore struct {
+	mutex        sync.Mutex
+	exchangeType string
+	disabled     bool                //不保存到数据库, 自定义 BaseURL 的K线和主网的不同
+	cache        map[string][]Record //数据库出错时使用的内存缓存
+	logger       model.Logger
+}
+
+func newRecordStore(opt Option, logger model.Logger) *recordStore {
+	return &recordStore{
+		exchangeType: opt.Type,
+		disabled:     opt.BaseURL != "",
+		cache:        make(map[string][]Record),
+		logger:       logger,
+	}
+}
+
+// missing get how many records should be fetched to fill the latest size records,
+// the last stored record is fetched again because it may be unfinished
+func (s *recordStore) missing(stockType, period string, size int) int {
+	length := periodLength(period)
+	if s.disabled || length <= 0 {
+		return size
+	}
+	_, last, count, err := model.RecordsRange(s.exchangeType, stockType, period)
+	if err != nil || count < int64(size) {
+		return size
+	}
+	if n := int((time.Now().Unix()-last)/length) + 1; n < size {
+		return n
+	}
+	return size
+}
+
+// merge save the fetched records and get the latest size records
+func (s *recordStore) merge(stockType, period string, recordsNew []Record, size int) ([]Record, error) {
+	if s.disabled {
+		return mergeRecords(nil, recordsNew, size), nil
+	}
+	if err := saveRecords(s.exchangeType, stockType, period, recordsNew); err != nil {
+		return s.fallback(stockType, period, recordsNew, size, err), nil
+	}
+	stored, err := model.ListRecords(s.exchangeType, stockType, period, 0, size)
+	if err != nil {
+		return s.fallback(stockType, period, recordsNew, size, err), nil
+	}
+	records := []Record{}
+	for _, r := range stored {
+		records = append(records, Record{
+			Time:   r.Time,
+			Open:   r.Open,
+			High:   r.High,
+			Low:    r.Low,
+			Close:  r.Close,
+			Volume: r.Volume,
+		})
+	}
+	return records, nil
+}
+
+// fallback keep the records in memory when the database fails
+func (s *recordStore) fallback(stockType, period string, recordsNew []Record, size int, err error) []Record {
+	s.logger.Log(constant.ERROR, stockType, 0.0, 0.0, "GetRecords() store error, ", err)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := stockType + period
+	s.cache[key] = mergeRecords(s.cache[key], recordsNew, size)
+	return s.cache[key]
+}
+
+// saveRecords save the records of an exchange into the database
+func saveRecords(exchangeType, stockType, period string, records []Record) error {
+	stored := []model.Record{}
+	for _, r := range records {
+		stored = append(stored, model.Record{
+			ExchangeType: exchangeType,
+			StockType:    stockType,
+			Period:       period,
+			Time:         r.Time,
+			Open:         r.Open,
+			High:         r.High,
+			Low:          r.Low,
+			Close:        r.Close,
+			Volume:       r.Volume,
+		})
+	}
+	return model.SaveRecords(stored)
+}
+
+// Backfill download the records of stockType since start into the store and return how many records are stored,
+// the exchanges which can not get the records since a time only store the latest records they give
+func Backfill(e Exchange, stockType, period string, start int64, progress func(stored int, last int64)) (int, error) {
+	stockType = strings.ToUpper(stockType)
+	length := periodLength(period)
+	if length <= 0 {
+		return 0, newError(ErrNotSupported, "Backfill", "unrecognized period: ", period)
+	}
+	pager, ok := e.(RecordsPager)
+	if !ok {
+		typed, ok := e.(TypedExchange)
+		if !ok {
+			return 0, newError(ErrNotSupported, "Backfill", "the exchange can not get the records")
+		}
+		records, err := typed.Records(stockType, period, int((time.Now().Unix()-start)/length)+1)
+		if err != nil {
+			return 0, err
+		}
+		if progress != nil && len(records) > 0 {
+			progress(len(records), records[len(records)-1].Time)
+		}
+		return len(records), nil
+	}
+	stored := 0
+	for since := start; since < time.Now().Unix(); {
+		records, err := pager.RecordsSince(stockType, period, since, backfillPage)
+		if err != nil {
+			return stored, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		if err = saveRecords(e.GetType(), stockType, period, records); err != nil {
+			return stored, wrapError("Backfill", err)
+		}
+		stored += len(records)
+		last := records[len(records)-1].Time
+		if progress != nil {
+			progress(stored, last)
+		}
+		if last < since {
+			return stored, newError(ErrUnknown, "Backfill", fmt.Sprintf("the exchange returned the records before %v", since))
+		}
+		since = last + length
+	}
+	return stored, nil
+}
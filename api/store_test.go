@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+)
+
+func TestSaveRecords(t *testing.T) {
+	start := int64(1687255200)
+	records := []Record{}
+	for i := int64(0); i < 150; i++ {
+		records = append(records, Record{Time: start + i*60, Open: 100, High: 101, Low: 99, Close: 100, Volume: 1})
+	}
+	if err := saveRecords("store-test", "BTC/USDT", "M", records); err != nil {
+		t.Fatal(err)
+	}
+	// 已经保存的K线被更新, 新的K线被插入
+	update := []Record{
+		{Time: start + 149*60, Open: 100, High: 102, Low: 99, Close: 101, Volume: 2},
+		{Time: start + 150*60, Open: 101, High: 103, Low: 100, Close: 102, Volume: 1},
+		{Time: start + 150*60, Open: 101, High: 103, Low: 100, Close: 102, Volume: 1},
+	}
+	if err := saveRecords("store-test", "BTC/USDT", "M", update); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := model.ListRecords("store-test", "BTC/USDT", "M", 0, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 151 {
+		t.Fatalf("expect 151 records, got %d", len(stored))
+	}
+	if last := stored[149]; last.Close != 101 || last.Volume != 2 {
+		t.Errorf("expect the record at %d updated, got %+v", last.Time, last)
+	}
+	if last := stored[150]; last.Time != start+150*60 || last.Close != 102 {
+		t.Errorf("unexpected last record %+v", last)
+	}
+}
+
+func TestRecordStoreBaseURL(t *testing.T) {
+	s := newRecordStore(Option{Type: "store-test", BaseURL: "https://testnet.example.com"}, model.Logger{ExchangeType: constant.Binance})
+	records := []Record{{Time: 1687255200, Close: 100}, {Time: 1687255260, Close: 101}}
+	merged, err := s.merge("ETH/USDT", "M", records, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0].Close != 101 {
+		t.Errorf("unexpected records %+v", merged)
+	}
+	// 自定义地址的K线不保存到数据库
+	if _, _, count, err := model.RecordsRange("store-test", "ETH/USDT", "M"); err != nil || count != 0 {
+		t.Errorf("expect no stored records, got %d, error: %v", count, err)
+	}
+}
@@ -0,0 +1,404 @@
+package api
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/miaolz123/conver"
+	"golang.org/x/net/proxy"
+	"golang.org/x/net/websocket"
+)
+
+// streamTimeout the seconds without any message before the order book is taken as stale
+var streamTimeout int64 = 30
+
+// errResync means the order book is out of sync and must be rebuilt from a new snapshot
+var errResync = fmt.Errorf("the order book is out of sync")
+
+// streamProtocol is the exchange-specific part of a websocket stream
+type streamProtocol interface {
+	dial(symbol string) (*websocket.Conn, error)                        //连接并订阅深度和成交频道
+	handle(conn *websocket.Conn, message []byte, book *orderBook) error //处理一条消息, 返回 errResync 时重新同步订单簿
+}
+
+// bookLevel is a price level of an order book, the raw strings are kept for the checksum
+type bookLevel struct {
+	Price     float64
+	Amount    float64
+	RawPrice  string
+	RawAmount string
+}
+
+// orderBook is the order book of a stockType maintained by a websocket stream
+type orderBook struct {
+	mutex   sync.Mutex
+	bids    map[float64]bookLevel //买单, 按价格索引
+	asks    map[float64]bookLevel //卖单, 按价格索引
+	last    float64               //最新成交价
+	seq     int64                 //最后一次更新的序号
+	ready   bool                  //是否已经同步了快照
+	updated int64                 //最后一次收到消息的unix时间戳
+}
+
+func newOrderBook() *orderBook {
+	b := &orderBook{}
+	b.reset()
+	return b
+}
+
+// reset clear the order book, it is not ready until a new snapshot is received
+func (b *orderBook) reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.bids = make(map[float64]bookLevel)
+	b.asks = make(map[float64]bookLevel)
+	b.seq = 0
+	b.ready = false
+}
+
+// snapshot replace the order book with a snapshot, the levels are like [["price", "amount"], ...]
+func (b *orderBook) snapshot(bids, asks []interface{}, seq int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.bids = make(map[float64]bookLevel)
+	b.asks = make(map[float64]bookLevel)
+	b.apply(b.bids, bids)
+	b.apply(b.asks, asks)
+	b.seq = seq
+	b.ready = true
+	b.updated = time.Now().Unix()
+}
+
+// update apply the changed levels to the order book, a level with zero amount is removed
+func (b *orderBook) update(bids, asks []interface{}, seq int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.apply(b.bids, bids)
+	b.apply(b.asks, asks)
+	b.seq = seq
+	b.updated = time.Now().Unix()
+}
+
+// apply apply the levels to a side, the caller must hold the mutex
+func (b *orderBook) apply(side map[float64]bookLevel, levels []interface{}) {
+	for _, l := range levels {
+		level, ok := l.([]interface{})
+		if !ok || len(level) < 2 {
+			continue
+		}
+		price := conver.Float64Must(level[0])
+		amount := conver.Float64Must(level[1])
+		if amount <= 0 {
+			delete(side, price)
+			continue
+		}
+		side[price] = bookLevel{
+			Price:     price,
+			Amount:    amount,
+			RawPrice:  fmt.Sprint(level[0]),
+			RawAmount: fmt.Sprint(level[1]),
+		}
+	}
+}
+
+// trade keep the price of the latest trade
+func (b *orderBook) trade(price float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.last = price
+	b.updated = time.Now().Unix()
+}
+
+// sequence get the sequence of the last update and if the order book is ready
+func (b *orderBook) sequence() (int64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.seq, b.ready
+}
+
+// levels get the best size levels of both sides, all the levels if size <= 0
+func (b *orderBook) levels(size int) (bids, asks []bookLevel) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, level := range b.bids {
+		bids = append(bids, level)
+	}
+	for _, level := range b.asks {
+		asks = append(asks, level)
+	}
+	sort.Slice(bids, func(i, j int) bool {
+		return bids[i].Price > bids[j].Price
+	})
+	sort.Slice(asks, func(i, j int) bool {
+		return asks[i].Price < asks[j].Price
+	})
+	if size > 0 && len(bids) > size {
+		bids = bids[:size]
+	}
+	if size > 0 && len(asks) > size {
+		asks = asks[:size]
+	}
+	return
+}
+
+// ticker get the ticker from the order book, it fails if the order book is not ready or stale
+func (b *orderBook) ticker(size int) (ticker Ticker, ok bool) {
+	b.mutex.Lock()
+	ready := b.ready && time.Now().Unix()-b.updated < streamTimeout
+	last := b.last
+	b.mutex.Unlock()
+	if !ready {
+		return
+	}
+	bids, asks := b.levels(size)
+	if len(bids) < 1 || len(asks) < 1 {
+		return
+	}
+	for _, level := range bids {
+		ticker.Bids = append(ticker.Bids, OrderBook{Price: level.Price, Amount: level.Amount})
+	}
+	for _, level := range asks {
+		ticker.Asks = append(ticker.Asks, OrderBook{Price: level.Price, Amount: level.Amount})
+	}
+	ticker.Buy = ticker.Bids[0].Price
+	ticker.Sell = ticker.Asks[0].Price
+	ticker.Mid = (ticker.Buy + ticker.Sell) / 2
+	ticker.Last = last
+	return ticker, true
+}
+
+// stream keeps the websocket connections and the order books of an exchange
+type stream struct {
+	mutex    sync.Mutex
+	protocol streamProtocol
+	books    map[string]*orderBook      //按货币类型保存的订单簿
+	conns    map[string]*websocket.Conn //按货币类型保存的连接
+	done     chan struct{}              //关闭所有连接时关闭
+	logger   model.Logger
+}
+
+func newStream(protocol streamProtocol, logger model.Logger) *stream {
+	return &stream{
+		protocol: protocol,
+		books:    make(map[string]*orderBook),
+		conns:    make(map[string]*websocket.Conn),
+		logger:   logger,
+	}
+}
+
+// subscribe start streaming stockType, it does nothing if stockType is streaming already
+func (s *stream) subscribe(stockType, symbol string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.books[stockType]; ok {
+		return
+	}
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+	book := newOrderBook()
+	s.books[stockType] = book
+	go s.run(stockType, symbol, book, s.done)
+}
+
+// run keep the connection of stockType alive and apply the messages to its order book,
+// the order book is rebuilt from a new connection when a sequence gap is found
+func (s *stream) run(stockType, symbol string, book *orderBook, done chan struct{}) {
+	wait := time.Second
+	for !isDone(done) {
+		conn, err := s.protocol.dial(symbol)
+		if err != nil {
+			s.logger.Log(constant.ERROR, stockType, 0.0, 0.0, "Subscribe() error, ", err)
+			time.Sleep(wait)
+			if wait < time.Minute {
+				wait *= 2
+			}
+			continue
+		}
+		s.mutex.Lock()
+		if isDone(done) {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[stockType] = conn
+		s.mutex.Unlock()
+		for !isDone(done) {
+			var message []byte
+			conn.SetReadDeadline(time.Now().Add(time.Duration(streamTimeout) * time.Second))
+			if err = websocket.Message.Receive(conn, &message); err != nil {
+				break
+			}
+			if err = s.protocol.handle(conn, message, book); err != nil {
+				break
+			}
+			wait = time.Second
+		}
+		conn.Close()
+		book.reset()
+		if err != nil && err != errResync && !isDone(done) {
+			s.logger.Log(constant.ERROR, stockType, 0.0, 0.0, "Subscribe() error, ", err)
+			time.Sleep(wait)
+		}
+	}
+}
+
+// isDone check if the channel is closed
+func isDone(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ticker get the ticker of stockType from its order book, it fails if stockType is not streaming
+func (s *stream) ticker(stockType string, size int) (Ticker, bool) {
+	s.mutex.Lock()
+	book, ok := s.books[stockType]
+	s.mutex.Unlock()
+	if !ok {
+		return Ticker{}, false
+	}
+	return book.ticker(size)
+}
+
+// close close all the connections
+func (s *stream) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	for stockType, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, stockType)
+	}
+	s.books = make(map[string]*orderBook)
+}
+
+// dialStream connect to a websocket url through the proxy and send the subscribe messages
+func dialStream(proxyURL, url string, messages ...interface{}) (*websocket.Conn, error) {
+	origin := "http://" + strings.Split(strings.SplitN(url, "://", 2)[1], "/")[0] + "/"
+	config, err := websocket.NewConfig(url, origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: dialTimeout}
+	var conn *websocket.Conn
+	if proxyURL == "" {
+		conn, err = websocket.DialConfig(config)
+	} else {
+		conn, err = dialProxy(proxyURL, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		if err = websocket.JSON.Send(conn, message); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// dialProxy connect to a websocket server through a http or socks5 proxy
+func dialProxy(proxyURL string, config *websocket.Config) (*websocket.Conn, error) {
+	u, err := parseProxyURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	host := config.Location.Hostname()
+	addr := config.Location.Host
+	if config.Location.Port() == "" {
+		if config.Location.Scheme == "wss" {
+			addr = net.JoinHostPort(host, "443")
+		} else {
+			addr = net.JoinHostPort(host, "80")
+		}
+	}
+	var conn net.Conn
+	if u.Scheme == "socks5" {
+		var dialer proxy.Dialer
+		if dialer, err = proxy.FromURL(u, &net.Dialer{Timeout: dialTimeout}); err != nil {
+			return nil, err
+		}
+		if conn, err = dialer.Dial("tcp", addr); err != nil {
+			return nil, err
+		}
+	} else if conn, err = dialConnect(u, addr); err != nil {
+		return nil, err
+	}
+	if config.Location.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// dialConnect open a tunnel to addr by the CONNECT method of a http proxy
+func dialConnect(u *url.URL, addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		req.SetBasicAuth(u.User.Username(), password)
+		req.Header.Set("Proxy-Authorization", req.Header.Get("Authorization"))
+		req.Header.Del("Authorization")
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("the proxy refused to connect to %s: %s", addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
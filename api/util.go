@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
@@ -11,23 +12,38 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
-)
 
-var client = http.DefaultClient
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/miaolz123/conver"
+)
 
 // Position struct
 type Position struct {
-	Price         float64 //价格
-	Leverage      int     //杠杆比例
-	Amount        float64 //总合约数量
-	ConfirmAmount float64
-	FrozenAmount  float64 //冻结的合约数量
-	Profit        float64 //收益
-	ContractType  string  //合约类型
-	TradeType     string  //交易类型
-	StockType     string  //货币类型
+	Price            float64 //价格
+	Leverage         int     //杠杆比例
+	Amount           float64 //总合约数量
+	ConfirmAmount    float64 //可平仓的合约数量
+	FrozenAmount     float64 //冻结的合约数量
+	Profit           float64 //收益
+	Margin           float64 //占用的保证金
+	MarginMode       string  //保证金模式, crossed: 全仓, fixed: 逐仓
+	LiquidationPrice float64 //预估的强平价格
+	ContractType     string  //合约类型
+	TradeType        string  //交易类型
+	StockType        string  //货币类型
+}
+
+// FundingRate struct, the funding rate of a perpetual contract
+type FundingRate struct {
+	Rate      float64 //最近一次的资金费率
+	NextTime  int64   //下次收取资金费的unix时间戳
+	MarkPrice float64 //标记价格
+	StockType string  //货币类型
 }
 
+// Account is the balances of an account, like {"USDT": 100, "FrozenUSDT": 0}
+type Account map[string]float64
+
 // Order struct
 type Order struct {
 	ID         string  //订单ID
@@ -39,6 +55,19 @@ type Order struct {
 	StockType  string  //货币类型
 }
 
+// Trade struct, a fill of an order
+type Trade struct {
+	ID          string  //成交ID
+	OrderID     string  //订单ID
+	Price       float64 //成交价格
+	Amount      float64 //成交数量
+	Fee         float64 //手续费
+	FeeCurrency string  //手续费币种
+	Time        int64   //unix时间戳
+	TradeType   string  //交易类型
+	StockType   string  //货币类型
+}
+
 // Record struct
 type Record struct {
 	Time   int64   //unix时间戳
@@ -62,22 +91,61 @@ type Ticker struct {
 	Mid  float64     //(Buy + Sell) / 2
 	Sell float64     //卖一价, Asks[0].Price
 	Asks []OrderBook //卖单市场深度列表
+	Last float64     //最新成交价, 只有订阅了行情时才有
+}
+
+// sizeOf get the size argument passed from the javascript
+func sizeOf(sizes []interface{}, size int) int {
+	if len(sizes) > 0 && conver.IntMust(sizes[0]) > 0 {
+		return conver.IntMust(sizes[0])
+	}
+	return size
+}
+
+// feeCurrencyOf get the currency which the fee is charged in by default,
+// it is the received currency, the base currency of a buy and the quote currency of a sell
+func feeCurrencyOf(stockType, tradeType string) string {
+	base, quote, _ := splitStockType(stockType)
+	if tradeType == constant.TradeTypeSell {
+		return quote
+	}
+	return base
+}
+
+// mergeRecords merge the new records sorted by time into the cached records,
+// the last cached record is replaced because it may be unfinished, at most size records are kept
+func mergeRecords(records, recordsNew []Record, size int) []Record {
+	timeLast := int64(0)
+	if len(records) > 0 {
+		timeLast = records[len(records)-1].Time
+	}
+	for _, record := range recordsNew {
+		if record.Time > timeLast {
+			records = append(records, record)
+			timeLast = record.Time
+		} else if timeLast > 0 && record.Time == timeLast {
+			records[len(records)-1] = record
+		}
+	}
+	if len(records) > size {
+		records = records[len(records)-size:]
+	}
+	return records
 }
 
 func base64Encode(data string) string {
 	return base64.StdEncoding.EncodeToString([]byte(data))
 }
 
-func signMd5(params []string) string {
-	m := md5.New()
-	m.Write([]byte(strings.Join(params, "&")))
-	return hex.EncodeToString(m.Sum(nil))
+// hmacSha512 get the raw HMAC-SHA512 of a message, the exchanges encode it in different ways
+func hmacSha512(message, key []byte) []byte {
+	h := hmac.New(sha512.New, key)
+	h.Write(message)
+	return h.Sum(nil)
 }
 
 func signSha512(params []string, key string) string {
-	h := hmac.New(sha512.New, []byte(key))
-	h.Write([]byte(strings.Join(params, "&")))
-	return hex.EncodeToString(h.Sum(nil))
+	return hex.EncodeToString(hmacSha512([]byte(strings.Join(params, "&")), []byte(key)))
 }
 
 func signSha1(params []string, key string) string {
@@ -86,6 +154,12 @@ func signSha1(params []string, key string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+func signSha256Base64(message, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
 func signChbtc(params []string, key string) string {
 	sha := sha1.New()
 	sha.Write([]byte(key))
@@ -95,7 +169,7 @@ func signChbtc(params []string, key string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func post_gateio(url string, data []string, key string, sign string) (ret []byte, err error) {
+func post_gateio(client *http.Client, url string, data []string, key string, sign string) (ret []byte, err error) {
 	req, err := http.NewRequest("POST", url, strings.NewReader(strings.Join(data, "&")))
 	if err != nil {
 		return
@@ -115,7 +189,7 @@ func post_gateio(url string, data []string, key string, sign string) (ret []byte
 	return ret, err
 }
 
-func post(url string, data []string) (ret []byte, err error) {
+func post(client *http.Client, url string, data []string) (ret []byte, err error) {
 	req, err := http.NewRequest("POST", url, strings.NewReader(strings.Join(data, "&")))
 	if err != nil {
 		return
@@ -133,7 +207,7 @@ func post(url string, data []string) (ret []byte, err error) {
 	return ret, err
 }
 
-func get(url string) (ret []byte, err error) {
+func get(client *http.Client, url string) (ret []byte, err error) {
 	req, err := http.NewRequest("GET", url, strings.NewReader(""))
 	if err != nil {
 		return
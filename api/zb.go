@@ -3,7 +3,6 @@ package api
 import (
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/miaolz123/conver"
 	"github.com/HunterUPP/QuantBot/api/ZbAPI"
@@ -11,27 +10,57 @@ import (
 	"github.com/HunterUPP/QuantBot/model"
 )
 
+// zbErrorKinds the error codes of zb.com
+var zbErrorKinds = map[string]string{
+	"1003": ErrAuth,
+	"1012": ErrAuth,
+	"2001": ErrInsufficientBalance,
+	"2002": ErrInsufficientBalance,
+	"2003": ErrInsufficientBalance,
+	"2005": ErrInsufficientBalance,
+	"2006": ErrInsufficientBalance,
+	"2007": ErrInsufficientBalance,
+	"2009": ErrInsufficientBalance,
+	"3006": ErrAuth,
+	"4001": ErrAuth,
+	"4002": ErrRateLimited,
+}
+
 // Zb the exchange struct of zb.com
 type Zb struct {
+	client           *ZbAPI.Client
 	stockTypeMap     map[string]string
 	tradeTypeMap     map[int]string
 	recordsPeriodMap map[string]string
 	minAmountMap     map[string]float64
-	records          map[string][]Record
+	orderTypes       []string //支持的订单类型
+	markets          *marketCache
+	store            *recordStore
 	logger           model.Logger
 	option           Option
+	lastError        *Error
+
+	limiter *rateLimiter
+}
 
-	limit     float64
-	lastSleep int64
-	lastTimes int64
+// zbPeriods the periods of the records the exchange supports
+var zbPeriods = map[string]string{
+	"M":   "1min",
+	"M5":  "5min",
+	"M15": "15min",
+	"M30": "30min",
+	"H":   "1hour",
+	"D":   "1day",
+	"W":   "1week",
 }
 
+// zbOrderTypes the order types the exchange supports
+var zbOrderTypes = []string{constant.OrderTypeLimit, constant.OrderTypeMarket, constant.OrderTypePostOnly, constant.OrderTypeIOC}
+
 // NewZb create an exchange struct of zb.com
 func NewZb(opt Option) Exchange {
-	ZbAPI.Config.ACCESS_KEY = opt.AccessKey
-	ZbAPI.Config.SECRET_KEY = opt.SecretKey
-	//...
-	return &Zb{
+	e := &Zb{
+		client: ZbAPI.NewClient(newHTTPClient(opt), opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
 			"BTC/USDT":  "btc_usdt",
 			"ETH/USDT":  "eth_usdt",
@@ -43,15 +72,7 @@ func NewZb(opt Option) Exchange {
 			1: constant.TradeTypeBuy,
 			0: constant.TradeTypeSell,
 		},
-		recordsPeriodMap: map[string]string{
-			"M":   "001",
-			"M5":  "005",
-			"M15": "015",
-			"M30": "030",
-			"H":   "060",
-			"D":   "100",
-			"W":   "200",
-		},
+		recordsPeriodMap: zbPeriods,
 		minAmountMap: map[string]float64{
 			"BTC/USDT":  0.001,
 			"ETH/USDT":  0.001,
@@ -59,13 +80,20 @@ func NewZb(opt Option) Exchange {
 			"LTC/USDT":  0.001,
 			"QTUM/USDT": 0.001,
 		},
-		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
-		option:  opt,
+		orderTypes: zbOrderTypes,
+		logger:     model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		option:     opt,
 
-		limit:     10.0,
-		lastSleep: time.Now().UnixNano(),
+		limiter: limiterOf(opt),
+	}
+	if opt.BaseURL != "" {
+		//中币的行情和交易使用不同的域名, 覆盖时都使用同一个地址
+		e.client.DataURL = baseURLOf(opt, "/") + "data/v1/"
+		e.client.TradeURL = baseURLOf(opt, "/") + "api/"
 	}
+	e.markets = newMarketCache(e.stockTypeMap, e.minAmountMap, e.loadMarkets)
+	e.store = newRecordStore(opt, e.logger)
+	return e
 }
 
 // Log print something to console
@@ -83,40 +111,87 @@ func (e *Zb) GetName() string {
 	return e.option.Name
 }
 
-// SetLimit set the limit calls amount per second of this exchange
+// SetLimit set the request weight per second of this exchange, it is shared by the traders using the same api key
 func (e *Zb) SetLimit(times interface{}) float64 {
-	e.limit = conver.Float64Must(times)
-	return e.limit
+	return e.limiter.setRate(conver.Float64Must(times))
 }
 
-// AutoSleep auto sleep to achieve the limit calls amount per second of this exchange
+// AutoSleep wait until the requests over the budget are paid back, every request waits on the rate limiter by itself
 func (e *Zb) AutoSleep() {
-	now := time.Now().UnixNano()
-	interval := 1e+9/e.limit*conver.Float64Must(e.lastTimes) - conver.Float64Must(now-e.lastSleep)
-	if interval > 0.0 {
-		time.Sleep(time.Duration(conver.Int64Must(interval)))
-	}
-	e.lastTimes = 0
-	e.lastSleep = now
+	e.limiter.wait(0)
+}
+
+// GetRateLimit get the request budget of this exchange
+func (e *Zb) GetRateLimit() interface{} {
+	return e.limiter.status()
+}
+
+// Capabilities get what this exchange supports
+func (e *Zb) Capabilities() Capabilities {
+	return newCapabilities(e.GetType(), e, e.recordsPeriodMap, e.orderTypes)
 }
 
 // GetMinAmount get the min trade amonut of this exchange
 func (e *Zb) GetMinAmount(stock string) float64 {
-	return e.minAmountMap[stock]
+	return e.markets.minAmount(stock)
 }
 
-// GetAccount get the account detail of this exchange
-func (e *Zb) GetAccount() interface{} {
-	accountInfo, err := ZbAPI.GetAccountInfo()
+// GetMarkets get all the markets of this exchange
+func (e *Zb) GetMarkets() interface{} {
+	return e.markets.list()
+}
+
+// loadMarkets load the markets from the market config of zb.com
+func (e *Zb) loadMarkets() (markets []Market, err error) {
+	result, err := e.client.GetMarkets()
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
-		return false
+		return
+	}
+	for symbol, m := range *result {
+		base, quote, err := splitStockType(strings.Replace(symbol, "_", "/", -1))
+		if err != nil {
+			continue
+		}
+		markets = append(markets, Market{
+			StockType:       base + "/" + quote,
+			Symbol:          symbol,
+			BaseCurrency:    base,
+			QuoteCurrency:   quote,
+			PricePrecision:  m.PriceScale,
+			AmountPrecision: m.AmountScale,
+			TickSize:        stepOf(m.PriceScale),
+			LotSize:         stepOf(m.AmountScale),
+			MinAmount:       stepOf(m.AmountScale),
+		})
+	}
+	return
+}
+
+// GetLastError get the last error of this exchange
+func (e *Zb) GetLastError() interface{} {
+	if e.lastError == nil {
+		return nil
+	}
+	return *e.lastError
+}
+
+// fail log the error and keep it as the last error
+func (e *Zb) fail(method string, err error) bool {
+	e.lastError = wrapError(method, err)
+	e.logger.Log(constant.ERROR, "", 0.0, 0.0, e.lastError)
+	return false
+}
+
+// Account get the account detail of this exchange
+func (e *Zb) Account() (Account, error) {
+	accountInfo, err := e.client.GetAccountInfo()
+	if err != nil {
+		return nil, wrapError("GetAccount", err)
 	}
 	if accountInfo.Code != 0 && accountInfo.Code != 1000 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", accountInfo.Message)
-		return false
+		return nil, newCodeError("GetAccount", zbErrorKinds, accountInfo.Code, accountInfo.Message)
 	}
-	result := make(map[string]float64)
+	result := Account{}
 	count := len(accountInfo.Result.Coins)
 	for i := 0; i < count; i++ {
 		coin := accountInfo.Result.Coins[i]
@@ -129,73 +204,89 @@ func (e *Zb) GetAccount() interface{} {
 			result["Frozen"+strings.ToUpper(coin.EnName)] = freez
 		}
 	}
-	return result
+	return result, nil
 }
 
-// Trade place an order
-func (e *Zb) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+// GetAccount get the account detail of this exchange
+func (e *Zb) GetAccount() interface{} {
+	account, err := e.Account()
+	if err != nil {
+		return e.fail("GetAccount", err)
+	}
+	return account
+}
+
+// PlaceOrder place an order, it is a market order if price <= 0 and the amount of a market buy is in the quote currency,
+// zb has no market orders so it is an IOC limit order at the price of the worst level of the order book it eats
+func (e *Zb) PlaceOrder(tradeType string, stockType string, price, amount float64, msgs ...interface{}) (string, error) {
 	stockType = strings.ToUpper(stockType)
 	tradeType = strings.ToUpper(tradeType)
-	price := conver.Float64Must(_price)
-	amount := conver.Float64Must(_amount)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return "", newError(ErrInvalidSymbol, "Trade", "unrecognized stockType: ", stockType)
+	}
+	opts, msgs, err := orderOptionsOf("Trade", price, e.orderTypes, msgs)
+	if err != nil {
+		return "", err
 	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
-		return e.buy(stockType, price, amount, msgs...)
+		return e.place("Buy", "1", constant.TradeTypeBuy, stockType, price, amount, opts, msgs...)
 	case constant.TradeTypeSell:
-		return e.sell(stockType, price, amount, msgs...)
+		return e.place("Sell", "0", constant.TradeTypeSell, stockType, price, amount, opts, msgs...)
 	default:
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized tradeType: ", tradeType)
-		return false
+		return "", newError(ErrUnknown, "Trade", "unrecognized tradeType: ", tradeType)
 	}
 }
 
-func (e *Zb) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := ZbAPI.CreateOrder(conver.StringMust(amount), e.stockTypeMap[stockType], "1", conver.StringMust(price))
+// Trade place an order
+func (e *Zb) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
+	id, err := e.PlaceOrder(tradeType, stockType, conver.Float64Must(_price), conver.Float64Must(_amount), msgs...)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
-		return false
+		return e.fail("Trade", err)
 	}
-	if result.Code != 1000 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", result.Message)
-		return false
-	}
-	e.logger.Log(constant.BUY, stockType, price, amount, msgs...)
-	return result.Id
+	return id
 }
 
-func (e *Zb) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := ZbAPI.CreateOrder(conver.StringMust(amount), e.stockTypeMap[stockType], "0", conver.StringMust(price))
+func (e *Zb) place(method, side, tradeType, stockType string, price, amount float64, opts OrderOptions, msgs ...interface{}) (string, error) {
+	orderPrice, orderAmount := price, amount
+	if price <= 0 {
+		var err error
+		orderPrice, orderAmount, err = marketOrderOf(method, e.Ticker, e.markets, stockType, tradeType, amount)
+		if err != nil {
+			return "", err
+		}
+		opts.TimeInForce = constant.TimeInForceIOC
+		price = 0.0
+	}
+	orderType := ""
+	if opts.PostOnly {
+		orderType = "1"
+	} else if opts.TimeInForce == constant.TimeInForceIOC {
+		orderType = "2"
+	}
+	result, err := e.client.CreateOrder(conver.StringMust(orderAmount), e.markets.symbol(stockType), side, conver.StringMust(orderPrice), orderType)
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
-		return false
+		return "", wrapError(method, err)
 	}
 	if result.Code != 1000 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", result.Message)
-		return false
+		return "", newCodeError(method, zbErrorKinds, result.Code, result.Message)
 	}
-	e.logger.Log(constant.SELL, stockType, price, amount, msgs...)
-	return result.Id
+	e.logger.Log(tradeType, stockType, price, amount, msgs...)
+	return result.Id, nil
 }
 
-// GetOrder get details of an order
-func (e *Zb) GetOrder(stockType, id string) interface{} {
+// Order get details of an order
+func (e *Zb) Order(stockType, id string) (Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return Order{}, newError(ErrInvalidSymbol, "GetOrder", "unrecognized stockType: ", stockType)
 	}
-	result, err := ZbAPI.GetOrder(id, e.stockTypeMap[stockType])
+	result, err := e.client.GetOrder(id, e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
-		return false
+		return Order{}, wrapError("GetOrder", err)
 	}
 	if result.Code != 0 && result.Code != 1000 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", result.Message)
-		return false
+		return Order{}, newCodeError("GetOrder", zbErrorKinds, result.Code, result.Message)
 	}
 	return Order{
 		ID:         result.ID,
@@ -204,20 +295,27 @@ func (e *Zb) GetOrder(stockType, id string) interface{} {
 		DealAmount: result.TradeAmount,
 		TradeType:  e.tradeTypeMap[result.OrderType],
 		StockType:  stockType,
+	}, nil
+}
+
+// GetOrder get details of an order
+func (e *Zb) GetOrder(stockType, id string) interface{} {
+	order, err := e.Order(stockType, id)
+	if err != nil {
+		return e.fail("GetOrder", err)
 	}
+	return order
 }
 
-// GetOrders get all unfilled orders
-func (e *Zb) GetOrders(stockType string) interface{} {
+// Orders get all unfilled orders
+func (e *Zb) Orders(stockType string) ([]Order, error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
-		return false
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetOrders", "unrecognized stockType: ", stockType)
 	}
-	result, err := ZbAPI.GetOrders(e.stockTypeMap[stockType])
+	result, err := e.client.GetOrders(e.markets.symbol(stockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
-		return false
+		return nil, wrapError("GetOrders", err)
 	}
 	orders := []Order{}
 	count := len(*result)
@@ -231,39 +329,93 @@ func (e *Zb) GetOrders(stockType string) interface{} {
 			StockType:  stockType,
 		})
 	}
+	return orders, nil
+}
+
+// GetOrders get all unfilled orders
+func (e *Zb) GetOrders(stockType string) interface{} {
+	orders, err := e.Orders(stockType)
+	if err != nil {
+		return e.fail("GetOrders", err)
+	}
 	return orders
 }
 
-// GetTrades get all filled orders recently
+// Trades get the recent fills
+// zb.com has no api of fills, so every filled order is taken as a fill at its average price
+func (e *Zb) Trades(stockType string) ([]Trade, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetTrades", "unrecognized stockType: ", stockType)
+	}
+	result, err := e.client.GetOrdersHistory(e.markets.symbol(stockType))
+	if err != nil {
+		return nil, wrapError("GetTrades", err)
+	}
+	trades := []Trade{}
+	for _, order := range *result {
+		if order.TradeAmount <= 0 {
+			continue
+		}
+		tradeType := e.tradeTypeMap[order.OrderType]
+		trades = append(trades, Trade{
+			ID:          order.ID,
+			OrderID:     order.ID,
+			Price:       order.TradePrice,
+			Amount:      order.TradeAmount,
+			Fee:         order.Fees,
+			FeeCurrency: feeCurrencyOf(stockType, tradeType),
+			Time:        order.TradeDate / 1000,
+			TradeType:   tradeType,
+			StockType:   stockType,
+		})
+	}
+	return trades, nil
+}
+
+// GetTrades get the recent fills
 func (e *Zb) GetTrades(stockType string) interface{} {
-	return nil
+	trades, err := e.Trades(stockType)
+	if err != nil {
+		return e.fail("GetTrades", err)
+	}
+	return trades
 }
 
-// CancelOrder cancel an order
-func (e *Zb) CancelOrder(order Order) bool {
-	result, err := ZbAPI.CancelOrder(order.ID, e.stockTypeMap[order.StockType])
+// Cancel cancel an order
+func (e *Zb) Cancel(order Order) error {
+	result, err := e.client.CancelOrder(order.ID, e.markets.symbol(order.StockType))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
-		return false
+		return wrapError("CancelOrder", err)
 	}
 	if result.Code != 1000 {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", result.Message)
-		return false
+		return newCodeError("CancelOrder", zbErrorKinds, result.Code, result.Message)
 	}
 	e.logger.Log(constant.CANCEL, order.StockType, order.Price, order.Amount-order.DealAmount, order)
+	return nil
+}
+
+// CancelOrder cancel an order
+func (e *Zb) CancelOrder(order Order) bool {
+	if err := e.Cancel(order); err != nil {
+		return e.fail("CancelOrder", err)
+	}
 	return true
 }
 
-// getTicker get market ticker & depth
-func (e *Zb) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, err error) {
+// Ticker get market ticker & depth
+func (e *Zb) Ticker(stockType string, size int) (ticker Ticker, err error) {
 	stockType = strings.ToUpper(stockType)
-	if _, ok := e.stockTypeMap[stockType]; !ok {
-		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
+	if !e.markets.has(stockType) {
+		err = newError(ErrInvalidSymbol, "GetTicker", "unrecognized stockType: ", stockType)
 		return
 	}
-	result, err := ZbAPI.GetDepth(e.stockTypeMap[stockType], "10")
+	if size <= 0 {
+		size = 10
+	}
+	result, err := e.client.GetDepth(e.markets.symbol(stockType), fmt.Sprint(size))
 	if err != nil {
-		err = fmt.Errorf("GetTicker() error, %+v", err)
+		err = wrapError("GetTicker", err)
 		return
 	}
 	count := len(result.Bids)
@@ -281,7 +433,7 @@ func (e *Zb) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, e
 		})
 	}
 	if len(ticker.Bids) < 1 || len(ticker.Asks) < 1 {
-		err = fmt.Errorf("GetTicker() error, can not get enough Bids or Asks")
+		err = newError(ErrUnknown, "GetTicker", "can not get enough Bids or Asks")
 		return
 	}
 	ticker.Buy = ticker.Bids[0].Price
@@ -292,15 +444,82 @@ func (e *Zb) getTicker(stockType string, sizes ...interface{}) (ticker Ticker, e
 
 // GetTicker get market ticker & depth
 func (e *Zb) GetTicker(stockType string, sizes ...interface{}) interface{} {
-	ticker, err := e.getTicker(stockType, sizes...)
+	ticker, err := e.Ticker(stockType, sizeOf(sizes, 0))
 	if err != nil {
-		e.logger.Log(constant.ERROR, "", 0.0, 0.0, err)
-		return false
+		return e.fail("GetTicker", err)
 	}
 	return ticker
 }
 
+// Records get candlestick data
+func (e *Zb) Records(stockType, period string, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return resampleRecords(e.Records, e.recordsPeriodMap, stockType, period, size)
+	}
+	if size <= 0 {
+		size = 200
+	}
+	if size > 1000 {
+		size = 1000
+	}
+	recordsNew, err := e.klines(stockType, period, 0, e.store.missing(stockType, period, size))
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	return e.store.merge(stockType, period, recordsNew, size)
+}
+
+// RecordsSince get the candlestick data since a unix timestamp, it is used to backfill the history
+func (e *Zb) RecordsSince(stockType, period string, since int64, size int) ([]Record, error) {
+	stockType = strings.ToUpper(stockType)
+	if !e.markets.has(stockType) {
+		return nil, newError(ErrInvalidSymbol, "GetRecords", "unrecognized stockType: ", stockType)
+	}
+	if _, ok := e.recordsPeriodMap[period]; !ok {
+		return nil, newError(ErrNotSupported, "GetRecords", "unrecognized period: ", period)
+	}
+	if size > 1000 {
+		size = 1000
+	}
+	records, err := e.klines(stockType, period, since, size)
+	if err != nil {
+		return nil, wrapError("GetRecords", err)
+	}
+	return records, nil
+}
+
+// klines get the latest size records, or the records since a unix timestamp if since > 0
+func (e *Zb) klines(stockType, period string, since int64, size int) ([]Record, error) {
+	result, err := e.client.GetKline(e.markets.symbol(stockType), e.recordsPeriodMap[period], fmt.Sprint(size), since*1000)
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	for _, kline := range result.Data {
+		if len(kline) < 6 {
+			continue
+		}
+		records = append(records, Record{
+			Time:   int64(kline[0]) / 1000,
+			Open:   kline[1],
+			High:   kline[2],
+			Low:    kline[3],
+			Close:  kline[4],
+			Volume: kline[5],
+		})
+	}
+	return records, nil
+}
+
 // GetRecords get candlestick data
 func (e *Zb) GetRecords(stockType, period string, sizes ...interface{}) interface{} {
-	return nil
+	records, err := e.Records(stockType, period, sizeOf(sizes, 0))
+	if err != nil {
+		return e.fail("GetRecords", err)
+	}
+	return records
 }
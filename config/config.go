@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"os"
 	"strings"
 
 	"github.com/go-ini/ini"
@@ -10,6 +11,15 @@ import (
 var confs = make(map[string]string)
 
 func init() {
+	// 环境变量 QUANTBOT_CONFIG 指定配置文件时只加载这个文件
+	if path := os.Getenv("QUANTBOT_CONFIG"); path != "" {
+		conf, err := ini.InsensitiveLoad(path)
+		if err != nil {
+			log.Fatalln("Load", path, "error:", err)
+		}
+		load(conf)
+		return
+	}
 	conf, err := ini.InsensitiveLoad("custom/config.ini")
 	if err != nil {
 		conf, err = ini.InsensitiveLoad("config.ini")
@@ -17,6 +27,11 @@ func init() {
 			log.Fatalln("Load config.ini error:", err)
 		}
 	}
+	load(conf)
+}
+
+// load keep the keys of the config in lower case
+func load(conf *ini.File) {
 	keys := conf.Section("").KeyStrings()
 	for _, k := range keys {
 		confs[k] = conf.Section("").Key(k).String()
@@ -10,15 +10,20 @@ const (
 
 // exchange types
 const (
-	Zb         = "zb"
-	Okex       = "okex"
-	Huobi      = "huobi"
-	Binance    = "binance"
-	GateIo     = "gateio"
-	Bibox      = "bibox"
-	Poloniex   = "poloniex"
-	OkexFuture = "okex.future"
-	BigOne     = "big.one"
+	Zb            = "zb"
+	Okex          = "okex"
+	Huobi         = "huobi"
+	Binance       = "binance"
+	GateIo        = "gateio"
+	Bibox         = "bibox"
+	Poloniex      = "poloniex"
+	OkexFuture    = "okex.future"
+	BinanceFuture = "binance.future"
+	HuobiFuture   = "huobi.future"
+	Kraken        = "kraken"
+	Coinbase      = "coinbase"
+	BigOne        = "big.one"
+	Backtest      = "backtest"
 )
 
 // log types
@@ -45,8 +50,60 @@ const (
 	TradeTypeShortClose = "SHORT_CLOSE"
 )
 
+// order types
+const (
+	OrderTypeLimit    = "LIMIT"
+	OrderTypeMarket   = "MARKET"
+	OrderTypePostOnly = "POST_ONLY"
+	OrderTypeIOC      = "IOC"
+	OrderTypeFOK      = "FOK"
+)
+
+// time in force
+const (
+	TimeInForceGTC = "GTC"
+	TimeInForceIOC = "IOC"
+	TimeInForceFOK = "FOK"
+)
+
+// margin modes
+const (
+	MarginModeCrossed = "crossed"
+	MarginModeFixed   = "fixed"
+)
+
+// event types
+const (
+	EventOrder   = "ORDER"
+	EventBalance = "BALANCE"
+)
+
+// order status
+const (
+	OrderStatusNew      = "NEW"
+	OrderStatusPartial  = "PARTIALLY_FILLED"
+	OrderStatusFilled   = "FILLED"
+	OrderStatusCanceled = "CANCELED"
+)
+
+// condition types
+const (
+	ConditionStopMarket   = "STOP_MARKET"
+	ConditionStopLimit    = "STOP_LIMIT"
+	ConditionTakeProfit   = "TAKE_PROFIT"
+	ConditionTrailingStop = "TRAILING_STOP"
+)
+
+// condition status
+const (
+	ConditionPending   = "PENDING"
+	ConditionTriggered = "TRIGGERED"
+	ConditionCanceled  = "CANCELED"
+	ConditionFailed    = "FAILED"
+)
+
 // some variables
 var (
-	Consts        = []string{"M", "M5", "M15", "M30", "H", "D", "W"}
-	ExchangeTypes = []string{Zb, Okex, Huobi, Binance, GateIo, Bibox, Poloniex, OkexFuture, BigOne}
+	Consts        = []string{"M", "M3", "M5", "M15", "M30", "H", "H2", "H4", "H6", "H12", "D", "D3", "W"}
+	ExchangeTypes = []string{Zb, Okex, Huobi, Binance, GateIo, Bibox, Poloniex, OkexFuture, BinanceFuture, HuobiFuture, Kraken, Coinbase, BigOne, Backtest}
 )
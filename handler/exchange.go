@@ -2,17 +2,20 @@ package handler
 
 import (
 	"fmt"
+	"log"
+	"time"
 
-	"github.com/hprose/hprose-golang/rpc"
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
+	"github.com/HunterUPP/QuantBot/trader"
+	"github.com/hprose/hprose-golang/rpc"
 )
 
 type exchange struct{}
 
-// Types ...
+// Types 返回所有的交易所类型及其支持的功能
 func (exchange) Types(_ string, ctx rpc.Context) (resp response) {
-	resp.Data = constant.ExchangeTypes
+	resp.Data = trader.Capabilities()
 	resp.Success = true
 	return
 }
@@ -67,6 +70,10 @@ func (exchange) Put(req model.Exchange, ctx rpc.Context) (resp response) {
 		exchange.Type = req.Type
 		exchange.AccessKey = req.AccessKey
 		exchange.SecretKey = req.SecretKey
+		exchange.Passphrase = req.Passphrase
+		exchange.Settings = req.Settings
+		exchange.ProxyURL = req.ProxyURL
+		exchange.BaseURL = req.BaseURL
 		if err := model.DB.Save(&exchange).Error; err != nil {
 			resp.Message = fmt.Sprint(err)
 			return
@@ -83,6 +90,40 @@ func (exchange) Put(req model.Exchange, ctx rpc.Context) (resp response) {
 	return
 }
 
+// Backfill 在后台下载交易所从 start 开始的历史K线并保存到数据库, start 如 "2018-06-01 00:00:00",
+// 交易所必须是当前用户已经保存的交易所, 只使用它的 ID
+func (exchange) Backfill(req model.Exchange, stockType, period, start string, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if req.ID <= 0 {
+		resp.Message = "Please select a saved exchange"
+		return
+	}
+	exchange := model.Exchange{}
+	if err := model.DB.Where("user_id = ?", self.ID).First(&exchange, req.ID).Error; err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if _, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.Local); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	go func() {
+		stored, err := trader.Backfill(exchange, stockType, period, start, nil)
+		log.Printf("Backfill %v %v %v since %v: %v records stored, error: %v\n", exchange.Type, stockType, period, start, stored, err)
+	}()
+	resp.Success = true
+	return
+}
+
 // Delete
 func (exchange) Delete(ids []int64, ctx rpc.Context) (resp response) {
 	username := ctx.GetString("username")
@@ -35,6 +35,8 @@ func Server() {
 		Algorithm algorithm
 		Trader    runner
 		Log       logger
+		Recorder  recorder
+		Record    record
 	}{}
 	service.Event = event{}
 	service.AddBeforeFilterHandler(func(request []byte, ctx rpc.Context, next rpc.NextFilterHandler) (response []byte, err error) {
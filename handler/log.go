@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/hprose/hprose-golang/rpc"
+	"github.com/HunterUPP/QuantBot/api"
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
 )
@@ -52,6 +53,27 @@ func (logger) List(trader model.Trader, pagination pagination, filters filters,
 	return
 }
 
+// Journal 返回策略最近发送给交易所的请求和响应, 需要在交易所的 Settings 中设置 "journal": true
+func (logger) Journal(trader model.Trader, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if trader, err = self.GetTrader(trader.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = api.Journal(trader.ID)
+	resp.Success = true
+	return
+}
+
 // // Post /logs
 // func logs(c *iris.Context) {
 // 	resp := iris.Map{
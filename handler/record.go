@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HunterUPP/QuantBot/api"
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+type record struct{}
+
+// Range 返回数据库中保存的K线的时间范围和数量
+func (record) Range(exchangeType, stockType, period string, ctx rpc.Context) (resp response) {
+	if ctx.GetString("username") == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	first, last, count, err := model.RecordsRange(exchangeType, strings.ToUpper(stockType), period)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		First int64
+		Last  int64
+		Count int64
+	}{
+		First: first,
+		Last:  last,
+		Count: count,
+	}
+	resp.Success = true
+	return
+}
+
+// Import 导入 csv 或者 json 格式的K线, data 为文件的内容
+func (record) Import(opt api.ImportOptions, data string, ctx rpc.Context) (resp response) {
+	if ctx.GetString("username") == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	result, err := api.ImportRecords(strings.NewReader(data), opt)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = result
+	resp.Success = true
+	return
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+	"github.com/HunterUPP/QuantBot/trader"
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+type recorder struct{}
+
+// List 返回正在运行的行情记录
+func (recorder) List(_ string, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = trader.ListRecordings(self.ID)
+	resp.Success = true
+	return
+}
+
+// Start 开始在后台记录交易所的行情, interval 为采样间隔(秒), depth 为记录的深度档数
+func (recorder) Start(req model.Exchange, stockType string, interval int64, depth int, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	exchange := model.Exchange{}
+	if err := model.DB.Where("user_id = ?", self.ID).First(&exchange, req.ID).Error; err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	recording, err := trader.StartRecording(self.ID, exchange, stockType, interval, depth)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = recording
+	resp.Success = true
+	return
+}
+
+// Stop 停止一个行情记录
+func (recorder) Stop(id int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if err := trader.StopRecording(self.ID, id); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
@@ -108,6 +108,7 @@ func (runner) Delete(req model.Trader, ctx rpc.Context) (resp response) {
 	if err := model.DB.Where("id = ?", req.ID).Delete(&model.Trader{}).Error; err != nil {
 		resp.Message = fmt.Sprint(err)
 	} else {
+		trader.StopConditions(req.ID) //删除后不再监控条件单
 		resp.Success = true
 	}
 	return
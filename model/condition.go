@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"github.com/HunterUPP/QuantBot/constant"
+)
+
+// Condition struct, a conditional order of a trader, the trader watches the ticker and places the order when it is triggered
+type Condition struct {
+	ID           int64     `gorm:"primary_key;AUTO_INCREMENT" json:"id"`
+	TraderID     int64     `gorm:"index" json:"-"`
+	ExchangeName string    `gorm:"type:varchar(200)" json:"exchangeName"` //交易所名称
+	Type         string    `gorm:"type:varchar(20)" json:"type"`          //STOP_MARKET, STOP_LIMIT, TAKE_PROFIT, TRAILING_STOP
+	TradeType    string    `gorm:"type:varchar(20)" json:"tradeType"`
+	StockType    string    `gorm:"type:varchar(20)" json:"stockType"`
+	StopPrice    float64   `json:"stopPrice"` //触发价格, 追踪止损是激活价格, 0 表示立即激活
+	Price        float64   `json:"price"`     //触发后的下单价格, <= 0 是市价单
+	Amount       float64   `json:"amount"`    //下单数量, 和 E.Trade() 的数量相同
+	Callback     float64   `json:"callback"`  //追踪止损的回调比例, 如 0.01
+	Extreme      float64   `json:"extreme"`   //追踪止损激活后的最高价(卖出)或者最低价(买入)
+	OcoID        int64     `json:"ocoId"`     //OCO 的另一个条件单, 一个触发后另一个撤销
+	Status       string    `gorm:"type:varchar(20);index" json:"status"`
+	OrderID      string    `gorm:"type:varchar(100)" json:"orderId"` //触发后的订单 ID
+	Message      string    `gorm:"type:text" json:"message"`         //撤销或者失败的原因
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// SaveCondition insert a new condition or update a stored one
+func SaveCondition(c *Condition) error {
+	if c.ID > 0 {
+		return DB.Save(c).Error
+	}
+	return DB.Create(c).Error
+}
+
+// SaveOCO insert two new conditions linked to each other in a transaction
+func SaveOCO(a, b *Condition) error {
+	tx := DB.Begin()
+	err := tx.Create(a).Error
+	if err == nil {
+		err = tx.Create(b).Error
+	}
+	if err == nil {
+		a.OcoID, b.OcoID = b.ID, a.ID
+		err = tx.Save(a).Error
+	}
+	if err == nil {
+		err = tx.Save(b).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		a.ID, b.ID, a.OcoID, b.OcoID = 0, 0, 0, 0
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// ListPendingConditions get the pending conditions of a trader sorted by id
+func ListPendingConditions(traderID int64) (conditions []Condition, err error) {
+	err = DB.Where("trader_id = ? AND status = ?", traderID, constant.ConditionPending).Order("id").Find(&conditions).Error
+	return
+}
@@ -38,7 +38,7 @@ func init() {
 			log.Fatalln("Connect to database error:", err)
 		}
 	}
-	DB.AutoMigrate(&User{}, &Exchange{}, &Algorithm{}, &TraderExchange{}, &Trader{}, &Log{})
+	DB.AutoMigrate(&User{}, &Exchange{}, &Algorithm{}, &TraderExchange{}, &Trader{}, &Log{}, &Record{}, &Condition{})
 	users := []User{}
 	DB.Find(&users)
 	if len(users) == 0 {
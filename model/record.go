@@ -0,0 +1,130 @@
+package model
+
+import (
+	"strings"
+)
+
+// Record struct, a stored candlestick of an exchange, keyed by exchange type, stockType, period and time
+type Record struct {
+	ID           int64   `gorm:"primary_key;AUTO_INCREMENT" json:"-"`
+	ExchangeType string  `gorm:"type:varchar(50);unique_index:idx_record_key" json:"exchangeType"`
+	StockType    string  `gorm:"type:varchar(20);unique_index:idx_record_key" json:"stockType"`
+	Period       string  `gorm:"type:varchar(10);unique_index:idx_record_key" json:"period"`
+	Time         int64   `gorm:"unique_index:idx_record_key" json:"time"` //unix时间戳
+	Open         float64 `json:"open"`
+	High         float64 `json:"high"`
+	Low          float64 `json:"low"`
+	Close        float64 `json:"close"`
+	Volume       float64 `json:"volume"`
+}
+
+// recordBatch the rows of every INSERT statement of SaveRecords, 9 columns each row under the 999 variables of sqlite
+var recordBatch = 100
+
+// SaveRecords insert the records of one exchange, stockType and period in a transaction, the stored records of the same time are updated,
+// the stored ones are found by one query and the new ones are inserted in batches
+func SaveRecords(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	first, last := records[0].Time, records[0].Time
+	for _, r := range records {
+		if r.Time < first {
+			first = r.Time
+		}
+		if r.Time > last {
+			last = r.Time
+		}
+	}
+	r0 := records[0]
+	tx := DB.Begin()
+	stored := []Record{}
+	if err := tx.Where("exchange_type = ? AND stock_type = ? AND period = ? AND time >= ? AND time <= ?", r0.ExchangeType, r0.StockType, r0.Period, first, last).
+		Find(&stored).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	ids := make(map[int64]int64)
+	for _, r := range stored {
+		ids[r.Time] = r.ID
+	}
+	inserts := []Record{}
+	for _, r := range records {
+		id, ok := ids[r.Time]
+		if !ok {
+			ids[r.Time] = 0 //重复的时间只插入一次
+			inserts = append(inserts, r)
+			continue
+		}
+		if id == 0 {
+			continue
+		}
+		r.ID = id
+		if err := tx.Save(&r).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for len(inserts) > 0 {
+		n := len(inserts)
+		if n > recordBatch {
+			n = recordBatch
+		}
+		values := []string{}
+		vars := []interface{}{}
+		for _, r := range inserts[:n] {
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			vars = append(vars, r.ExchangeType, r.StockType, r.Period, r.Time, r.Open, r.High, r.Low, r.Close, r.Volume)
+		}
+		sql := "INSERT INTO " + tx.NewScope(&Record{}).TableName() + " (exchange_type, stock_type, period, time, open, high, low, close, volume) VALUES " + strings.Join(values, ", ")
+		if err := tx.Exec(sql, vars...).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		inserts = inserts[n:]
+	}
+	return tx.Commit().Error
+}
+
+// ListRecords get the latest size records before end sorted by time, all the latest records if end <= 0
+func ListRecords(exchangeType, stockType, period string, end int64, size int) (records []Record, err error) {
+	db := DB.Where("exchange_type = ? AND stock_type = ? AND period = ?", exchangeType, stockType, period)
+	if end > 0 {
+		db = db.Where("time < ?", end)
+	}
+	if err = db.Order("time desc").Limit(size).Find(&records).Error; err != nil {
+		return
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return
+}
+
+// ListRecordsBetween get the records from start to end sorted by time, end <= 0 means no limit
+func ListRecordsBetween(exchangeType, stockType, period string, start, end int64) (records []Record, err error) {
+	db := DB.Where("exchange_type = ? AND stock_type = ? AND period = ? AND time >= ?", exchangeType, stockType, period, start)
+	if end > 0 {
+		db = db.Where("time <= ?", end)
+	}
+	err = db.Order("time").Find(&records).Error
+	return
+}
+
+// RecordStockTypes get the stockTypes which have stored records of an exchange and period
+func RecordStockTypes(exchangeType, period string) (stockTypes []string, err error) {
+	err = DB.Model(&Record{}).Where("exchange_type = ? AND period = ?", exchangeType, period).Pluck("DISTINCT(stock_type)", &stockTypes).Error
+	return
+}
+
+// RecordsRange get the time of the first and the last stored records and how many records are stored
+func RecordsRange(exchangeType, stockType, period string) (first, last, count int64, err error) {
+	result := struct {
+		First int64
+		Last  int64
+		Count int64
+	}{}
+	err = DB.Model(&Record{}).Select("MIN(time) AS first, MAX(time) AS last, COUNT(*) AS count").
+		Where("exchange_type = ? AND stock_type = ? AND period = ?", exchangeType, stockType, period).Scan(&result).Error
+	return result.First, result.Last, result.Count, err
+}
@@ -14,6 +14,7 @@ type Trader struct {
 	AlgorithmID int64      `gorm:"index" json:"algorithmId"`
 	Name        string     `gorm:"type:varchar(200)" json:"name"`
 	Environment string     `gorm:"type:text" json:"environment"`
+	Paper       bool       `json:"paper"`
 	LastRunAt   time.Time  `json:"lastRunAt"`
 	CreatedAt   time.Time  `json:"createdAt"`
 	UpdatedAt   time.Time  `json:"updatedAt"`
@@ -98,6 +99,7 @@ func (user User) UpdateTrader(req Trader) (err error) {
 	}
 	runner.Name = req.Name
 	runner.Environment = req.Environment
+	runner.Paper = req.Paper
 	rs, err := user.GetTraderExchanges(runner.ID)
 	if err != nil {
 		db.Rollback()
@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/api"
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+)
+
+// Backfill download the records of an exchange since start into the database,
+// start is like "2018-06-01 00:00:00" in the local time
+func Backfill(e model.Exchange, stockType, period, start string, progress func(stored int, last int64)) (int, error) {
+	if e.BaseURL != "" {
+		// 历史K线以交易所类型保存, 不能混入测试网等其它地址的K线
+		return 0, fmt.Errorf("can not backfill the records of %v from the custom base URL %v", e.Name, e.BaseURL)
+	}
+	exchange, err := newExchange(e)
+	if err != nil {
+		return 0, err
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start time %q: %v", start, err)
+	}
+	return api.Backfill(exchange, stockType, period, t.Unix(), progress)
+}
+
+// Capabilities get what every exchange type supports, in the order of constant.ExchangeTypes
+func Capabilities() []api.Capabilities {
+	list := []api.Capabilities{}
+	for _, t := range constant.ExchangeTypes {
+		if c, ok := api.TypeCapabilities(t); ok {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// newExchange create an exchange out of a trader, it is used by the background jobs like backfill and recorder
+func newExchange(e model.Exchange) (api.Exchange, error) {
+	maker, ok := exchangeMaker[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized exchange type: %v", e.Type)
+	}
+	return maker(api.Option{
+		Type:       e.Type,
+		Name:       e.Name,
+		AccessKey:  e.AccessKey,
+		SecretKey:  e.SecretKey,
+		Passphrase: e.Passphrase,
+		Settings:   e.Settings,
+		ProxyURL:   e.ProxyURL,
+		BaseURL:    e.BaseURL,
+	}), nil
+}
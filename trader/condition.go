@@ -0,0 +1,497 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/api"
+	"github.com/HunterUPP/QuantBot/constant"
+	"github.com/HunterUPP/QuantBot/model"
+)
+
+// conditionInterval the interval of checking the pending conditions on the live exchanges
+var conditionInterval = 2 * time.Second
+
+// conditionRetries the times a triggered condition is retried when its order is rate limited or not sent
+var conditionRetries = 5
+
+// conditionEngine watches the tickers for the pending conditions of a trader and places the orders when they are triggered,
+// the conditions on the live exchanges are checked in the background and the ones on the backtest are checked by G.Sleep()
+type conditionEngine struct {
+	mutex      sync.Mutex
+	traderID   int64
+	es         []api.Exchange
+	conditions []*model.Condition //未触发的条件单
+	placing    map[int64]bool     //正在下单的条件单, 不能撤销
+	retries    map[int64]int      //条件单下单失败后重试的次数
+	logger     model.Logger
+	detached   bool //策略已经结束, 条件单都触发或者撤销后停止
+	done       chan struct{}
+	finished   chan struct{} //后台检查结束后关闭
+	once       sync.Once
+}
+
+// engines the running condition engines keyed by trader id, an engine lives on after its script crashes
+var engines = struct {
+	sync.Mutex
+	m map[int64]*conditionEngine
+}{m: make(map[int64]*conditionEngine)}
+
+// newConditionEngine load the pending conditions of a trader after its previous engine is stopped,
+// the ones on the backtest are canceled because its clock restarts
+func newConditionEngine(traderID int64, es []api.Exchange, logger model.Logger) (*conditionEngine, error) {
+	engines.Lock()
+	previous := engines.m[traderID]
+	engines.Unlock()
+	if previous != nil {
+		previous.stop()
+		<-previous.finished //等待进行中的检查结束, 避免重复触发
+	}
+	c := &conditionEngine{
+		traderID: traderID,
+		es:       es,
+		placing:  make(map[int64]bool),
+		retries:  make(map[int64]int),
+		logger:   logger,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	conditions, err := model.ListPendingConditions(traderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range conditions {
+		condition := &conditions[i]
+		if e := c.exchange(condition.ExchangeName); e != nil && e.GetType() == constant.Backtest {
+			c.finish(condition, constant.ConditionCanceled, "the backtest restarted")
+			continue
+		}
+		c.conditions = append(c.conditions, condition)
+	}
+	return c, nil
+}
+
+// start check the conditions in the background, the previous engine of the trader is stopped
+func (c *conditionEngine) start() {
+	engines.Lock()
+	if previous := engines.m[c.traderID]; previous != nil {
+		previous.stop()
+	}
+	engines.m[c.traderID] = c
+	engines.Unlock()
+	go func() {
+		ticker := time.NewTicker(conditionInterval)
+		defer ticker.Stop()
+		defer func() {
+			engines.Lock()
+			if engines.m[c.traderID] == c {
+				delete(engines.m, c.traderID)
+			}
+			engines.Unlock()
+			close(c.finished)
+		}()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if !c.check(false) {
+					c.stop()
+				}
+			}
+		}
+	}()
+}
+
+// stop stop checking the conditions, the pending ones are kept in the database,
+// the engine is removed from the running engines after its running check is done
+func (c *conditionEngine) stop() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// stopped whether the engine has been stopped
+func (c *conditionEngine) stopped() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// StopConditions stop the condition engine of a trader without waiting for its running check
+func StopConditions(traderID int64) {
+	engines.Lock()
+	c := engines.m[traderID]
+	engines.Unlock()
+	if c != nil {
+		c.stop()
+	}
+}
+
+// watching whether the condition engine of a trader is running
+func watching(traderID int64) bool {
+	engines.Lock()
+	defer engines.Unlock()
+	return engines.m[traderID] != nil && !engines.m[traderID].stopped()
+}
+
+// detach keep checking the conditions after the script ends until none of them is pending
+func (c *conditionEngine) detach() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.detached = true
+	if len(c.conditions) > 0 {
+		c.logger.Log(constant.INFO, "", 0.0, 0.0, len(c.conditions), " conditions are still watched")
+	}
+}
+
+// exchange get the exchange of the trader by its name
+func (c *conditionEngine) exchange(name string) api.Exchange {
+	for _, e := range c.es {
+		if e.GetName() == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// add check and save a new condition, the trailing stop is activated at once if its stopPrice <= 0
+func (c *conditionEngine) add(condition *model.Condition) error {
+	if err := c.validate(condition); err != nil {
+		return err
+	}
+	if err := model.SaveCondition(condition); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conditions = append(c.conditions, condition)
+	return nil
+}
+
+// oco check and save two new conditions linked to each other, when one of them is triggered the other one is canceled
+func (c *conditionEngine) oco(a, b *model.Condition) error {
+	if err := c.validate(a); err != nil {
+		return err
+	}
+	if err := c.validate(b); err != nil {
+		return err
+	}
+	// 两个条件单关联之后再一起加入, 避免只有一个被检查
+	if err := model.SaveOCO(a, b); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conditions = append(c.conditions, a, b)
+	return nil
+}
+
+// validate check a new condition and reset its status
+func (c *conditionEngine) validate(condition *model.Condition) error {
+	condition.Type = strings.ToUpper(condition.Type)
+	condition.TradeType = strings.ToUpper(condition.TradeType)
+	condition.StockType = strings.ToUpper(condition.StockType)
+	switch condition.TradeType {
+	case constant.TradeTypeBuy, constant.TradeTypeSell, constant.TradeTypeLong,
+		constant.TradeTypeShort, constant.TradeTypeLongClose, constant.TradeTypeShortClose:
+	default:
+		return fmt.Errorf("unrecognized tradeType: %v", condition.TradeType)
+	}
+	switch {
+	case c.exchange(condition.ExchangeName) == nil:
+		return fmt.Errorf("unrecognized exchange: %v", condition.ExchangeName)
+	case condition.StockType == "":
+		return fmt.Errorf("the stockType is required")
+	case condition.Amount <= 0:
+		return fmt.Errorf("invalid amount: %v", condition.Amount)
+	}
+	switch condition.Type {
+	case constant.ConditionStopMarket, constant.ConditionTakeProfit:
+		if condition.StopPrice <= 0 {
+			return fmt.Errorf("invalid stopPrice: %v", condition.StopPrice)
+		}
+	case constant.ConditionStopLimit:
+		if condition.StopPrice <= 0 || condition.Price <= 0 {
+			return fmt.Errorf("a %v condition needs both the stopPrice and the price", condition.Type)
+		}
+	case constant.ConditionTrailingStop:
+		if condition.Callback <= 0 || condition.Callback >= 1 {
+			return fmt.Errorf("invalid callback: %v, it should be between 0 and 1", condition.Callback)
+		}
+	default:
+		return fmt.Errorf("unrecognized condition type: %v", condition.Type)
+	}
+	condition.TraderID = c.traderID
+	condition.Status = constant.ConditionPending
+	condition.Extreme = 0.0
+	condition.OcoID = 0
+	return nil
+}
+
+// cancel cancel a pending condition and its OCO pair by its id
+func (c *conditionEngine) cancel(id int64, message string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, condition := range c.conditions {
+		if condition.ID != id {
+			continue
+		}
+		if c.placing[id] || condition.OcoID > 0 && c.placing[condition.OcoID] {
+			return fmt.Errorf("condition(id = %v) is being triggered", id)
+		}
+		// OCO 的两个条件单一起撤销
+		for _, other := range c.conditions {
+			if other.ID == id || condition.OcoID > 0 && other.ID == condition.OcoID {
+				c.finish(other, constant.ConditionCanceled, message)
+			}
+		}
+		c.conditions = c.pending()
+		return nil
+	}
+	return fmt.Errorf("condition(id = %v) not exist or has been finished", id)
+}
+
+// list get all the pending conditions
+func (c *conditionEngine) list() []model.Condition {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	conditions := []model.Condition{}
+	for _, condition := range c.conditions {
+		conditions = append(conditions, *condition)
+	}
+	return conditions
+}
+
+// check check the conditions on the backtest if clocked or the ones on the live exchanges if not,
+// it returns false when the script has ended and none of the live conditions is pending
+func (c *conditionEngine) check(clocked bool) bool {
+	for _, condition := range c.due(clocked) {
+		c.place(condition)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conditions = c.pending()
+	if !c.detached {
+		return true
+	}
+	for _, condition := range c.conditions {
+		if e := c.exchange(condition.ExchangeName); e != nil {
+			if _, ok := e.(api.Clock); !ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// due get the triggered conditions and mark them as placing, the tickers are got without holding the mutex
+func (c *conditionEngine) due(clocked bool) (conditions []*model.Condition) {
+	c.mutex.Lock()
+	candidates := []*model.Condition{}
+	for _, condition := range c.conditions {
+		if condition.Status != constant.ConditionPending || c.placing[condition.ID] {
+			continue
+		}
+		e := c.exchange(condition.ExchangeName)
+		if e == nil {
+			continue
+		}
+		if _, ok := e.(api.Clock); ok != clocked {
+			continue
+		}
+		if _, ok := e.(api.TypedExchange); !ok {
+			c.finish(condition, constant.ConditionFailed, "the exchange can not place orders")
+			continue
+		}
+		candidates = append(candidates, condition)
+	}
+	c.mutex.Unlock()
+	tickers := make(map[string]api.Ticker)
+	for _, condition := range candidates {
+		key := condition.ExchangeName + "|" + condition.StockType
+		if _, ok := tickers[key]; ok {
+			continue
+		}
+		// 订阅了行情的交易所从本地维护的订单簿读取
+		ticker, err := c.exchange(condition.ExchangeName).(api.TypedExchange).Ticker(condition.StockType, 1)
+		if err != nil {
+			c.logger.Log(constant.ERROR, condition.StockType, 0.0, 0.0, "Condition #", condition.ID, " check error, ", err)
+			continue
+		}
+		tickers[key] = ticker
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, condition := range candidates {
+		ticker, ok := tickers[condition.ExchangeName+"|"+condition.StockType]
+		// 获取行情期间可能已经被撤销
+		if !ok || condition.Status != constant.ConditionPending || !c.triggered(condition, ticker) {
+			continue
+		}
+		c.placing[condition.ID] = true
+		conditions = append(conditions, condition)
+	}
+	return
+}
+
+// place place the order of a triggered condition without holding the mutex,
+// it is retried only when the request is rate limited or not sent, and the open orders are checked after a network error
+func (c *conditionEngine) place(condition *model.Condition) {
+	c.mutex.Lock()
+	// 同一批触发的 OCO 条件单, 另一个下单后这个已经撤销
+	if condition.Status != constant.ConditionPending || c.stopped() {
+		delete(c.placing, condition.ID)
+		c.mutex.Unlock()
+		return
+	}
+	c.mutex.Unlock()
+	t := c.exchange(condition.ExchangeName).(api.TypedExchange)
+	price := condition.Price
+	if condition.Type == constant.ConditionStopMarket || condition.Type == constant.ConditionTrailingStop {
+		price = 0.0
+	}
+	before, _ := t.Orders(condition.StockType)
+	id, err := t.PlaceOrder(condition.TradeType, condition.StockType, price, condition.Amount, "Condition #", condition.ID)
+	if err != nil && api.ErrorKind(err) == api.ErrNetwork && !api.IsUnsent(err) {
+		// 请求可能已经到达交易所, 从未完成订单中查找新的订单, 不再重新下单
+		if id = placedOrder(t, condition, before); id == "" {
+			err = fmt.Errorf("the order may have been placed, check the orders before adding it again, %v", err)
+		} else {
+			err = nil
+		}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.placing, condition.ID)
+	if err != nil && retryable(err) && c.retries[condition.ID] < conditionRetries {
+		c.retries[condition.ID]++
+		c.logger.Log(constant.ERROR, condition.StockType, 0.0, 0.0, "Condition #", condition.ID, " will retry ", c.retries[condition.ID], "/", conditionRetries, ", ", err)
+		return
+	}
+	delete(c.retries, condition.ID)
+	if err != nil {
+		c.finish(condition, constant.ConditionFailed, err.Error())
+		c.logger.Log(constant.ERROR, condition.StockType, 0.0, 0.0, "Condition #", condition.ID, " failed, ", err)
+		c.cancelOCO(condition, fmt.Sprint("OCO #", condition.ID, " failed"))
+		return
+	}
+	condition.OrderID = id
+	c.finish(condition, constant.ConditionTriggered, "")
+	c.logger.Log(constant.INFO, condition.StockType, price, condition.Amount, "Condition #", condition.ID, " ", condition.Type, " triggered, order ", id)
+	c.cancelOCO(condition, fmt.Sprint("OCO #", condition.ID, " triggered"))
+}
+
+// placedOrder find the new open order of a condition which is not in the open orders before placing it
+func placedOrder(t api.TypedExchange, condition *model.Condition, before []api.Order) string {
+	if before == nil {
+		return ""
+	}
+	after, err := t.Orders(condition.StockType)
+	if err != nil {
+		return ""
+	}
+	known := make(map[string]bool)
+	for _, order := range before {
+		known[order.ID] = true
+	}
+	for _, order := range after {
+		if !known[order.ID] && order.TradeType == condition.TradeType {
+			return order.ID
+		}
+	}
+	return ""
+}
+
+// cancelOCO cancel the pending OCO pair of a finished condition, the caller must hold the mutex
+func (c *conditionEngine) cancelOCO(condition *model.Condition, message string) {
+	for _, other := range c.conditions {
+		if condition.OcoID > 0 && other.ID == condition.OcoID && other.Status == constant.ConditionPending {
+			c.finish(other, constant.ConditionCanceled, message)
+			c.logger.Log(constant.INFO, other.StockType, 0.0, 0.0, "Condition #", other.ID, " canceled, ", message)
+		}
+	}
+}
+
+// retryable whether the order of a triggered condition can be sent again, it is rejected by the rate limit or not sent at all
+func retryable(err error) bool {
+	return api.ErrorKind(err) == api.ErrRateLimited || api.IsUnsent(err)
+}
+
+// triggered update the extreme price of a trailing stop and check if the condition is triggered,
+// the price is the last price if the market is streamed, otherwise it is the price the order would be filled at
+func (c *conditionEngine) triggered(condition *model.Condition, ticker api.Ticker) bool {
+	buying := isBuying(condition.TradeType)
+	price := ticker.Last
+	if price <= 0 && buying {
+		price = ticker.Sell
+	} else if price <= 0 {
+		price = ticker.Buy
+	}
+	if price <= 0 {
+		return false
+	}
+	switch condition.Type {
+	case constant.ConditionStopMarket, constant.ConditionStopLimit:
+		return buying && price >= condition.StopPrice || !buying && price <= condition.StopPrice
+	case constant.ConditionTakeProfit:
+		return buying && price <= condition.StopPrice || !buying && price >= condition.StopPrice
+	case constant.ConditionTrailingStop:
+		if condition.Extreme <= 0 {
+			if condition.StopPrice <= 0 || buying && price <= condition.StopPrice || !buying && price >= condition.StopPrice {
+				condition.Extreme = price
+				c.save(condition)
+			}
+			return false
+		}
+		if buying && price < condition.Extreme || !buying && price > condition.Extreme {
+			condition.Extreme = price
+			c.save(condition)
+			return false
+		}
+		if buying {
+			return price >= condition.Extreme*(1+condition.Callback)
+		}
+		return price <= condition.Extreme*(1-condition.Callback)
+	}
+	return false
+}
+
+// isBuying whether the order of the tradeType buys, a buying condition is triggered by rising prices
+func isBuying(tradeType string) bool {
+	switch tradeType {
+	case constant.TradeTypeBuy, constant.TradeTypeLong, constant.TradeTypeShortClose:
+		return true
+	}
+	return false
+}
+
+// pending get the pending conditions, the caller must hold the mutex
+func (c *conditionEngine) pending() []*model.Condition {
+	conditions := []*model.Condition{}
+	for _, condition := range c.conditions {
+		if condition.Status == constant.ConditionPending {
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions
+}
+
+// finish set the final status of a condition and save it
+func (c *conditionEngine) finish(condition *model.Condition, status, message string) {
+	condition.Status = status
+	condition.Message = message
+	c.save(condition)
+}
+
+// save save a condition, the error is logged because the engine goes on
+func (c *conditionEngine) save(condition *model.Condition) {
+	if err := model.SaveCondition(condition); err != nil {
+		c.logger.Log(constant.ERROR, condition.StockType, 0.0, 0.0, "Condition #", condition.ID, " save error, ", err)
+	}
+}
@@ -1,17 +1,17 @@
 package trader
 
 import (
-	//"encoding/json"
-	//"fmt"
+	"encoding/json"
+	"fmt"
 	"log"
 	//"reflect"
 	"sync"
 	"time"
 
-	"github.com/miaolz123/conver"
 	"github.com/HunterUPP/QuantBot/api"
 	"github.com/HunterUPP/QuantBot/constant"
 	"github.com/HunterUPP/QuantBot/model"
+	"github.com/miaolz123/conver"
 	"github.com/robertkrimen/otto"
 )
 
@@ -20,15 +20,16 @@ type Tasks map[string][]task
 // Global ...
 type Global struct {
 	model.Trader
-	Logger  model.Logger   //利用这个对象保存日志
-	ctx     *otto.Otto     //js虚拟机
-	es      []api.Exchange //交易所列表
-	tasks   Tasks          //任务列表
-	running bool
+	Logger     model.Logger   //利用这个对象保存日志
+	ctx        *otto.Otto     //js虚拟机
+	es         []api.Exchange //交易所列表
+	tasks      Tasks          //任务列表
+	running    bool
+	conditions *conditionEngine //本地模拟的条件单
 	//statusLog string
 }
 
-//js中的一个任务,目的是可以并发工作
+// js中的一个任务,目的是可以并发工作
 type task struct {
 	ctx  *otto.Otto    //js虚拟机
 	fn   otto.Value    //代表该任务的js函数
@@ -41,6 +42,11 @@ func (g *Global) Sleep(intervals ...interface{}) {
 	if len(intervals) > 0 {
 		interval = conver.Int64Must(intervals[0])
 	}
+	if g.simulate(interval) {
+		g.conditions.check(true)
+		g.dispatch()
+		return
+	}
 	if interval > 0 {
 		time.Sleep(time.Duration(interval * 1000000))
 	} else {
@@ -48,6 +54,71 @@ func (g *Global) Sleep(intervals ...interface{}) {
 			e.AutoSleep()
 		}
 	}
+	g.dispatch()
+}
+
+// dispatch 把交易所推送的订单和资金变化传给 js 中定义的 onOrder(event, exchange) 和 onBalance(event, exchange),
+// 只在主脚本中调用, ExecTasks 运行期间跳过
+func (g *Global) dispatch() {
+	// 并发任务中的 Sleep 不能使用主虚拟机, 事件留到主脚本的 Sleep 中处理
+	if g.running {
+		return
+	}
+	onOrder, _ := g.ctx.Get("onOrder")
+	onBalance, _ := g.ctx.Get("onBalance")
+	if !onOrder.IsFunction() && !onBalance.IsFunction() {
+		return
+	}
+	for _, e := range g.es {
+		s, ok := e.(api.EventSource)
+		if !ok {
+			continue
+		}
+		events, err := s.Events()
+		if err != nil {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
+			continue
+		}
+		for _, event := range events {
+			fn := onOrder
+			if event.Type == constant.EventBalance {
+				fn = onBalance
+			}
+			if !fn.IsFunction() {
+				continue
+			}
+			if _, err := fn.Call(fn, event, e); err != nil {
+				g.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
+			}
+		}
+	}
+}
+
+// useCallbacks 策略定义了 onOrder 或者 onBalance 时事件只交给它们, 这时 E.GetEvents() 返回错误, 两者不能同时使用
+func (g *Global) useCallbacks() {
+	onOrder, _ := g.ctx.Get("onOrder")
+	onBalance, _ := g.ctx.Get("onBalance")
+	if !onOrder.IsFunction() && !onBalance.IsFunction() {
+		return
+	}
+	for _, e := range g.es {
+		if s, ok := e.(api.EventSource); ok {
+			s.UseCallbacks()
+		}
+	}
+}
+
+// simulate 回测时推进交易所的虚拟时钟而不是真正休眠, 历史数据用完时结束策略
+func (g *Global) simulate(interval int64) (simulated bool) {
+	for _, e := range g.es {
+		if c, ok := e.(api.Clock); ok {
+			simulated = true
+			if !c.Advance(interval) {
+				panic(errHalt)
+			}
+		}
+	}
+	return
 }
 
 // Console ...
@@ -60,6 +131,113 @@ func (g *Global) Log(msgs ...interface{}) {
 	g.Logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
 }
 
+// Resample 把K线合并为更长的周期, 如 G.Resample(records, "H4"), records 可以是任意的K线数组
+func (g *Global) Resample(records interface{}, period string) interface{} {
+	list, ok := records.([]api.Record)
+	if !ok {
+		data, err := json.Marshal(records)
+		if err == nil {
+			err = json.Unmarshal(data, &list)
+		}
+		if err != nil {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Resample() error, ", err)
+			return false
+		}
+	}
+	resampled, err := api.Resample(list, period)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Resample() error, ", err)
+		return false
+	}
+	return resampled
+}
+
+// conditionOptions the options of a conditional order passed from the javascript
+type conditionOptions struct {
+	Type      string
+	TradeType string
+	StockType string
+	StopPrice float64
+	Price     float64
+	Amount    float64
+	Callback  float64
+}
+
+// conditionOf get a condition on the exchange, the exchange is an exchange object or its name
+func (g *Global) conditionOf(exchange, options interface{}) (*model.Condition, error) {
+	condition := &model.Condition{}
+	switch e := exchange.(type) {
+	case api.Exchange:
+		condition.ExchangeName = e.GetName()
+	case string:
+		condition.ExchangeName = e
+	default:
+		return nil, fmt.Errorf("unrecognized exchange: %v", exchange)
+	}
+	opts := conditionOptions{}
+	data, err := json.Marshal(options)
+	if err == nil {
+		err = json.Unmarshal(data, &opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	condition.Type = opts.Type
+	condition.TradeType = opts.TradeType
+	condition.StockType = opts.StockType
+	condition.StopPrice = opts.StopPrice
+	condition.Price = opts.Price
+	condition.Amount = opts.Amount
+	condition.Callback = opts.Callback
+	return condition, nil
+}
+
+// AddCondition 添加一个条件单, 触发后以 E.Trade() 下单, 如 G.AddCondition(E, {type: "STOP_MARKET", tradeType: "SELL", stockType: "BTC/USDT", stopPrice: 5800, amount: 0.1}), 成功返回条件单的 ID
+func (g *Global) AddCondition(exchange, options interface{}) interface{} {
+	condition, err := g.conditionOf(exchange, options)
+	if err == nil {
+		err = g.conditions.add(condition)
+	}
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddCondition() error, ", err)
+		return false
+	}
+	return condition.ID
+}
+
+// AddOCO 添加两个条件单, 一个触发后另一个撤销, 成功返回两个条件单的 ID
+func (g *Global) AddOCO(exchange, options, other interface{}) interface{} {
+	a, err := g.conditionOf(exchange, options)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddOCO() error, ", err)
+		return false
+	}
+	b, err := g.conditionOf(exchange, other)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddOCO() error, ", err)
+		return false
+	}
+	if err := g.conditions.oco(a, b); err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddOCO() error, ", err)
+		return false
+	}
+	return []int64{a.ID, b.ID}
+}
+
+// CancelCondition 撤销一个未触发的条件单, OCO 的另一个条件单也一起撤销
+func (g *Global) CancelCondition(id interface{}) bool {
+	if err := g.conditions.cancel(conver.Int64Must(id), "canceled by the script"); err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelCondition() error, ", err)
+		return false
+	}
+	return true
+}
+
+// GetConditions 返回所有未触发的条件单, 包括策略重启之前添加的
+func (g *Global) GetConditions() interface{} {
+	return g.conditions.list()
+}
+
 // LogProfit ...
 func (g *Global) LogProfit(msgs ...interface{}) {
 	profit := 0.0
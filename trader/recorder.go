@@ -0,0 +1,234 @@
+package trader
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HunterUPP/QuantBot/api"
+	"github.com/HunterUPP/QuantBot/model"
+)
+
+// recordingDir the directory of the recorded market data
+var recordingDir = "custom/market"
+
+// Recording struct, a background job which snapshots the ticker, depth and public trades of a stockType at a fixed interval
+type Recording struct {
+	ID           int64
+	UserID       int64  `json:"-"`
+	ExchangeID   int64  //交易所ID
+	ExchangeType string //交易所类型
+	StockType    string //货币类型
+	Interval     int64  //采样间隔, 秒
+	Depth        int    //记录的深度档数
+	Trades       bool   //是否记录公开成交, 只有支持的交易所才会记录
+	Started      int64  //开始时间, unix时间戳
+	Snapshots    int64  //已经记录的行情快照数
+	TradeCount   int64  //已经记录的成交数
+	File         string //当前写入的文件
+	LastError    string //最近一次的错误
+	done         chan struct{}
+}
+
+// recordingLine is a line of the recorded files, Type is "ticker" or "trade"
+type recordingLine struct {
+	Time   int64       `json:"time"` //记录时间, unix时间戳, 毫秒
+	Type   string      `json:"type"`
+	Ticker *api.Ticker `json:"ticker,omitempty"`
+	Trade  *api.Trade  `json:"trade,omitempty"`
+}
+
+// recordings the running recordings keyed by id
+var recordings = struct {
+	sync.Mutex
+	m    map[int64]*Recording
+	next int64
+}{m: make(map[int64]*Recording)}
+
+// StartRecording start recording stockType of an exchange in the background, the files are rotated every hour,
+// like custom/market/okex/BTC_USDT/2018060112.jsonl.gz
+func StartRecording(userID int64, e model.Exchange, stockType string, interval int64, depth int) (Recording, error) {
+	exchange, err := newExchange(e)
+	if err != nil {
+		return Recording{}, err
+	}
+	if interval <= 0 {
+		interval = 1
+	}
+	if depth <= 0 {
+		depth = 20
+	}
+	_, trades := exchange.(api.TradeFeed)
+	r := &Recording{
+		UserID:       userID,
+		ExchangeID:   e.ID,
+		ExchangeType: e.Type,
+		StockType:    strings.ToUpper(stockType),
+		Interval:     interval,
+		Depth:        depth,
+		Trades:       trades,
+		Started:      time.Now().Unix(),
+		done:         make(chan struct{}),
+	}
+	recordings.Lock()
+	recordings.next++
+	r.ID = recordings.next
+	recordings.m[r.ID] = r
+	recordings.Unlock()
+	go r.run(exchange)
+	return r.status(), nil
+}
+
+// StopRecording stop a recording of the user
+func StopRecording(userID, id int64) error {
+	recordings.Lock()
+	defer recordings.Unlock()
+	r, ok := recordings.m[id]
+	if !ok || r.UserID != userID {
+		return fmt.Errorf("recording %v not found", id)
+	}
+	close(r.done)
+	delete(recordings.m, id)
+	return nil
+}
+
+// ListRecordings get the running recordings of the user
+func ListRecordings(userID int64) []Recording {
+	recordings.Lock()
+	defer recordings.Unlock()
+	list := []Recording{}
+	for _, r := range recordings.m {
+		if r.UserID == userID {
+			list = append(list, r.status())
+		}
+	}
+	return list
+}
+
+// status get a copy of the recording, the caller must hold the lock of recordings
+func (r *Recording) status() Recording {
+	return Recording{
+		ID:           r.ID,
+		UserID:       r.UserID,
+		ExchangeID:   r.ExchangeID,
+		ExchangeType: r.ExchangeType,
+		StockType:    r.StockType,
+		Interval:     r.Interval,
+		Depth:        r.Depth,
+		Trades:       r.Trades,
+		Started:      r.Started,
+		Snapshots:    r.Snapshots,
+		TradeCount:   r.TradeCount,
+		File:         r.File,
+		LastError:    r.LastError,
+	}
+}
+
+// run snapshot the market at every interval until the recording is stopped
+func (r *Recording) run(exchange api.Exchange) {
+	writer := &rotatingWriter{dir: filepath.Join(recordingDir, r.ExchangeType, strings.Replace(r.StockType, "/", "_", -1))}
+	defer writer.close()
+	typed, _ := exchange.(api.TypedExchange)
+	feed, _ := exchange.(api.TradeFeed)
+	lastTrade := int64(0)     //已经记录的最新成交时间
+	seen := map[string]bool{} //最新成交时间的成交ID, 避免重复记录
+	ticker := time.NewTicker(time.Duration(r.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		lines := []recordingLine{}
+		errs := []string{}
+		if typed != nil {
+			if t, err := typed.Ticker(r.StockType, r.Depth); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				lines = append(lines, recordingLine{Time: now.UnixNano() / 1000000, Type: "ticker", Ticker: &t})
+			}
+		}
+		trades := 0
+		if feed != nil {
+			if list, err := feed.MarketTrades(r.StockType, 0); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				for i := range list {
+					t := list[i]
+					if t.Time < lastTrade || (t.Time == lastTrade && seen[t.ID]) {
+						continue
+					}
+					if t.Time > lastTrade {
+						lastTrade = t.Time
+						seen = map[string]bool{}
+					}
+					seen[t.ID] = true
+					lines = append(lines, recordingLine{Time: now.UnixNano() / 1000000, Type: "trade", Trade: &t})
+					trades++
+				}
+			}
+		}
+		if err := writer.write(now, lines); err != nil {
+			errs = append(errs, err.Error())
+		}
+		recordings.Lock()
+		if len(lines) > trades {
+			r.Snapshots++
+		}
+		r.TradeCount += int64(trades)
+		r.File = writer.name
+		r.LastError = strings.Join(errs, "; ")
+		recordings.Unlock()
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rotatingWriter write the json lines into a gzip file of every hour
+type rotatingWriter struct {
+	dir  string
+	name string //当前文件的路径
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// write append the lines to the file of the hour, the file of the last hour is closed
+func (w *rotatingWriter) write(now time.Time, lines []recordingLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	name := filepath.Join(w.dir, now.Format("2006010215")+".jsonl.gz")
+	if name != w.name {
+		w.close()
+		if err := os.MkdirAll(w.dir, 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		//追加写入时会产生多个 gzip 成员, gzip 和 zcat 都可以正常读取
+		w.name, w.file, w.gz = name, file, gzip.NewWriter(file)
+	}
+	encoder := json.NewEncoder(w.gz)
+	for _, line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return w.gz.Flush()
+}
+
+// close close the current file
+func (w *rotatingWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+		w.file.Close()
+	}
+	w.name, w.file, w.gz = "", nil, nil
+}
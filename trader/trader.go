@@ -15,15 +15,20 @@ var (
 	Executor      = make(map[int64]*Global) //保存正在运行的策略，防止重复运行
 	errHalt       = fmt.Errorf("HALT")
 	exchangeMaker = map[string]func(api.Option) api.Exchange{ //保存所有交易所的构造函数
-		constant.Zb:         api.NewZb,
-		constant.Okex:       api.NewOKEX,
-		constant.Huobi:      api.NewHuobi,
-		constant.Binance:    api.NewBinance,
-		constant.GateIo:     api.NewGateIo,
-		constant.Bibox:      api.NewBibox,
-		constant.Poloniex:   api.NewPoloniex,
-		constant.OkexFuture: api.NewOkexFuture,
-		constant.BigOne:     api.NewBigOne,
+		constant.Zb:            api.NewZb,
+		constant.Okex:          api.NewOKEX,
+		constant.Huobi:         api.NewHuobi,
+		constant.Binance:       api.NewBinance,
+		constant.GateIo:        api.NewGateIo,
+		constant.Bibox:         api.NewBibox,
+		constant.Poloniex:      api.NewPoloniex,
+		constant.OkexFuture:    api.NewOkexFuture,
+		constant.BinanceFuture: api.NewBinanceFuture,
+		constant.HuobiFuture:   api.NewHuobiFuture,
+		constant.Kraken:        api.NewKraken,
+		constant.Coinbase:      api.NewCoinbase,
+		constant.BigOne:        api.NewBigOne,
+		constant.Backtest:      api.NewBacktest,
 	}
 )
 
@@ -32,6 +37,9 @@ func GetTraderStatus(id int64) (status int64) {
 	if t, ok := Executor[id]; ok && t != nil {
 		status = t.Status
 	}
+	if status == 0 && watching(id) {
+		status = 2 //策略已经结束, 条件单仍在监控
+	}
 	return
 }
 
@@ -43,7 +51,7 @@ func Switch(id int64) (err error) {
 	return run(id)
 }
 
-//核心是初始化js运行环境，及其可以调用的api
+// 核心是初始化js运行环境，及其可以调用的api
 func initialize(id int64) (trader Global, err error) {
 	if t := Executor[id]; t != nil && t.Status > 0 {
 		return
@@ -81,19 +89,30 @@ func initialize(id int64) (trader Global, err error) {
 	for _, e := range es {
 		if maker, ok := exchangeMaker[e.Type]; ok {
 			opt := api.Option{
-				TraderID:  trader.ID,
-				Type:      e.Type,
-				Name:      e.Name,
-				AccessKey: e.AccessKey,
-				SecretKey: e.SecretKey,
+				TraderID:   trader.ID,
+				Type:       e.Type,
+				Name:       e.Name,
+				AccessKey:  e.AccessKey,
+				SecretKey:  e.SecretKey,
+				Passphrase: e.Passphrase,
+				Settings:   e.Settings,
+				ProxyURL:   e.ProxyURL,
+				BaseURL:    e.BaseURL,
+			}
+			e := maker(opt)
+			if e.GetType() != constant.Backtest && (trader.Paper || api.IsPaper(opt)) {
+				e = api.NewPaper(e, opt) //模拟交易, 行情来自真实的交易所
 			}
-			trader.es = append(trader.es, maker(opt))
+			trader.es = append(trader.es, e)
 		}
 	}
 	if len(trader.es) == 0 {
 		err = fmt.Errorf("Please add at least one exchange")
 		return
 	}
+	if trader.conditions, err = newConditionEngine(trader.ID, trader.es, trader.Logger); err != nil {
+		return
+	}
 	trader.ctx.Set("Global", &trader)
 	trader.ctx.Set("G", &trader)
 	trader.ctx.Set("Exchange", trader.es[0])
@@ -109,16 +128,28 @@ func run(id int64) (err error) {
 	if err != nil {
 		return
 	}
+	trader.conditions.start()
 	go func() {
 		defer func() {
-			if err := recover(); err != nil && err != errHalt {
+			err := recover()
+			if err != nil && err != errHalt {
 				trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
 			}
+			if err == errHalt {
+				trader.conditions.stop() //手动停止时不再监控条件单, 下次运行时继续
+			} else {
+				trader.conditions.detach() //策略出错或者结束后继续监控未触发的条件单
+			}
 			if exit, err := trader.ctx.Get("exit"); err == nil && exit.IsFunction() {
 				if _, err := exit.Call(exit); err != nil {
 					trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
 				}
 			}
+			for _, e := range trader.es {
+				if s, ok := e.(api.Streamer); ok {
+					s.CloseStreams() //关闭行情订阅
+				}
+			}
 			trader.Status = 0
 		}()
 		trader.LastRunAt = time.Now()
@@ -126,6 +157,7 @@ func run(id int64) (err error) {
 		if _, err := trader.ctx.Run(trader.Algorithm.Script); err != nil {
 			trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
 		}
+		trader.useCallbacks()
 		if main, err := trader.ctx.Get("main"); err != nil || !main.IsFunction() {
 			trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Can not get the main function")
 		} else {
@@ -148,10 +180,15 @@ func run(id int64) (err error) {
 
 // stop ...
 func stop(id int64) (err error) {
-	if t, ok := Executor[id]; !ok || t == nil {
+	t, ok := Executor[id]
+	if !ok || t == nil {
 		return fmt.Errorf("Can not found the Trader")
 	}
-	Executor[id].ctx.Interrupt <- func() { panic(errHalt) }
+	if t.Status == 0 {
+		StopConditions(id) //策略已经结束, 只停止条件单的监控
+		return
+	}
+	t.ctx.Interrupt <- func() { panic(errHalt) }
 	return
 }
 
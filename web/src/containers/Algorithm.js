@@ -5,7 +5,7 @@ import { TraderList, TraderPut, TraderDelete, TraderSwitch, TraderCache } from '
 import React from 'react';
 import { connect } from 'react-redux';
 import { Link, browserHistory } from 'react-router';
-import { Badge, Button, Dropdown, Form, Input, Menu, Modal, Select, Table, Tag, Tooltip, notification } from 'antd';
+import { Badge, Button, Dropdown, Form, Input, Menu, Modal, Select, Switch, Table, Tag, Tooltip, notification } from 'antd';
 
 const FormItem = Form.Item;
 const Option = Select.Option;
@@ -159,6 +159,7 @@ function main() {
         id: 0,
         algorithmId: algorithm.id,
         name: `New Trader @ ${new Date().toLocaleDateString()}`,
+        paper: false,
         exchanges: [],
       };
     }
@@ -238,6 +239,7 @@ function main() {
         id: traderInfo.id,
         algorithmId: traderInfo.algorithmId,
         name: values.name,
+        paper: values.paper,
         exchanges: traderInfo.exchanges,
       };
 
@@ -416,6 +418,17 @@ function main() {
                 </Tooltip>)}
               </div> : ''}
             </FormItem>
+            <FormItem
+              {...formItemLayout}
+              label="Paper Trading"
+            >
+              {getFieldDecorator('paper', {
+                valuePropName: 'checked',
+                initialValue: traderInfo.paper,
+              })(
+                <Switch />
+              )}
+            </FormItem>
           </Form>
         </Modal>
       </div>
@@ -119,6 +119,10 @@ class Exchange extends React.Component {
         type: '',
         accessKey: '',
         secretKey: '',
+        passphrase: '',
+        proxyUrl: '',
+        baseUrl: '',
+        settings: '',
       };
     }
     this.setState({ info, infoModalShow: true });
@@ -138,6 +142,10 @@ class Exchange extends React.Component {
         type: values.type,
         accessKey: values.accessKey,
         secretKey: values.secretKey,
+        passphrase: values.passphrase,
+        proxyUrl: values.proxyUrl,
+        baseUrl: values.baseUrl,
+        settings: values.settings,
       };
 
       dispatch(ExchangePut(req, pagination.pageSize, pagination.current, this.order));
@@ -254,6 +262,46 @@ class Exchange extends React.Component {
                 <Input />
               )}
             </FormItem>
+            <FormItem
+              {...formItemLayout}
+              label="Passphrase"
+            >
+              {getFieldDecorator('passphrase', {
+                initialValue: info.passphrase,
+              })(
+                <Input placeholder="okex, coinbase" />
+              )}
+            </FormItem>
+            <FormItem
+              {...formItemLayout}
+              label="ProxyURL"
+            >
+              {getFieldDecorator('proxyUrl', {
+                initialValue: info.proxyUrl,
+              })(
+                <Input placeholder="socks5://127.0.0.1:1080" />
+              )}
+            </FormItem>
+            <FormItem
+              {...formItemLayout}
+              label="BaseURL"
+            >
+              {getFieldDecorator('baseUrl', {
+                initialValue: info.baseUrl,
+              })(
+                <Input />
+              )}
+            </FormItem>
+            <FormItem
+              {...formItemLayout}
+              label="Settings"
+            >
+              {getFieldDecorator('settings', {
+                initialValue: info.settings,
+              })(
+                <Input type="textarea" autosize={{ minRows: 2, maxRows: 6 }} />
+              )}
+            </FormItem>
             <Form.Item wrapperCol={{ span: 12, offset: 7 }} style={{ marginTop: 24 }}>
               <Button type="primary" onClick={this.handleInfoSubmit} loading={exchange.loading}>Submit</Button>
             </Form.Item>
@@ -4,6 +4,7 @@ import assign from 'lodash/assign';
 const EXCHANGE_INIT = {
   loading: false,
   types: [],
+  capabilities: {},
   total: 0,
   list: [],
   message: '',
@@ -23,7 +24,8 @@ function exchange(state = EXCHANGE_INIT, action) {
     case actions.EXCHANGE_TYPES_SUCCESS:
       return assign({}, state, {
         loading: false,
-        types: action.types,
+        types: action.types.map(c => c.Type),
+        capabilities: action.types.reduce((m, c) => assign(m, { [c.Type]: c }), {}),
       });
     case actions.EXCHANGE_TYPES_FAILURE:
       return assign({}, state, {